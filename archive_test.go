@@ -0,0 +1,29 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --search-archives
+// ---------------------------------------------------------------------------
+
+func TestIntegrationSearchArchives(t *testing.T) {
+	tmpDir := t.TempDir()
+	z := filepath.Join(tmpDir, "lib.jar")
+	tg := filepath.Join(tmpDir, "src.tgz")
+	fixture.WriteBytes(t, z, fixture.Zip(t, fixture.Entry{Name: "a/Main.java", Content: "class Main { // TODO jar\n"}))
+	fixture.WriteBytes(t, tg, fixture.Gzip(t, fixture.Tar(t, fixture.Entry{Name: "b/c.go", Content: "x\n// TODO tgz\n"})))
+	mustWriteFile(t, filepath.Join(tmpDir, "plain.txt"), "TODO plain\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--search-archives", "TODO", tmpDir, `\.(java|go)$`})
+	lines := splitLines(stdout)
+	expectContains(t, lines, z+"!a/Main.java:1:class Main { // TODO jar")
+	expectContains(t, lines, tg+"!b/c.go:2:// TODO tgz")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 matches, got %v", lines)
+	}
+}
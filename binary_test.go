@@ -0,0 +1,38 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+func TestIntegrationBinaryPolicies(t *testing.T) {
+	tmpDir := t.TempDir()
+	bin := filepath.Join(tmpDir, "prog")
+	fixture.WriteBytes(t, bin, []byte("\x7fELF\x02\x01\x01 TODO symbol\nTODO again\n"))
+	mustWriteFile(t, filepath.Join(tmpDir, "notes.txt"), "TODO text\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "notes.txt")+":1:TODO text")
+	if len(lines) != 1 {
+		t.Errorf("expected binary file to be skipped, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--binary=match-only", "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectContains(t, lines, "Binary file "+bin+" matches")
+	expectContains(t, lines, filepath.Join(tmpDir, "notes.txt")+":1:TODO text")
+	if len(lines) != 2 {
+		t.Errorf("expected one line per file, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--text", "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectContains(t, lines, bin+":2:TODO again")
+	if !strings.Contains(stdout, bin+":1:") {
+		t.Errorf("expected the first line of the binary to match with --text, got %v", lines)
+	}
+}
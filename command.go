@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/template"
+
+	"github.com/freedomben/findref/search"
+)
+
+// command is a run of findref from the command line.  It holds the settings
+// read from the flags and the config file, and prints the results of the
+// search as they arrive.
+type command struct {
+	settings *search.Settings
+	stats    *search.Statistics
+	out      io.Writer
+
+	trackStats      bool
+	jsonOutput      bool
+	listFiles       bool
+	matchTemplate   *template.Template
+	fileTemplate    *template.Template
+	summaryTemplate *template.Template
+
+	matcher search.Matcher
+}
+
+func newCommand(out io.Writer) *command {
+	return &command{
+		settings: search.NewSettings(),
+		stats:    search.NewStatistics(),
+		out:      out,
+	}
+}
+
+func (c *command) debug(a ...interface{}) {
+	if c.settings.Debug {
+		fmt.Println(a...)
+	}
+}
+
+// usesTemplates reports whether matches are rendered through a user supplied
+// template rather than printed directly
+func (c *command) usesTemplates() bool {
+	return c.matchTemplate != nil || c.fileTemplate != nil
+}
+
+// sink returns where the results of the search are printed: JSON Lines with
+// --json, the templates if any were given, or the grep style text otherwise
+func (c *command) sink() search.Sink {
+	if c.jsonOutput {
+		return search.NewJSONSink(c.out)
+	}
+	text := search.NewTextSink(c.out, c.settings)
+	text.ShowStats = c.trackStats
+	if c.usesTemplates() || c.summaryTemplate != nil {
+		return &templateSink{TextSink: text, c: c}
+	}
+	return text
+}
+
+// printErr reports an error met by the search.  With --json it goes to
+// stderr, so that stdout stays valid JSON Lines.
+func (c *command) printErr(err error) {
+	if c.jsonOutput {
+		fmt.Fprintln(os.Stderr, colors.Red+"[error]: "+err.Error()+colors.Restore)
+		return
+	}
+	printErr(err)
+}
+
+// fileSink is where the files of a --files listing are printed
+type fileSink interface {
+	File(f search.FileToScan)
+	Summary(stats *search.Statistics)
+}
+
+// printFiles lists the files a search with opts would scan, as JSON Lines
+// with their sizes and modification times with --json, or one path per line
+func (c *command) printFiles(opts search.Options) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	files, stats := search.ListFiles(ctx, opts)
+	c.stats = stats
+	if c.settings.Quiet {
+		return
+	}
+
+	var sink fileSink
+	if c.jsonOutput {
+		sink = search.NewJSONSink(c.out)
+	} else {
+		text := search.NewTextSink(c.out, c.settings)
+		text.ShowStats = c.trackStats
+		sink = text
+	}
+	for _, f := range files {
+		sink.File(f)
+	}
+	sink.Summary(stats)
+}
+
+func (c *command) finishAndExit() {
+	if !c.settings.Quiet && c.stats.Interrupted() {
+		fmt.Fprintln(os.Stderr, colors.Yellow+"[warning]: the search "+c.stats.Interruption().String()+", results are partial"+colors.Restore)
+	}
+	exit(c.exitStatus())
+}
+
+// exitStatus follows grep: 0 if anything matched, 1 if nothing did and 2 if a
+// start directory couldn't be read, unless --quiet found a match anyway.  A
+// search cut short by --timeout or a signal has a status of its own.  With
+// --files, listing any file counts as a match.
+func (c *command) exitStatus() int {
+	matched := c.stats.MatchCount() > 0
+	if c.listFiles {
+		matched = c.stats.FileCount() > 0
+	}
+	switch {
+	case matched && c.settings.Quiet:
+		return ExitCodeMatch
+	case c.stats.Interrupted():
+		return interruptionExitCode(c.stats.Interruption())
+	case c.stats.UnreadableRootCount() > 0:
+		return ExitCodeError
+	case matched:
+		return ExitCodeMatch
+	default:
+		return ExitCodeNoMatch
+	}
+}
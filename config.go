@@ -10,34 +10,62 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/freedomben/findref/search"
 	"gopkg.in/yaml.v3"
 )
 
-// stdinReader is the source for interactive prompts. Tests may override it.
+// stdinReader is the source for interactive prompts and for searching
+// standard input. Tests may override it.
 var stdinReader io.Reader = os.Stdin
 
 // FileConfig represents configuration values loaded from a YAML file.
 // All fields are pointers so we can distinguish between "unset" and
 // an explicit false/zero value.
 type FileConfig struct {
-	All             *bool    `yaml:"all"`
-	Debug           *bool    `yaml:"debug"`
-	Stats           *bool    `yaml:"stats"`
-	Hidden          *bool    `yaml:"hidden"`
-	Version         *bool    `yaml:"version"`
-	NoColor         *bool    `yaml:"no_color"`
-	MatchCase       *bool    `yaml:"match_case"`
-	IgnoreCase      *bool    `yaml:"ignore_case"`
-	FilenameOnly    *bool    `yaml:"filename_only"`
-	MaxLineLength   *int     `yaml:"max_line_length"`
-	NoMaxLineLength *bool    `yaml:"no_max_line_length"`
-	Exclude         []string `yaml:"exclude"`
-	ExcludePattern  []string `yaml:"exclude_pattern"`
-	Include         []string `yaml:"include"`
-	IncludePattern  []string `yaml:"include_pattern"`
-	MatchRegex      string   `yaml:"match_regex"`
-	StartDir        string   `yaml:"start_dir"`
-	FilenameRegex   string   `yaml:"filename_regex"`
+	All             *bool                      `yaml:"all"`
+	Debug           *bool                      `yaml:"debug"`
+	Stats           *bool                      `yaml:"stats"`
+	Hidden          *bool                      `yaml:"hidden"`
+	Version         *bool                      `yaml:"version"`
+	NoColor         *bool                      `yaml:"no_color"`
+	MatchCase       *bool                      `yaml:"match_case"`
+	IgnoreCase      *bool                      `yaml:"ignore_case"`
+	FilenameOnly    *bool                      `yaml:"filename_only"`
+	JSON            *bool                      `yaml:"json"`
+	MaxLineLength   *int                       `yaml:"max_line_length"`
+	NoMaxLineLength *bool                      `yaml:"no_max_line_length"`
+	SearchZip       *bool                      `yaml:"search_zip"`
+	SearchArchives  *bool                      `yaml:"search_archives"`
+	Follow          *bool                      `yaml:"follow"`
+	ArchiveDepth    *int                       `yaml:"archive_depth"`
+	Encoding        string                     `yaml:"encoding"`
+	Binary          string                     `yaml:"binary"`
+	MaxFilesize     string                     `yaml:"max_filesize"`
+	MinFilesize     string                     `yaml:"min_filesize"`
+	NewerThan       string                     `yaml:"newer_than"`
+	OlderThan       string                     `yaml:"older_than"`
+	MaxDepth        *int                       `yaml:"max_depth"`
+	MaxCount        *int                       `yaml:"max_count"`
+	MaxResults      *int                       `yaml:"max_results"`
+	Timeout         string                     `yaml:"timeout"`
+	Threads         *int                       `yaml:"threads"`
+	Nice            *bool                      `yaml:"nice"`
+	Exclude         []string                   `yaml:"exclude"`
+	ExcludePattern  []string                   `yaml:"exclude_pattern"`
+	Include         []string                   `yaml:"include"`
+	IncludePattern  []string                   `yaml:"include_pattern"`
+	Glob            []string                   `yaml:"glob"`
+	Iglob           []string                   `yaml:"iglob"`
+	Type            []string                   `yaml:"type"`
+	TypeNot         []string                   `yaml:"type_not"`
+	Types           map[string]search.FileType `yaml:"types"`
+	MatchRegex      string                     `yaml:"match_regex"`
+	StartDir        string                     `yaml:"start_dir"`
+	Paths           []string                   `yaml:"paths"`
+	FilenameRegex   string                     `yaml:"filename_regex"`
+	Template        string                     `yaml:"template"`
+	FileTemplate    string                     `yaml:"file_template"`
+	SummaryTemplate string                     `yaml:"summary_template"`
 }
 
 func findConfigFile() (string, error) {
@@ -128,6 +156,12 @@ match_regex: ""
 # Starting directory. Set to "." to search the current working directory.
 start_dir: "."
 
+# Search several directories or files at once (like repeating --path).
+# When set, start_dir is ignored. Command-line directories still take precedence.
+# paths:
+#   - src
+#   - test
+
 # Optional filename filter (regex). Leave blank to search all files.
 filename_regex: ""
 
@@ -141,13 +175,30 @@ no_color: false           # disable colorized output
 match_case: false         # force case-sensitive matching (otherwise smart-case)
 ignore_case: false        # force case-insensitive matching
 filename_only: false      # print only filenames with matches
+json: false               # print the results as JSON Lines
 max_line_length: 2000     # maximum line length before clipping
 no_max_line_length: false # disable line length limit entirely
+search_zip: false         # search inside gzip, bzip2 and zlib compressed files
+search_archives: false    # search the members of zip, jar, tar and tar.gz archives
+follow: false             # descend into symlinked directories
+archive_depth: 2          # how many levels of nested archives to open
+encoding: auto            # auto, utf-8, utf-16le, utf-16be or latin1
+binary: skip              # skip, match-only or text
+max_filesize: ""          # skip larger files, e.g. 10M
+min_filesize: ""          # skip smaller files, e.g. 1K
+newer_than: ""            # only files modified within a duration (7d) or since a date (2024-05-01)
+older_than: ""            # only files last modified before a duration ago or a date
+max_depth: -1             # directories to descend below each start directory (-1 for no limit)
+max_count: 0              # matching lines to report per file (0 for no limit)
+max_results: 0            # matching lines to report in total (0 for no limit)
+timeout: ""               # stop the search after a duration, e.g. 30s
+threads: 0                # files to search at once (0 for one per CPU)
+nice: false               # run with the lowest CPU and I/O priority (Linux only)
 
 # Additional paths or files to exclude. Defaults mirror the built-in list; remove or add as needed.
 exclude:
 `)
-	for _, entry := range defaultExcludeDirs {
+	for _, entry := range search.DefaultExcludes() {
 		fmt.Fprintf(&b, "  - %s\n", entry)
 	}
 	b.WriteString("\n# RE2 regex patterns to exclude. Paths matching any pattern are skipped.\n")
@@ -162,6 +213,27 @@ exclude:
 	b.WriteString("# include_pattern:\n")
 	b.WriteString("#   - '\\.go$'\n")
 	b.WriteString("#   - '\\.py$'\n")
+	b.WriteString("\n# Globs evaluated in order; the last match wins and a leading '!' excludes. iglob ignores case.\n")
+	b.WriteString("# glob:\n")
+	b.WriteString("#   - '**/*.go'\n")
+	b.WriteString("#   - '!**/*_mock.go'\n")
+	b.WriteString("# iglob:\n")
+	b.WriteString("#   - '*.md'\n")
+	b.WriteString("\n# Search only (type) or never (type_not) files of these types. See --type-list.\n")
+	b.WriteString("# type:\n")
+	b.WriteString("#   - go\n")
+	b.WriteString("# type_not:\n")
+	b.WriteString("#   - markdown\n")
+	b.WriteString("\n# Define new file types or replace built in ones. Filenames may be globs.\n")
+	b.WriteString("# types:\n")
+	b.WriteString("#   terraform:\n")
+	b.WriteString("#     extensions: [.tf, .tfvars]\n")
+	b.WriteString("#     filenames: [.terraformrc]\n")
+	b.WriteString("#     shebangs: []\n")
+	b.WriteString("\n# Go text/template output. See --help for the fields available to each template.\n")
+	b.WriteString("# template: '{{relpath .Path}}:{{.LineNumber}}:{{.Column}}: {{.Match}}'\n")
+	b.WriteString("# file_template: '== {{.Path}} ({{.MatchCount}}) =='\n")
+	b.WriteString("# summary_template: '{{.Matches}} matches in {{.FilesMatched}} files'\n")
 	return b.String()
 }
 
@@ -252,12 +324,45 @@ func configArgs(cfg *FileConfig) []string {
 	addBool(cfg.MatchCase, "--match-case")
 	addBool(cfg.IgnoreCase, "--ignore-case")
 	addBool(cfg.FilenameOnly, "--filename-only")
+	addBool(cfg.JSON, "--json")
 	addBool(cfg.NoMaxLineLength, "--no-max-line-length")
+	addBool(cfg.SearchZip, "--search-zip")
+	addBool(cfg.SearchArchives, "--search-archives")
+	addBool(cfg.Follow, "--follow")
+	addBool(cfg.Nice, "--nice")
 
 	if cfg.MaxLineLength != nil {
 		args = append(args, "--max-line-length", strconv.Itoa(*cfg.MaxLineLength))
 	}
 
+	if cfg.ArchiveDepth != nil {
+		args = append(args, "--archive-depth", strconv.Itoa(*cfg.ArchiveDepth))
+	}
+
+	if cfg.MaxDepth != nil {
+		args = append(args, "--max-depth", strconv.Itoa(*cfg.MaxDepth))
+	}
+	if cfg.MaxCount != nil {
+		args = append(args, "--max-count", strconv.Itoa(*cfg.MaxCount))
+	}
+	if cfg.MaxResults != nil {
+		args = append(args, "--max-results", strconv.Itoa(*cfg.MaxResults))
+	}
+	if cfg.Threads != nil {
+		args = append(args, "--threads", strconv.Itoa(*cfg.Threads))
+	}
+
+	addString := func(val string, flagName string) {
+		if trimmed := strings.TrimSpace(val); trimmed != "" {
+			args = append(args, flagName, trimmed)
+		}
+	}
+	addString(cfg.MaxFilesize, "--max-filesize")
+	addString(cfg.MinFilesize, "--min-filesize")
+	addString(cfg.NewerThan, "--newer-than")
+	addString(cfg.OlderThan, "--older-than")
+	addString(cfg.Timeout, "--timeout")
+
 	for _, ex := range cfg.Exclude {
 		trimmed := strings.TrimSpace(ex)
 		if trimmed != "" {
@@ -286,6 +391,51 @@ func configArgs(cfg *FileConfig) []string {
 		}
 	}
 
+	for _, g := range cfg.Glob {
+		trimmed := strings.TrimSpace(g)
+		if trimmed != "" {
+			args = append(args, "--glob", trimmed)
+		}
+	}
+
+	for _, g := range cfg.Iglob {
+		trimmed := strings.TrimSpace(g)
+		if trimmed != "" {
+			args = append(args, "--iglob", trimmed)
+		}
+	}
+
+	for _, t := range cfg.Type {
+		trimmed := strings.TrimSpace(t)
+		if trimmed != "" {
+			args = append(args, "--type", trimmed)
+		}
+	}
+
+	for _, t := range cfg.TypeNot {
+		trimmed := strings.TrimSpace(t)
+		if trimmed != "" {
+			args = append(args, "--type-not", trimmed)
+		}
+	}
+
+	if trimmed := strings.TrimSpace(cfg.Encoding); trimmed != "" {
+		args = append(args, "--encoding", trimmed)
+	}
+	if trimmed := strings.TrimSpace(cfg.Binary); trimmed != "" {
+		args = append(args, "--binary", trimmed)
+	}
+
+	if cfg.Template != "" {
+		args = append(args, "--template", cfg.Template)
+	}
+	if cfg.FileTemplate != "" {
+		args = append(args, "--file-template", cfg.FileTemplate)
+	}
+	if cfg.SummaryTemplate != "" {
+		args = append(args, "--summary-template", cfg.SummaryTemplate)
+	}
+
 	return args
 }
 
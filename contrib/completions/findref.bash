@@ -59,6 +59,15 @@ _findref_completion() {
         -c --ignore-case
         -f --filename-only
         -x --no-max-line-length
+        -z --search-zip
+        --search-archives
+        --text
+        --type-list
+        -L --follow
+        -q --quiet
+        --nice
+        --json
+        --files
         --help
         --mcp
     )
@@ -68,6 +77,27 @@ _findref_completion() {
         -E --exclude-pattern
         -i --include
         -I --include-pattern
+        --template
+        --file-template
+        --summary-template
+        --files-from
+        -p --path
+        --archive-depth
+        --encoding
+        --binary
+        -t --type
+        -T --type-not
+        --glob
+        --iglob
+        --max-filesize
+        --min-filesize
+        --newer-than
+        --older-than
+        --max-depth
+        --max-count
+        --max-results
+        --timeout
+        -j --threads
         --write-config
     )
     # Keep in sync with defaultExcludeDirs in settings.go
@@ -99,6 +129,9 @@ _findref_completion() {
         --write-config)
             expecting_value="write-config"
             ;;
+        --template|--file-template|--summary-template|--files-from|--path|-p|--archive-depth|--encoding|--binary|--type|-t|--type-not|-T|--glob|--iglob|--max-filesize|--min-filesize|--newer-than|--older-than|--max-depth|--max-count|--max-results|--timeout|--threads|-j)
+            expecting_value="freeform"
+            ;;
     esac
 
     if [[ $cur == --exclude=* ]]; then
@@ -205,6 +238,10 @@ _findref_completion() {
                 # Regex pattern — no meaningful completions, just let the user type
                 return 0
                 ;;
+            freeform)
+                # Free-form value — no meaningful completions, just let the user type
+                return 0
+                ;;
             max-length)
                 local prefix=""
                 local value="$cur"
@@ -306,11 +343,11 @@ _findref_completion() {
             continue
         fi
         case "$token" in
-            --exclude|--exclude-pattern|--include|--include-pattern|--max-line-length|-e|-E|-i|-I|-l)
+            --exclude|--exclude-pattern|--include|--include-pattern|--max-line-length|-e|-E|-i|-I|-l|--template|--file-template|--summary-template|--files-from|--path|-p|--archive-depth|--encoding|--binary|--type|-t|--type-not|-T|--glob|--iglob|--max-filesize|--min-filesize|--newer-than|--older-than|--max-depth|--max-count|--max-results|--timeout|--threads|-j)
                 pending_option="$token"
                 continue
                 ;;
-            --exclude=*|--exclude-pattern=*|--include=*|--include-pattern=*|--max-line-length=*|-l=*)
+            --exclude=*|--exclude-pattern=*|--include=*|--include-pattern=*|--max-line-length=*|-l=*|--template=*|--file-template=*|--summary-template=*|--files-from=*|--path=*|--archive-depth=*|--encoding=*|--binary=*|--type=*|--type-not=*|--glob=*|--iglob=*|--max-filesize=*|--min-filesize=*|--newer-than=*|--older-than=*|--max-depth=*|--max-count=*|--max-results=*|--timeout=*|--threads=*)
                 continue
                 ;;
             -*)
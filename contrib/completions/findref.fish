@@ -49,10 +49,10 @@ function __fish_findref_positional_count
             case '--'
                 set after_dd 1
                 continue
-            case '-e' '--exclude' '-E' '--exclude-pattern' '-i' '--include' '-I' '--include-pattern' '-l' '--max-line-length' '--write-config'
+            case '-e' '--exclude' '-E' '--exclude-pattern' '-i' '--include' '-I' '--include-pattern' '-l' '--max-line-length' '--write-config' '--template' '--file-template' '--summary-template' '--files-from' '--path' '-p' '--archive-depth' '--encoding' '--binary' '--type' '-t' '--type-not' '-T' '--glob' '--iglob' '--max-filesize' '--min-filesize' '--newer-than' '--older-than' '--max-depth' '--max-count' '--max-results' '--timeout' '--threads' '-j'
                 set expect_value 1
                 continue
-            case '--exclude=*' '-e=*' '--exclude-pattern=*' '-E=*' '--include=*' '-i=*' '--include-pattern=*' '-I=*' '--max-line-length=*' '-l=*' '--write-config=*'
+            case '--exclude=*' '-e=*' '--exclude-pattern=*' '-E=*' '--include=*' '-i=*' '--include-pattern=*' '-I=*' '--max-line-length=*' '-l=*' '--write-config=*' '--template=*' '--file-template=*' '--summary-template=*' '--files-from=*' '--path=*' '--archive-depth=*' '--encoding=*' '--binary=*' '--type=*' '--type-not=*' '--glob=*' '--iglob=*' '--max-filesize=*' '--min-filesize=*' '--newer-than=*' '--older-than=*' '--max-depth=*' '--max-count=*' '--max-results=*' '--timeout=*' '--threads=*'
                 continue
             case '-*'
                 continue
@@ -133,6 +133,36 @@ complete -c findref -s e -l exclude -fr -d 'Exclude matching directories or file
 complete -c findref -s E -l exclude-pattern -fr -d 'Exclude paths matching RE2 regex (repeatable)'
 complete -c findref -s i -l include -fr -d 'Include only matching files (repeatable)' -a '(__fish_complete_path)'
 complete -c findref -s I -l include-pattern -fr -d 'Include only files matching RE2 regex (repeatable)'
+complete -c findref -l template -fr -d 'Render each match through a Go text/template'
+complete -c findref -l file-template -fr -d 'Render a Go text/template once per file with matches'
+complete -c findref -l summary-template -fr -d 'Render a Go text/template after the search completes'
+complete -c findref -l files-from -fr -d 'Search the files listed in a file (- for stdin)' -a '(__fish_complete_path)'
+complete -c findref -s p -l path -fr -d 'Search the given directory or file (repeatable)' -a '(__fish_complete_path)'
+complete -c findref -s z -l search-zip -f -d 'Search inside gzip, bzip2 and zlib compressed files'
+complete -c findref -l search-archives -f -d 'Search the members of zip, jar, tar and tar.gz archives'
+complete -c findref -l archive-depth -fr -d 'Maximum nesting of archives to open'
+complete -c findref -l encoding -fr -d 'Encoding of searched files (auto, utf-8, utf-16le, utf-16be, latin1)'
+complete -c findref -l binary -fr -d 'How to handle binary files (skip, match-only, text)'
+complete -c findref -l text -f -d 'Search binary files as if they were text'
+complete -c findref -s t -l type -fr -d 'Search only files of the given type'
+complete -c findref -s T -l type-not -fr -d 'Don\'t search files of the given type'
+complete -c findref -l type-list -f -d 'List the known file types and exit'
+complete -c findref -l glob -fr -d 'Include files matching the glob, or exclude with a leading \'!\''
+complete -c findref -l iglob -fr -d 'Like --glob but case-insensitive'
+complete -c findref -l max-filesize -fr -d 'Skip files larger than this size'
+complete -c findref -l min-filesize -fr -d 'Skip files smaller than this size'
+complete -c findref -l newer-than -fr -d 'Only search files modified after this duration ago or date'
+complete -c findref -l older-than -fr -d 'Only search files modified before this duration ago or date'
+complete -c findref -l max-depth -fr -d 'Descend at most this many directories'
+complete -c findref -s L -l follow -f -d 'Descend into symlinked directories'
+complete -c findref -s q -l quiet -f -d 'Print nothing, stop at the first match and report it in the exit status'
+complete -c findref -l max-count -fr -d 'Stop searching a file after this many matching lines'
+complete -c findref -l max-results -fr -d 'Stop the search after this many results'
+complete -c findref -l timeout -fr -d 'Stop the search after this duration'
+complete -c findref -s j -l threads -fr -d 'Number of files to search at once'
+complete -c findref -l nice -f -d 'Run with the lowest CPU and I/O priority'
+complete -c findref -l json -f -d 'Print the results as JSON Lines'
+complete -c findref -l files -f -d 'List the files that would be searched'
 
 complete -c findref -n '__fish_findref_needs_match_regex' -f -d 'Regular expression to search for' -a '(__fish_findref_match_examples)'
 complete -c findref -n '__fish_findref_needs_start_dir' -f -d 'Directory to start searching from' -a '(__fish_findref_start_dirs)'
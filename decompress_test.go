@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --search-zip
+// ---------------------------------------------------------------------------
+
+func TestIntegrationSearchZip(t *testing.T) {
+	tmpDir := t.TempDir()
+	gz := filepath.Join(tmpDir, "app.log.gz")
+	bz := filepath.Join(tmpDir, "old.log.bz2")
+	zl := filepath.Join(tmpDir, "data.zz")
+	fixture.WriteBytes(t, gz, fixture.Gzip(t, []byte("TODO gz\n")))
+	fixture.WriteBytes(t, bz, []byte(fixture.Bzip2))
+	fixture.WriteBytes(t, zl, fixture.Zlib(t, []byte("TODO zlib\n")))
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "TODO", tmpDir})
+	if strings.Contains(stdout, "TODO gz") {
+		t.Errorf("expected compressed files not to be decompressed without --search-zip, got %q", stdout)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "-z", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, gz+":1:TODO gz")
+	expectContains(t, lines, bz+":2:TODO bz")
+	expectContains(t, lines, zl+":1:TODO zlib")
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+func TestIntegrationEncodingFlag(t *testing.T) {
+	tmpDir := t.TempDir()
+	fixture.WriteBytes(t, filepath.Join(tmpDir, "win.log"), fixture.UTF16("ok\nERROR denied\n", false, false))
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "ERROR", tmpDir})
+	expectContains(t, splitLines(stdout), filepath.Join(tmpDir, "win.log")+":2:ERROR denied")
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--encoding", "utf-8", "ERROR", tmpDir})
+	if strings.TrimSpace(stdout) != "" {
+		t.Errorf("expected no matches with --encoding utf-8, got %q", stdout)
+	}
+}
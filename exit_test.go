@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/freedomben/findref/search"
+)
+
+// searchStats runs a search of paths for pattern and returns its statistics
+func searchStats(t *testing.T, pattern string, paths ...string) *search.Statistics {
+	t.Helper()
+	results, err := search.Search(context.Background(), search.Options{Pattern: pattern, Paths: paths})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	return results.Wait()
+}
+
+// ---------------------------------------------------------------------------
+// exitStatus
+// ---------------------------------------------------------------------------
+
+func TestExitStatus(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+	if got := cmd.exitStatus(); got != ExitCodeNoMatch {
+		t.Errorf("expected %d without matches, got %d", ExitCodeNoMatch, got)
+	}
+
+	cmd.stats = searchStats(t, "TODO", tmpDir)
+	if got := cmd.exitStatus(); got != ExitCodeMatch {
+		t.Errorf("expected %d with a match, got %d", ExitCodeMatch, got)
+	}
+
+	cmd.stats = searchStats(t, "TODO", tmpDir, filepath.Join(tmpDir, "missing"))
+	if got := cmd.exitStatus(); got != ExitCodeError {
+		t.Errorf("expected %d when a root couldn't be read, got %d", ExitCodeError, got)
+	}
+
+	cmd.settings.Quiet = true
+	if got := cmd.exitStatus(); got != ExitCodeMatch {
+		t.Errorf("expected a match to win over errors with --quiet, got %d", got)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Integration: exit codes and --quiet
+// ---------------------------------------------------------------------------
+
+func TestIntegrationExitCodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+
+	runFindrefMain(t, []string{"--no-color", "TODO", tmpDir})
+	if testExitCode != ExitCodeMatch {
+		t.Errorf("expected %d when a line matched, got %d", ExitCodeMatch, testExitCode)
+	}
+
+	runFindrefMain(t, []string{"--no-color", "FIXME", tmpDir})
+	if testExitCode != ExitCodeNoMatch {
+		t.Errorf("expected %d when nothing matched, got %d", ExitCodeNoMatch, testExitCode)
+	}
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "-p", filepath.Join(t.TempDir(), "missing"), "-p", tmpDir, "TODO"})
+	if testExitCode != ExitCodeError {
+		t.Errorf("expected %d for a missing start directory, got %d", ExitCodeError, testExitCode)
+	}
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "a.txt")+":1:TODO a")
+	if len(lines) != 2 {
+		t.Errorf("expected the match and an error, got %v", lines)
+	}
+}
+
+func TestIntegrationQuiet(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		mustWriteFile(t, filepath.Join(tmpDir, name), "TODO one\nTODO two\n")
+	}
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--stats", "-q", "TODO", tmpDir})
+	if stdout != "" {
+		t.Errorf("expected no output with --quiet, got %q", stdout)
+	}
+	if testExitCode != ExitCodeMatch {
+		t.Errorf("expected %d, got %d", ExitCodeMatch, testExitCode)
+	}
+	if cmd.stats.MatchCount() != 1 {
+		t.Errorf("expected the search to stop at the first match, got %d matches", cmd.stats.MatchCount())
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--quiet", "--filename-only", "FIXME", tmpDir})
+	if stdout != "" || testExitCode != ExitCodeNoMatch {
+		t.Errorf("expected silence and %d, got %q and %d", ExitCodeNoMatch, stdout, testExitCode)
+	}
+}
@@ -1,11 +1,30 @@
 package main
 
 import (
+	"fmt"
+	"io"
 	"os"
 )
 
-type FileToScan struct {
-	Path string
-	Info os.FileInfo
-	Err  error
+// stdinIsPiped reports whether standard input is a pipe or a redirected file
+// rather than a terminal or /dev/null.  Tests may override it.
+var stdinIsPiped = func() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	mode := info.Mode()
+	return mode&os.ModeNamedPipe != 0 || mode.IsRegular()
+}
+
+// openFileList opens the source for --files-from, where "-" means stdin
+func openFileList(source string) (io.ReadCloser, error) {
+	if source == "-" {
+		return io.NopCloser(stdinReader), nil
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("opening --files-from list %q: %w", source, err)
+	}
+	return file, nil
 }
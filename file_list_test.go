@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func withStdin(t *testing.T, content string) {
+	t.Helper()
+	oldReader := stdinReader
+	stdinReader = strings.NewReader(content)
+	t.Cleanup(func() {
+		stdinReader = oldReader
+		testStdinPiped = false
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Integration: stdin search and --files-from
+// ---------------------------------------------------------------------------
+
+func TestIntegrationStdinDash(t *testing.T) {
+	withStdin(t, "first line\nan error happened\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "error", "-"})
+	expectContains(t, splitLines(stdout), "(standard input):2:an error happened")
+}
+
+func TestIntegrationStdinPiped(t *testing.T) {
+	withStdin(t, "an error happened\n")
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "file.txt"), "error in file\n")
+
+	testStdinPiped = true
+	stdout, _ := runFindrefMainInDir(t, []string{"--no-color", "error"}, tmpDir)
+	lines := splitLines(stdout)
+	expectContains(t, lines, "(standard input):1:an error happened")
+	if len(lines) != 1 {
+		t.Errorf("expected only the stdin match, got %v", lines)
+	}
+}
+
+func TestIntegrationFilesFrom(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	c := filepath.Join(tmpDir, "c.txt")
+	mustWriteFile(t, a, "TODO a\n")
+	mustWriteFile(t, b, "TODO b\n")
+	mustWriteFile(t, c, "TODO c\n")
+	list := filepath.Join(tmpDir, "list")
+	mustWriteFile(t, list, a+"\n"+c+"\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--filename-only", "--files-from", list, "TODO"})
+	lines := splitLines(stdout)
+	expectContains(t, lines, a)
+	expectContains(t, lines, c)
+	expectNotContains(t, lines, b)
+}
+
+func TestIntegrationFilesFromStdin(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.txt")
+	b := filepath.Join(tmpDir, "b.txt")
+	mustWriteFile(t, a, "TODO a\n")
+	mustWriteFile(t, b, "TODO b\n")
+	withStdin(t, b+"\x00")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--files-from", "-", "TODO"})
+	lines := splitLines(stdout)
+	expectContains(t, lines, b+":1:TODO b")
+	if len(lines) != 1 {
+		t.Errorf("expected only the listed file to be searched, got %v", lines)
+	}
+}
+
+func TestIntegrationMultiplePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src", "a.go")
+	test := filepath.Join(tmpDir, "test", "b.go")
+	other := filepath.Join(tmpDir, "other", "c.go")
+	single := filepath.Join(tmpDir, "other", "d.txt")
+	mustWriteFile(t, src, "TODO a\n")
+	mustWriteFile(t, test, "TODO b\n")
+	mustWriteFile(t, other, "TODO c\n")
+	mustWriteFile(t, single, "TODO d\n")
+
+	stdout, _ := runFindrefMain(t, []string{
+		"--no-color", "--filename-only",
+		"--path", filepath.Join(tmpDir, "src"),
+		"-p", filepath.Join(tmpDir, "test"),
+		"-p", single,
+		"-p", filepath.Join(tmpDir, "src", "a.go"),
+		"TODO",
+	})
+	lines := splitLines(stdout)
+	expectContains(t, lines, src)
+	expectContains(t, lines, test)
+	expectContains(t, lines, single)
+	expectNotContains(t, lines, other)
+	if len(lines) != 3 {
+		t.Errorf("expected each file reported once, got %v", lines)
+	}
+}
+
+func TestIntegrationPathWithFilenameRegex(t *testing.T) {
+	tmpDir := t.TempDir()
+	goFile := filepath.Join(tmpDir, "a.go")
+	txtFile := filepath.Join(tmpDir, "a.txt")
+	mustWriteFile(t, goFile, "TODO\n")
+	mustWriteFile(t, txtFile, "TODO\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--filename-only", "--path", tmpDir, "TODO", `\.go$`})
+	lines := splitLines(stdout)
+	expectContains(t, lines, goFile)
+	expectNotContains(t, lines, txtFile)
+}
+
+func TestIntegrationNamedFileIgnoresHiddenAndDefaultExcludes(t *testing.T) {
+	tmpDir := t.TempDir()
+	lock := filepath.Join(tmpDir, "yarn.lock")
+	hidden := filepath.Join(tmpDir, ".hid.txt")
+	mustWriteFile(t, lock, "TODO lock\n")
+	mustWriteFile(t, hidden, "TODO hidden\n")
+
+	// Like grep, a file named as a root is searched even though a walk of
+	// its directory would pass over it
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "-p", lock, "-p", hidden, "TODO"})
+	lines := splitLines(stdout)
+	expectContains(t, lines, lock+":1:TODO lock")
+	expectContains(t, lines, hidden+":1:TODO hidden")
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "TODO", tmpDir})
+	if stdout != "" {
+		t.Errorf("expected the walk of the directory to skip both files, got %q", stdout)
+	}
+
+	// The user's own excludes still apply
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--exclude", "yarn.lock", "TODO", lock})
+	if stdout != "" {
+		t.Errorf("expected --exclude to skip the named file, got %q", stdout)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --type, --type-not, --type-list, config and MCP
+// ---------------------------------------------------------------------------
+
+func TestIntegrationTypeFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "main.go"), "// TODO go\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "app.tsx"), "// TODO tsx\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "README.md"), "TODO docs\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "bin", "tool"), "#!/usr/bin/env python3\n# TODO script\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "-t", "go", "--type", "ts,py", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "main.go")+":1:// TODO go")
+	expectContains(t, lines, filepath.Join(tmpDir, "app.tsx")+":1:// TODO tsx")
+	expectContains(t, lines, filepath.Join(tmpDir, "bin", "tool")+":2:# TODO script")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 matches, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "-T", "markdown", "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectNotContains(t, lines, filepath.Join(tmpDir, "README.md")+":1:TODO docs")
+	if len(lines) != 3 {
+		t.Errorf("expected 3 matches, got %v", lines)
+	}
+}
+
+func TestIntegrationConfigTypes(t *testing.T) {
+	base := t.TempDir()
+	workDir := filepath.Join(base, "work")
+	homeDir := filepath.Join(base, "home")
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
+
+	mustWriteFile(t, filepath.Join(workDir, ".findref.yaml"),
+		"match_regex: TODO\ntype:\n  - terraform\ntypes:\n  terraform:\n    extensions: [tf]\n")
+	mustWriteFile(t, filepath.Join(workDir, "main.tf"), "# TODO tf\n")
+	mustWriteFile(t, filepath.Join(workDir, "main.go"), "// TODO go\n")
+
+	stdout, _ := runFindrefMainInDir(t, []string{"--no-color"}, workDir)
+	lines := splitLines(stdout)
+	expectContains(t, lines, "main.tf:1:# TODO tf")
+	if len(lines) != 1 {
+		t.Errorf("expected only the terraform file to match, got %v", lines)
+	}
+
+	// --type-list exits, so check what it prints with the config's types loaded
+	stdout, _ = captureOutput(func() { printFileTypes(cmd.settings.FileTypes()) })
+	lines = splitLines(stdout)
+	expectContains(t, lines, "terraform: *.tf")
+	expectContains(t, lines, "go: *.go, go.mod, go.work")
+	resetTestState(t)
+}
+
+func TestMCPSearchFileTypes(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.go"), "TODO a\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.py"), "TODO b\n")
+
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, FileTypes: []string{"py"}, FilenameOnly: true})
+	result, _ := handleSearch(context.Background(), args)
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 1 || !strings.HasSuffix(filenames[0], "b.py") {
+		t.Errorf("expected only b.py, got %v", filenames)
+	}
+
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, FileTypes: []string{"nope"}})
+	result, _ = handleSearch(context.Background(), args)
+	if !result.IsError {
+		t.Error("expected an error for an unknown file type")
+	}
+}
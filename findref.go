@@ -1,16 +1,16 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"regexp"
 	"runtime"
-	"sort"
 	"strings"
 	"time"
+
+	"github.com/freedomben/findref/search"
 )
 
 func Usage() string {
@@ -36,7 +36,8 @@ func Usage() string {
 
         %sstart_dir:  This optional argument sets the starting directory to crawl looking
                     for eligible files with lines matching match_regex.  Default value
-                    is the current working directory, AKA $PWD or '.'%s
+                    is the current working directory, AKA $PWD or '.'  Pass '-' to search
+                    standard input instead (the default when input is piped to findref)%s
 
         %sfilename_regex:  This optional argument restricts the set of files checked for
                          matching lines.  Eligible files must match this expression.
@@ -52,12 +53,62 @@ func Usage() string {
               Exclude directories or files whose names match the provided value (repeat for multiple; defaults skip VCS metadata)
         -E | --exclude-pattern
               Exclude files/directories whose path matches the provided RE2 regex (repeatable; combinable with --exclude)
+        --binary
+              How to handle binary files: skip (default), match-only or text.  Files are considered binary
+              if their first block contains a NUL byte, a known binary magic number, or mostly invalid
+              UTF-8.  match-only prints "Binary file X matches" instead of the matching lines
+        --text
+              Search binary files as if they were text.  Same as --binary=text
+        --encoding
+              Encoding of searched files: auto (default), utf-8, utf-16le, utf-16be or latin1.  Auto detects
+              byte order marks, BOM-less UTF-16 and Latin-1, and transcodes them to UTF-8 before matching
         -f | --filename-only
               Display only filenames with matches, not the matches themselves
+        --json
+              Print the results as JSON Lines, one object per event: begin, match and end for each file
+              with matches, then a summary with the statistics
+        --files
+              List the files that would be searched, without searching them.  There is no match_regex,
+              so the arguments are [start_dir] [filename_regex].  With --json each file's size and
+              modification time are included
+        --files-from
+              Search only the files listed (newline or NUL separated) in the given file, or '-' for stdin.  Filters still apply
         -c | --ignore-case
               Ignore case in regex (overrides smart-case)
         -h | --hidden
               Include hidden files and files in hidden directories
+        --glob
+              Include only files matching the glob, or exclude files and directories matching it if it
+              starts with '!'.  '*' doesn't cross directories and '**' matches any number of them, e.g.
+              '**/*_mock.go' or '!testdata/**'.  A glob containing '/' is anchored at the start
+              directory, so 'src/*.go' doesn't match 'lib/src/a.go', while one without matches file
+              names in any directory, like '*.go'.  Repeatable; when several globs match a path the
+              last one wins
+        --iglob
+              Like --glob, but matches case-insensitively.  Evaluated in order together with --glob
+        --max-filesize
+              Skip files larger than the given size.  Sizes are bytes, or use a K, M, G or T suffix, e.g. 10M
+        --min-filesize
+              Skip files smaller than the given size
+        --newer-than
+              Only search files modified more recently than the given duration ago (e.g. 90m, 36h, 7d, 2w)
+              or date (e.g. 2024-05-01, '2024-05-01 13:00' or RFC 3339)
+        --older-than
+              Only search files last modified before the given duration ago or date
+        -L | --follow
+              Descend into symlinked directories.  Directories already searched (e.g. through a symlink
+              loop) are skipped.  Without it, symlinked directories are skipped, while symlinked files are
+              always searched.  Broken symlinks are skipped and counted in --stats
+        --max-depth
+              Descend at most this many directories below each start directory.  1 searches only the files
+              directly inside it
+        -t | --type
+              Search only files of the given type, e.g. go, js, py or markdown.  Types are matched by
+              extension, filename and #! line.  Repeatable, and values may be comma separated
+        -T | --type-not
+              Don't search files of the given type.  Repeatable, and values may be comma separated
+        --type-list
+              List the known file types, including those defined under 'types' in the config file, and exit
         -i | --include
               Include only files whose names match the provided value (repeatable; whitelist approach)
         -I | --include-pattern
@@ -66,14 +117,47 @@ func Usage() string {
               Match regex case (if unset smart-case is used)
         -n | --no-color
               Disable colorized output
+        -p | --path
+              Search the given directory or file (repeatable).  Replaces the start_dir argument, so the
+              remaining arguments are match_regex [filename_regex].  Overlapping paths are searched once
         -l | --max-line-length
               Set maximum line length in characters (default is 2,000)
         -x |  --no-max-line-length
               Remove maximum line length.  Match againt lines of any length
+        --max-count
+              Stop searching a file after this many matching lines
+        --max-results
+              Stop the whole search after this many matching lines (or files with --filename-only).  Files
+              not yet scanned when the limit is reached are skipped
+        -j | --threads
+              Number of files to search at once.  Defaults to one per CPU, or four per CPU when a start
+              directory is on a network filesystem (NFS, SMB, sshfs, ...) since searching it is I/O bound
+        --nice
+              Lower findref's CPU priority to the minimum and put its disk I/O in the idle class (Linux
+              only), so a background search doesn't slow down builds or other work
+        --timeout
+              Stop the search after the given duration (e.g. 30s or 2m).  Matches found so far are still
+              printed, --stats are marked as partial and the exit status is 124.  Ctrl-C (SIGINT) and
+              SIGTERM stop the search the same way with an exit status of 130, and a second one exits at once
+        -q | --quiet
+              Print nothing and stop at the first match.  The exit status is 0 if anything matched, 1 if
+              nothing did and 2 on errors, e.g. in 'if findref -q TODO src; then'
         -s | --stats
               Track basic statistics and print them on exit
+        --search-archives
+              Search the members of zip (jar, war, ...), tar and tar.gz archives, reported as archive.zip!inner/path
+        --archive-depth
+              Maximum nesting of archives within archives to open with --search-archives (default is 2)
+        --template
+              Render each match through a Go text/template (fields: .Path .LineNumber .Line .Column .Match .Spans .Captures .Named)
+        --file-template
+              Render a Go text/template once per file with matches, before its matches (fields: .Path .MatchCount)
+        --summary-template
+              Render a Go text/template after the search (fields: .Elapsed .FilesScanned .FilesMatched .LinesScanned .Matches)
         -v | --version
               Print current version and exit
+        -z | --search-zip
+              Search inside gzip, bzip2 and zlib compressed files (detected by content, not extension)
         --
               End of options.  Use when one of the args starts with a '-'
         --mcp
@@ -110,6 +194,9 @@ func Usage() string {
         %s// Search only in specific files by name%s
         %sfindref%s %s--include%s %smain.go%s %s"package main"%s
 
+        %s// List the Go files under ./src that would be searched, without searching them%s
+        %sfindref%s %s--files --type%s %sgo%s %s./src%s
+
 `,
 		// Top block
 		colors.Red, versionString(false), colors.Restore, // Title
@@ -202,6 +289,13 @@ func Usage() string {
 		colors.Green, colors.Restore, // ninth example option
 		colors.Yellow, colors.Restore, // ninth example include value
 		colors.Cyan, colors.Restore, // ninth example match_regex
+
+		// Tenth Example (files)
+		colors.LightGray, colors.Restore, // tenth example comment
+		colors.Brown, colors.Restore, // tenth example findref
+		colors.Green, colors.Restore, // tenth example options
+		colors.Yellow, colors.Restore, // tenth example type value
+		colors.Blue, colors.Restore, // tenth example start_dir
 	)
 }
 
@@ -210,6 +304,16 @@ const Date = "2025-03-25"
 
 const MaxLineLengthDefault = 2000
 
+// Exit statuses, the same as grep's
+const (
+	ExitCodeMatch   = 0
+	ExitCodeNoMatch = 1
+	ExitCodeError   = 2
+
+	ExitCodeTimeout     = 124
+	ExitCodeInterrupted = 130
+)
+
 type multiValueFlag []string
 
 func (m *multiValueFlag) String() string {
@@ -224,233 +328,49 @@ func (m *multiValueFlag) Set(value string) error {
 	return nil
 }
 
-var FILE_PROCESSING_COMPLETE error = nil
-
-var settings *Settings = NewSettings()
-var statistics *Statistics = NewStatistics()
-var colors *Colors = NewColors()
-
-var filenameOnlyFiles []string = make([]string, 0, 100)
-var filesToScan []FileToScan = make([]FileToScan, 0, 100)
-
-const (
-	scannerDefaultInitialCap = 64 * 1024
-	scannerMinimumInitialCap = 4 * 1024
-	scannerMaxTokenCap       = 256 * 1024 * 1024
-	scannerMaxLineSlack      = 1024
-)
-
-func scannerBufferLimits(info os.FileInfo) (int, int) {
-	initialCap := scannerDefaultInitialCap
-	maxToken := scannerDefaultInitialCap
-
-	if info != nil {
-		if size := info.Size(); size > 0 {
-			if size > int64(scannerMaxTokenCap) {
-				maxToken = scannerMaxTokenCap
-			} else {
-				maxToken = int(size)
-			}
-			if int64(initialCap) > size {
-				initialCap = int(size)
-			}
-		}
-	}
+// globValue is a flag.Value that adds to the settings' ordered list of globs
+// so that interleaved --glob and --iglob flags keep their relative order
+type globValue struct {
+	settings   *search.Settings
+	ignoreCase bool
+}
 
-	if !settings.NoMaxLineLength {
-		desired := settings.MaxLineLength + scannerMaxLineSlack
-		if desired < scannerMinimumInitialCap {
-			desired = scannerMinimumInitialCap
-		}
-		if desired > scannerMaxTokenCap {
-			desired = scannerMaxTokenCap
-		}
-		if desired > maxToken {
-			maxToken = desired
-		}
-	} else if maxToken == scannerDefaultInitialCap {
-		maxToken = scannerMaxTokenCap
+func (g globValue) String() string {
+	if g.settings == nil {
+		return ""
 	}
+	return strings.Join(g.settings.Globs(), ",")
+}
 
-	if maxToken < scannerMinimumInitialCap {
-		maxToken = scannerMinimumInitialCap
-	}
-	if maxToken > scannerMaxTokenCap {
-		maxToken = scannerMaxTokenCap
-	}
+func (g globValue) Set(value string) error {
+	return g.settings.AddGlobs(g.ignoreCase, value)
+}
 
-	if initialCap < scannerMinimumInitialCap {
-		initialCap = scannerMinimumInitialCap
-	}
-	if initialCap > maxToken {
-		initialCap = maxToken
-	}
+// exit is swapped out by the tests so that finishAndExit can be exercised
+var exit = os.Exit
 
-	return initialCap, maxToken
-}
+// colors are used by the findref command's own messages and templates.  The
+// searches it runs print with the colors of their Settings.
+var colors = search.NewColors()
 
 func usageAndExit() {
 	flag.Usage()
-	os.Exit(1)
+	os.Exit(ExitCodeError)
 }
 
 func usageAndExitErr(errMsg error) {
 	flag.Usage()
-	fmt.Println(colors.Red + "[error]: " + errMsg.Error() + colors.Restore)
-	os.Exit(1)
+	printErr(errMsg)
+	os.Exit(ExitCodeError)
 }
 
 func exitWithErr(errMsg error) {
-	fmt.Println(colors.Red + "[error]: " + errMsg.Error() + colors.Restore)
-	os.Exit(1)
-}
-
-func debug(a ...interface{}) {
-	if settings.Debug {
-		fmt.Println(a...)
-	}
+	printErr(errMsg)
+	os.Exit(ExitCodeError)
 }
 
-func containsNullByte(line []byte) bool {
-	for _, el := range line {
-		if el == 0 {
-			return true
-		}
-	}
-	return false
-}
-
-func checkForMatches(path string) []Match {
-	debug(colors.Blue+"Checking file for matches:"+colors.Restore, path)
-	file, err := os.Open(path)
-	if err != nil {
-		fmt.Println(colors.Red+"Error opening file at '"+path+"'.  Err: "+colors.Restore, err)
-		debug(colors.Red+"Error opening file at '"+path+"'.  It might be a bad symlink.  Err: "+colors.Restore, err)
-		return []Match{Match{path, 0, []byte{}, []int{}, 0}}
-	}
-	defer func() {
-		// if path == "src/main/java/com/canopy/service/EFileService.java" {
-		// 	fmt.Println("Closing the file: " + path)
-		// }
-		file.Close()
-	}()
-
-	retval := make([]Match, 50)
-
-	// Split function defaults to ScanLines
-	scanner := bufio.NewScanner(file)
-
-	fileInfo, statErr := file.Stat()
-	if statErr != nil {
-		debug(colors.Red+"Unable to stat file '"+path+"' while sizing scanner buffer. Falling back to defaults. Err: "+colors.Restore, statErr)
-	}
-
-	initialCap, maxToken := scannerBufferLimits(fileInfo)
-
-	// Fix for max token size:  https://stackoverflow.com/a/37455465/2062384
-	buf := make([]byte, 0, initialCap)
-	scanner.Buffer(buf, maxToken)
-
-	var lineNumber int = 0
-	for scanner.Scan() {
-		lineNumber += 1
-		line := scanner.Bytes()
-		statistics.IncrLineCount()
-		if containsNullByte(line) {
-			// This is a binary file.  Skip it!
-			debug(colors.Blue+"Not processing binary file:"+colors.Restore, path)
-			statistics.IncrSkippedNullCount()
-			return retval
-		}
-		if matchIndex := settings.MatchRegex.FindIndex(line); matchIndex != nil {
-			// we have a match! loc == nil means no match so just ignore that case
-			statistics.IncrMatchCount()
-			if settings.FilenameOnly {
-				filenameOnlyFiles = append(filenameOnlyFiles, path)
-			} else {
-				m := Match{path, lineNumber, line, matchIndex, settings.MaxLineLength}
-				if !settings.NoMaxLineLength && (len(line) > settings.MaxLineLength) {
-					statistics.IncrSkippedLongCount()
-					m.printMatchClip()
-					// m.printMatchTooLong()
-				} else {
-					m.printMatch()
-				}
-				retval = append(retval, m)
-			}
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		debug(colors.Red+"Error scanning line from file '"+path+"'. File will be skipped.  Err: "+colors.Restore, err)
-		statistics.IncrErroredFilesCount()
-	}
-	return retval
-}
-
-func processFile(path string, info os.FileInfo, err error) error {
-	if err != nil {
-		debug("filepath.Walk encountered error with path '"+path+"'", err)
-		return FILE_PROCESSING_COMPLETE
-	}
-
-	if info.IsDir() {
-		if settings.ShouldExcludeDir(path) {
-			debug(colors.Blue, "Directory", path, "is excluded and will be pruned", colors.Restore)
-			return filepath.SkipDir
-		}
-		if settings.IsHidden(path) {
-			debug(colors.Blue, "Directory", path, "is hidden and will be pruned", colors.Restore)
-			return filepath.SkipDir // skip the whole sub-contents of this hidden directory
-		} else {
-			return FILE_PROCESSING_COMPLETE
-		}
-	}
-
-	if settings.ShouldExcludeFile(path) {
-		debug(colors.Blue, "File", path, "is excluded and will be skipped", colors.Restore)
-		return FILE_PROCESSING_COMPLETE
-	}
-
-	if !settings.ShouldIncludeFile(path) {
-		debug(colors.Blue, "File", path, "does not match include filter and will be skipped", colors.Restore)
-		return FILE_PROCESSING_COMPLETE
-	}
-
-	if settings.PassesFileFilter(path) {
-		debug(colors.Blue+"Passes file filter:", path)
-		if settings.IsHidden(path) {
-			debug(colors.Blue + "Hidden file '" + colors.Restore + path + colors.Blue + "' not processed")
-			return FILE_PROCESSING_COMPLETE
-		}
-		statistics.IncrFilesToScan()
-		defer statistics.IncrFileCount()
-
-		filesToScan = append(filesToScan, FileToScan{Path: path, Info: info, Err: err})
-	} else {
-		debug(colors.Blue + "Ignoring file cause it doesn't match filter: " + colors.Restore + path)
-	}
-	return FILE_PROCESSING_COMPLETE
-}
-
-func getMatchRegex(ignoreCase bool, matchCase bool, usersRegex string) (*regexp.Regexp, error) {
-	// If ignore case is set, ignore the case of the regex.
-	// if match-case is not set, use smart case which means if it's all lower case be case-insensitive,
-	// but if there's capitals then be case-sensitive
-	regexToCompile := usersRegex
-	if ignoreCase || (!matchCase && !regexp.MustCompile("[A-Z]").MatchString(usersRegex)) {
-		debug(colors.Blue, "Match regex will be case-insensitive", colors.Restore)
-		regexToCompile = "(?i)" + usersRegex
-	} else {
-		debug(colors.Blue, "Match regex will be exactly as user provided", colors.Restore)
-	}
-
-	compiled, err := regexp.Compile(regexToCompile)
-	if err != nil {
-		return nil, fmt.Errorf("invalid match regex %q: %w", usersRegex, err)
-	}
-
-	return compiled, nil
+func printErr(errMsg error) {
+	fmt.Println(colors.Red + "[error]: " + errMsg.Error() + colors.Restore)
 }
 
 func versionString(color bool) string {
@@ -465,6 +385,13 @@ func printVersion() {
 	fmt.Println(versionString(true))
 }
 
+// printFileTypes prints every known file type for --type-list
+func printFileTypes(types []*search.FileType) {
+	for _, t := range types {
+		fmt.Println(t.String())
+	}
+}
+
 func uniq(stringSlice []string) []string {
 	/* There is no built-in uniq function for slices, so we will use a map */
 	stringMap := make(map[string]bool)
@@ -480,35 +407,16 @@ func uniq(stringSlice []string) []string {
 	return retval
 }
 
-func finishAndExit() {
-	if settings.FilenameOnly {
-		filenames := uniq(filenameOnlyFiles)
-		sort.Strings(filenames)
-		for _, filename := range filenames {
-			fmt.Printf("%s%s%s\n", colors.Purple, filename, colors.Restore)
-		}
-	}
-
-	if settings.TrackStats {
-		fmt.Printf("%sElapsed time:%s  %s\n", colors.Cyan, colors.Restore, statistics.ElapsedTime().String())
-		fmt.Printf("%sLines scanned:%s %d\n", colors.Cyan, colors.Restore, statistics.LineCount())
-		fmt.Printf("%sFiles scanned:%s %d\n", colors.Cyan, colors.Restore, statistics.FileCount())
-		fmt.Printf("%sMatches found:%s %d\n", colors.Cyan, colors.Restore, statistics.MatchCount())
-		fmt.Printf("%sSkipped Long: %s %d\n", colors.Cyan, colors.Restore, statistics.SkippedLongCount())
-		fmt.Printf("%sSkipped Null: %s %d\n", colors.Cyan, colors.Restore, statistics.SkippedNullCount())
-		fmt.Printf("%sErrored Files:%s %d\n", colors.Cyan, colors.Restore, statistics.ErroredFilesCount())
-	}
+func main() {
+	runCLI(newCommand(os.Stdout))
 }
 
-func worker(id int, jobs <-chan string, results chan<- []Match) {
-	for file := range jobs {
-		debug(colors.Blue, "Worker number", id, "started file", colors.Restore, file)
-		results <- checkForMatches(file)
-		debug(colors.Blue, "Worker number", id, "finished file", colors.Restore, file)
-	}
-}
+// runCLI configures c from the config file and the command line, runs the
+// search and exits
+func runCLI(c *command) {
+	settings := c.settings
+	debug := c.debug
 
-func main() {
 	fileConfig, configPath, configErr := loadConfigFile()
 	if configErr != nil {
 		exitWithErr(configErr)
@@ -526,6 +434,10 @@ func main() {
 	cPtr := flag.Bool("c", false, "Alias for --ignore-case")
 	fPtr := flag.Bool("f", false, "Alias for --filename-only")
 	xPtr := flag.Bool("x", false, "Alias for --no-max-line-length")
+	zPtr := flag.Bool("z", false, "Alias for --search-zip")
+	LPtr := flag.Bool("L", false, "Alias for --follow")
+	jPtr := flag.Int("j", 0, "Alias for --threads")
+	qPtr := flag.Bool("q", false, "Alias for --quiet")
 	lPtr := flag.Int("l", MaxLineLengthDefault, "Alias for --max-line-length")
 	allPtr := flag.Bool("all", false, "Include hidden files and ignore case (implies: -c -h)")
 	helpPtr := flag.Bool("help", false, "Show usage")
@@ -539,9 +451,33 @@ func main() {
 	filenameOnlyPtr := flag.Bool("filename-only", false, "Display only filenames with matches")
 	maxLineLengthPtr := flag.Int("max-line-length", MaxLineLengthDefault, "Set maximum line length in characters (default is 2,000)")
 	noMaxLineLengthPtr := flag.Bool("no-max-line-length", false, "Remove maximum line length.  Match againt lines of any length")
+	maxCountPtr := flag.Int("max-count", 0, "Stop searching a file after this many matching lines (0 means no limit)")
+	maxResultsPtr := flag.Int("max-results", 0, "Stop the search after this many results in total (0 means no limit)")
+	threadsPtr := flag.Int("threads", 0, "Number of files to search at once (default is one per CPU, more on network filesystems)")
+	nicePtr := flag.Bool("nice", false, "Run with the lowest CPU and I/O priority (Linux only)")
+	timeoutPtr := flag.Duration("timeout", 0, "Stop the search after this long (e.g. 30s or 2m) and print what was found so far")
+	quietPtr := flag.Bool("quiet", false, "Print nothing and stop at the first match.  Only the exit status reports the result")
+	followPtr := flag.Bool("follow", false, "Follow symlinked directories, skipping any that loop back")
+	searchZipPtr := flag.Bool("search-zip", false, "Search inside gzip, bzip2 and zlib compressed files")
+	searchArchivesPtr := flag.Bool("search-archives", false, "Search the members of zip, jar, tar and tar.gz archives")
+	binaryPtr := flag.String("binary", "skip", "How to handle binary files: skip, match-only or text")
+	textPtr := flag.Bool("text", false, "Search binary files as if they were text (same as --binary=text)")
+	encodingPtr := flag.String("encoding", "auto", "Encoding of searched files: auto, utf-8, utf-16le, utf-16be or latin1")
+	archiveDepthPtr := flag.Int("archive-depth", search.ArchiveDepthDefault, "Maximum nesting of archives within archives to open")
+	maxFilesizePtr := flag.String("max-filesize", "", "Skip files larger than this size, e.g. 512K or 10M")
+	minFilesizePtr := flag.String("min-filesize", "", "Skip files smaller than this size, e.g. 1K")
+	newerThanPtr := flag.String("newer-than", "", "Only search files modified after this duration ago (36h, 7d, 2w) or date (2024-05-01)")
+	olderThanPtr := flag.String("older-than", "", "Only search files modified before this duration ago (36h, 7d, 2w) or date (2024-05-01)")
+	maxDepthPtr := flag.Int("max-depth", search.MaxDepthUnlimited, "Descend at most this many directories below each start directory")
 	mcpPtr := flag.Bool("mcp", false, "Run as an MCP (Model Context Protocol) server over stdio")
 	writeConfigPtr := flag.String("write-config", "", "Write a default config file to 'local' or 'global' (default: local) and exit")
 	forcePtr := flag.Bool("force", false, "Force overwrite without prompting (used with --write-config)")
+	templatePtr := flag.String("template", "", "Render each match through a Go text/template")
+	fileTemplatePtr := flag.String("file-template", "", "Render a Go text/template once per file with matches")
+	summaryTemplatePtr := flag.String("summary-template", "", "Render a Go text/template after the search completes")
+	jsonPtr := flag.Bool("json", false, "Print the results as JSON Lines")
+	filesPtr := flag.Bool("files", false, "List the files that would be searched, without searching them")
+	filesFromPtr := flag.String("files-from", "", "Search the files listed (newline or NUL separated) in the given file, or '-' for stdin")
 	excludeValues := multiValueFlag{}
 	flag.Var(&excludeValues, "exclude", "Exclude directories or files whose names match the provided value (repeatable)")
 	flag.Var(&excludeValues, "e", "Alias for --exclude")
@@ -551,6 +487,18 @@ func main() {
 	includeValues := multiValueFlag{}
 	flag.Var(&includeValues, "include", "Include only files whose names match the provided value (repeatable)")
 	flag.Var(&includeValues, "i", "Alias for --include")
+	pathValues := multiValueFlag{}
+	flag.Var(&pathValues, "path", "Search the given directory or file (repeatable; replaces the start_dir argument)")
+	flag.Var(&pathValues, "p", "Alias for --path")
+	flag.Var(globValue{settings: settings}, "glob", "Include files matching the glob, or exclude them if it starts with '!' (repeatable, evaluated in order)")
+	flag.Var(globValue{settings: settings, ignoreCase: true}, "iglob", "Like --glob but case-insensitive (repeatable)")
+	typeValues := multiValueFlag{}
+	flag.Var(&typeValues, "type", "Search only files of the given type, e.g. go or js (repeatable or comma separated)")
+	flag.Var(&typeValues, "t", "Alias for --type")
+	typeNotValues := multiValueFlag{}
+	flag.Var(&typeNotValues, "type-not", "Don't search files of the given type (repeatable or comma separated)")
+	flag.Var(&typeNotValues, "T", "Alias for --type-not")
+	typeListPtr := flag.Bool("type-list", false, "List the known file types and exit")
 	includePatternValues := multiValueFlag{}
 	flag.Var(&includePatternValues, "include-pattern", "Include only files whose path matches the provided RE2 regex (repeatable)")
 	flag.Var(&includePatternValues, "I", "Alias for --include-pattern")
@@ -572,6 +520,7 @@ func main() {
 	if *nPtr || *nocolorPtr {
 		debug("Color output is disabled")
 		colors.ZeroColors()
+		settings.Colors.ZeroColors()
 	}
 
 	if *vPtr || *versionPtr {
@@ -579,6 +528,16 @@ func main() {
 		os.Exit(0)
 	}
 
+	if fileConfig != nil {
+		if err := settings.AddFileTypes(fileConfig.Types); err != nil {
+			exitWithErr(err)
+		}
+	}
+	if *typeListPtr {
+		printFileTypes(settings.FileTypes())
+		os.Exit(0)
+	}
+
 	if *helpPtr {
 		flag.Usage()
 		os.Exit(0)
@@ -594,11 +553,40 @@ func main() {
 	}
 
 	settings.Debug = *debugPtr || *dPtr
-	settings.TrackStats = *statsPtr || *sPtr
+	c.trackStats = *statsPtr || *sPtr
+	c.jsonOutput = *jsonPtr
+	c.listFiles = *filesPtr
 	settings.FilenameOnly = *filenameOnlyPtr || *fPtr
 	allEnabled := *allPtr || *aPtr
 	settings.IncludeHidden = (*hiddenPtr || *hPtr) || allEnabled
 	settings.NoMaxLineLength = *noMaxLineLengthPtr || *xPtr
+	settings.SearchZip = *searchZipPtr || *zPtr
+	settings.FollowSymlinks = *followPtr || *LPtr
+	settings.Quiet = *quietPtr || *qPtr
+	if *maxCountPtr < 0 {
+		usageAndExitErr(fmt.Errorf("%s", "--max-count must not be negative"))
+	}
+	settings.MaxCount = *maxCountPtr
+	if *maxResultsPtr < 0 {
+		usageAndExitErr(fmt.Errorf("%s", "--max-results must not be negative"))
+	}
+	settings.MaxResults = *maxResultsPtr
+	if *threadsPtr < 0 || *jPtr < 0 {
+		usageAndExitErr(fmt.Errorf("%s", "--threads must not be negative"))
+	}
+	settings.Threads = *threadsPtr
+	if *jPtr != 0 {
+		settings.Threads = *jPtr
+	}
+	if *timeoutPtr < 0 {
+		usageAndExitErr(fmt.Errorf("%s", "--timeout must not be negative"))
+	}
+	settings.Timeout = *timeoutPtr
+	settings.SearchArchives = *searchArchivesPtr
+	settings.ArchiveDepth = *archiveDepthPtr
+	if settings.ArchiveDepth < 1 {
+		usageAndExitErr(fmt.Errorf("%s", "--archive-depth must be at least 1"))
+	}
 	*matchCasePtr = *matchCasePtr || *mPtr
 	*ignoreCasePtr = (*ignoreCasePtr || *cPtr) || allEnabled
 	settings.UseDefaultExcludes = !allEnabled
@@ -609,11 +597,12 @@ func main() {
 	if *maxLineLengthPtr != MaxLineLengthDefault {
 		settings.MaxLineLength = *maxLineLengthPtr
 	}
+	var err error
 	if len(excludeValues) > 0 {
 		settings.AddExcludes([]string(excludeValues)...)
 	}
 	if len(excludePatternValues) > 0 {
-		if err := settings.AddExcludePatterns([]string(excludePatternValues)...); err != nil {
+		if err = settings.AddExcludePatterns([]string(excludePatternValues)...); err != nil {
 			exitWithErr(err)
 		}
 	}
@@ -621,65 +610,182 @@ func main() {
 		settings.AddIncludes([]string(includeValues)...)
 	}
 	if len(includePatternValues) > 0 {
-		if err := settings.AddIncludePatterns([]string(includePatternValues)...); err != nil {
+		if err = settings.AddIncludePatterns([]string(includePatternValues)...); err != nil {
 			exitWithErr(err)
 		}
 	}
 
-	if configPath != "" && settings.Debug {
-		fmt.Println(colors.Blue+"Using config file:"+colors.Restore, configPath)
+	if err = settings.SelectTypes([]string(typeValues)...); err != nil {
+		usageAndExitErr(err)
+	}
+	if err = settings.ExcludeTypes([]string(typeNotValues)...); err != nil {
+		usageAndExitErr(err)
+	}
+
+	if *maxFilesizePtr != "" {
+		if settings.MaxFilesize, err = search.ParseSize(*maxFilesizePtr); err != nil {
+			usageAndExitErr(fmt.Errorf("--max-filesize: %w", err))
+		}
+	}
+	if *minFilesizePtr != "" {
+		if settings.MinFilesize, err = search.ParseSize(*minFilesizePtr); err != nil {
+			usageAndExitErr(fmt.Errorf("--min-filesize: %w", err))
+		}
+	}
+	if *newerThanPtr != "" {
+		if settings.NewerThan, err = search.ParseTimeBound(*newerThanPtr, time.Now()); err != nil {
+			usageAndExitErr(fmt.Errorf("--newer-than: %w", err))
+		}
+	}
+	if *olderThanPtr != "" {
+		if settings.OlderThan, err = search.ParseTimeBound(*olderThanPtr, time.Now()); err != nil {
+			usageAndExitErr(fmt.Errorf("--older-than: %w", err))
+		}
+	}
+	if *maxDepthPtr < search.MaxDepthUnlimited {
+		usageAndExitErr(fmt.Errorf("%s", "--max-depth must not be negative"))
+	}
+	settings.MaxDepth = *maxDepthPtr
+
+	if settings.Encoding, err = search.ParseEncoding(*encodingPtr); err != nil {
+		usageAndExitErr(err)
+	}
+	if settings.Binary, err = search.ParseBinaryPolicy(*binaryPtr); err != nil {
+		usageAndExitErr(err)
+	}
+	if *textPtr {
+		if settings.Binary != search.BinarySkip && settings.Binary != search.BinaryText {
+			usageAndExitErr(fmt.Errorf("%s", "--text contradicts --binary="+settings.Binary.String()))
+		}
+		settings.Binary = search.BinaryText
+	}
+	if c.matchTemplate, err = parseOutputTemplate("template", *templatePtr); err != nil {
+		exitWithErr(err)
+	}
+	if c.fileTemplate, err = parseOutputTemplate("file-template", *fileTemplatePtr); err != nil {
+		exitWithErr(err)
+	}
+	if c.summaryTemplate, err = parseOutputTemplate("summary-template", *summaryTemplatePtr); err != nil {
+		exitWithErr(err)
+	}
+	if settings.FilenameOnly && c.usesTemplates() {
+		usageAndExitErr(fmt.Errorf("%s", "--template and --file-template cannot be combined with -f|--filename-only"))
+	}
+	if c.jsonOutput && (c.usesTemplates() || c.summaryTemplate != nil) {
+		usageAndExitErr(fmt.Errorf("%s", "--json cannot be combined with --template, --file-template or --summary-template"))
+	}
+	if c.listFiles && (c.usesTemplates() || c.summaryTemplate != nil) {
+		usageAndExitErr(fmt.Errorf("%s", "--files cannot be combined with --template, --file-template or --summary-template"))
 	}
 
-	if settings.TrackStats {
-		statistics.startTime = time.Now()
-		debug(colors.Blue, "Start time is:", colors.Restore, statistics.startTime.String())
+	if configPath != "" && settings.Debug {
+		fmt.Println(colors.Blue+"Using config file:"+colors.Restore, configPath)
 	}
 
-	debug(colors.Blue, "stats enabled: ", colors.Restore, settings.TrackStats)
+	debug(colors.Blue, "stats enabled: ", colors.Restore, c.trackStats)
 	debug(colors.Blue, "match-case enabled: ", colors.Restore, *matchCasePtr)
 	debug(colors.Blue, "ignore-case enabled: ", colors.Restore, *ignoreCasePtr)
 	debug(colors.Blue, "include hidden files: ", colors.Restore, settings.IncludeHidden)
 	debug(colors.Blue, "debug mode: ", colors.Restore, settings.Debug)
 	debug(colors.Blue, "filename only: ", colors.Restore, settings.FilenameOnly)
+	debug(colors.Blue, "json output: ", colors.Restore, c.jsonOutput)
+	debug(colors.Blue, "list files: ", colors.Restore, c.listFiles)
 	debug(colors.Blue, "max line length: ", colors.Restore, settings.MaxLineLength)
 	debug(colors.Blue, "no max line length enabled: ", colors.Restore, settings.NoMaxLineLength)
+	debug(colors.Blue, "search zip: ", colors.Restore, settings.SearchZip)
+	debug(colors.Blue, "encoding: ", colors.Restore, settings.Encoding)
+	debug(colors.Blue, "binary: ", colors.Restore, settings.Binary)
+	debug(colors.Blue, "search archives: ", colors.Restore, settings.SearchArchives, "depth", settings.ArchiveDepth)
 	debug(colors.Blue, "excluded paths: ", colors.Restore, settings.Excludes())
 	debug(colors.Blue, "excluded patterns: ", colors.Restore, settings.ExcludePatterns())
 	debug(colors.Blue, "included paths: ", colors.Restore, settings.Includes())
 	debug(colors.Blue, "included patterns: ", colors.Restore, settings.IncludePatterns())
-
-	rootDir := "."
-
-	if len(flag.Args()) > 3 {
-		usageAndExitErr(fmt.Errorf("%s", "Too many args (expected 1 <= 3)"))
+	debug(colors.Blue, "globs: ", colors.Restore, settings.Globs())
+	debug(colors.Blue, "file size limits: ", colors.Restore, settings.MinFilesize, "to", settings.MaxFilesize)
+	debug(colors.Blue, "modified between: ", colors.Restore, settings.NewerThan, "and", settings.OlderThan)
+	debug(colors.Blue, "max depth: ", colors.Restore, settings.MaxDepth)
+	debug(colors.Blue, "follow symlinks: ", colors.Restore, settings.FollowSymlinks)
+	debug(colors.Blue, "quiet: ", colors.Restore, settings.Quiet)
+	debug(colors.Blue, "max count: ", colors.Restore, settings.MaxCount)
+	debug(colors.Blue, "max results: ", colors.Restore, settings.MaxResults)
+	debug(colors.Blue, "timeout: ", colors.Restore, settings.Timeout)
+
+	roots := []string{"."}
+
+	// With --files nothing is searched for, so there is no match_regex, and
+	// with --path the start directories are given as flags, leaving only
+	// match_regex and filename_regex as positional arguments
+	startDirArg := 1
+	if c.listFiles {
+		startDirArg = 0
+	}
+	filenameRegexArg := startDirArg + 1
+	if len(pathValues) > 0 {
+		filenameRegexArg = startDirArg
+	}
+	if len(flag.Args()) > filenameRegexArg+1 {
+		if len(pathValues) > 0 {
+			usageAndExitErr(fmt.Errorf("Too many args (expected %d <= %d when using -p|--path)", startDirArg, filenameRegexArg+1))
+		}
+		usageAndExitErr(fmt.Errorf("Too many args (expected %d <= %d)", startDirArg, filenameRegexArg+1))
 	}
 
-	matchArg := ""
-	if len(flag.Args()) >= 1 {
-		matchArg = flag.Args()[0]
-	} else if fileConfig != nil && strings.TrimSpace(fileConfig.MatchRegex) != "" {
-		matchArg = strings.TrimSpace(fileConfig.MatchRegex)
-	}
+	if !c.listFiles {
+		matchArg := ""
+		if len(flag.Args()) >= 1 {
+			matchArg = flag.Args()[0]
+		} else if fileConfig != nil && strings.TrimSpace(fileConfig.MatchRegex) != "" {
+			matchArg = strings.TrimSpace(fileConfig.MatchRegex)
+		}
 
-	if matchArg == "" {
-		usageAndExitErr(fmt.Errorf("%s", "Must specify regex to match against files"))
-	}
+		if matchArg == "" {
+			usageAndExitErr(fmt.Errorf("%s", "Must specify regex to match against files"))
+		}
 
-	matchRegex, err := getMatchRegex(*ignoreCasePtr, *matchCasePtr, matchArg)
-	if err != nil {
-		exitWithErr(err)
+		matchRegex, err := search.CompileMatchRegex(*ignoreCasePtr, *matchCasePtr, matchArg)
+		if err != nil {
+			exitWithErr(err)
+		}
+		settings.MatchRegex = matchRegex
 	}
-	settings.MatchRegex = matchRegex
 
-	if len(flag.Args()) >= 2 {
-		rootDir = flag.Args()[1]
+	searchStdin := false
+	explicitRoots := []string{}
+	if len(pathValues) > 0 {
+		explicitRoots = []string(pathValues)
+	} else if len(flag.Args()) > startDirArg {
+		explicitRoots = []string{flag.Args()[startDirArg]}
+	}
+	if len(explicitRoots) > 0 {
+		if *filesFromPtr != "" {
+			usageAndExitErr(fmt.Errorf("%s", "start_dir and -p|--path cannot be combined with --files-from"))
+		}
+		for _, root := range explicitRoots {
+			if root == "-" {
+				searchStdin = true
+			}
+		}
+		if searchStdin && c.listFiles {
+			usageAndExitErr(fmt.Errorf("%s", "'-' (stdin) cannot be combined with --files"))
+		}
+		if searchStdin && len(explicitRoots) > 1 {
+			usageAndExitErr(fmt.Errorf("%s", "'-' (stdin) cannot be combined with other start directories"))
+		}
+		roots = search.NormalizeRoots(explicitRoots)
+	} else if fileConfig != nil && len(search.NormalizeRoots(fileConfig.Paths)) > 0 {
+		roots = search.NormalizeRoots(fileConfig.Paths)
 	} else if fileConfig != nil && strings.TrimSpace(fileConfig.StartDir) != "" {
-		rootDir = strings.TrimSpace(fileConfig.StartDir)
+		roots = []string{strings.TrimSpace(fileConfig.StartDir)}
+	} else if *filesFromPtr == "" && !c.listFiles && stdinIsPiped() {
+		searchStdin = true
+	}
+	if searchStdin && *filesFromPtr == "-" {
+		usageAndExitErr(fmt.Errorf("%s", "Cannot read both the search input and --files-from list from stdin"))
 	}
 
 	filenameRegexValue := ""
-	if len(flag.Args()) == 3 {
-		filenameRegexValue = flag.Args()[2]
+	if len(flag.Args()) == filenameRegexArg+1 {
+		filenameRegexValue = flag.Args()[filenameRegexArg]
 	} else if fileConfig != nil && strings.TrimSpace(fileConfig.FilenameRegex) != "" {
 		filenameRegexValue = strings.TrimSpace(fileConfig.FilenameRegex)
 	}
@@ -692,42 +798,56 @@ func main() {
 		settings.FilenameRegex = filenameRegex
 	}
 
-	debug(colors.Blue, "matchRegex: ", colors.Restore, settings.MatchRegex.String())
-	debug(colors.Blue, "rootDir: ", colors.Restore, rootDir)
+	debug(colors.Blue, "matchRegex: ", colors.Restore, settings.MatchRegex)
+	debug(colors.Blue, "roots: ", colors.Restore, roots)
+	debug(colors.Blue, "search stdin: ", colors.Restore, searchStdin)
+	debug(colors.Blue, "files from: ", colors.Restore, *filesFromPtr)
 	debug(colors.Blue, "fileRegex: ", colors.Restore, settings.FilenameRegex.String())
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
-	filepath.Walk(rootDir, processFile)
-
-	// TODO: set niceness value to low
-
-	jobs := make(chan string, len(filesToScan))
-	results := make(chan []Match, 100)
 
-	// two workers for each core
-	numWorkers := runtime.NumCPU() * 1
-	for w := 0; w < numWorkers; w++ {
-		go worker(w, jobs, results)
+	if *nicePtr {
+		if err := lowerPriority(); err != nil {
+			fmt.Fprintln(os.Stderr, colors.Yellow+"[warning]: "+err.Error()+colors.Restore)
+		}
+	}
+	// The debug output of the search goes with the command's own, to stdout
+	opts := search.Options{Paths: roots, Settings: settings, Errors: c.printErr, DebugOutput: os.Stdout}
+	switch {
+	case searchStdin:
+		opts.Input = stdinReader
+	case *filesFromPtr != "":
+		list, err := openFileList(*filesFromPtr)
+		if err != nil {
+			exitWithErr(err)
+		}
+		opts.FileList = list
 	}
 
-	// create a job for each file to scan
-	for _, val := range filesToScan {
-		jobs <- val.Path
+	if c.listFiles {
+		c.printFiles(opts)
+		c.finishAndExit()
+		return
 	}
-	close(jobs)
 
-	for r := 0; r < len(filesToScan); r++ {
-		result := <-results
-		for _, res := range result {
-			if res.hasMatch() {
-				//res.printMatch()
-			}
-		}
+	results, err := search.Search(context.Background(), opts)
+	if err != nil {
+		exitWithErr(err)
+	}
+	stopSignals := c.stopOnSignal(results)
+	defer stopSignals()
+	switch {
+	case settings.Quiet:
+		c.stats = results.Wait()
+	case settings.FilenameOnly:
+		c.stats = results.DeliverSorted(c.sink())
+	default:
+		c.stats = results.Deliver(c.sink())
 	}
 
 	// Repeat settings at the end
 	debug(colors.Cyan, "Search settings were:", colors.Restore)
-	debug(colors.Blue, "* stats enabled: ", colors.Restore, settings.TrackStats)
+	debug(colors.Blue, "* stats enabled: ", colors.Restore, c.trackStats)
 	debug(colors.Blue, "* match-case enabled: ", colors.Restore, *matchCasePtr)
 	debug(colors.Blue, "* ignore-case enabled: ", colors.Restore, *ignoreCasePtr)
 	debug(colors.Blue, "* include hidden files: ", colors.Restore, settings.IncludeHidden)
@@ -740,8 +860,8 @@ func main() {
 	debug(colors.Blue, "* included paths: ", colors.Restore, settings.Includes())
 	debug(colors.Blue, "* included patterns: ", colors.Restore, settings.IncludePatterns())
 	debug(colors.Blue, "* matchRegex: ", colors.Restore, settings.MatchRegex.String())
-	debug(colors.Blue, "* rootDir: ", colors.Restore, rootDir)
+	debug(colors.Blue, "* roots: ", colors.Restore, roots)
 	debug(colors.Blue, "* fileRegex: ", colors.Restore, settings.FilenameRegex.String())
 
-	finishAndExit()
+	c.finishAndExit()
 }
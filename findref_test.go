@@ -2,360 +2,41 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"testing"
-	"time"
 )
 
-func resetTestState(t *testing.T) {
-	t.Helper()
-	settings = NewSettings()
-	statistics = NewStatistics()
-	colors = NewColors()
-	filenameOnlyFiles = make([]string, 0, 100)
-	filesToScan = make([]FileToScan, 0, 100)
-}
-
-func mustGetMatchRegex(t *testing.T, ignoreCase bool, matchCase bool, usersRegex string) *regexp.Regexp {
-	t.Helper()
-	r, err := getMatchRegex(ignoreCase, matchCase, usersRegex)
-	if err != nil {
-		t.Fatalf("unexpected error compiling regex %q: %v", usersRegex, err)
-	}
-	return r
-}
-
-func TestContainsNullByte(t *testing.T) {
-	if containsNullByte([]byte("Here is a string....")) {
-		t.Fail()
-	}
-	if !containsNullByte([]byte("Here is a \x00string....")) {
-		t.Fail()
-	}
-}
-
-func TestPassesFileFilter(t *testing.T) {
-	s := NewSettings()
-	s.FilenameRegex = regexp.MustCompile("abcd")
-	if !s.PassesFileFilter("abcd.txt") {
-		t.Fail()
-	}
-	if s.PassesFileFilter("abc.txt") {
-		t.Fail()
-	}
-	if !s.PassesFileFilter("abcd") {
-		t.Fail()
-	}
-	s.FilenameRegex = regexp.MustCompile(`.*\.txt`)
-	if !s.PassesFileFilter("abcd.txt") {
-		t.Fail()
-	}
-	if s.PassesFileFilter("abc.tx") {
-		t.Fail()
-	}
-	if s.PassesFileFilter("abcd") {
-		t.Fail()
-	}
-}
+// cmd is the command the tests configure and inspect.  resetTestState
+// replaces it and runFindrefMain runs the command line with it.
+var cmd *command
 
-func TestIsHidden(t *testing.T) {
-	s := NewSettings()
-	if s.IsHidden("/home/ben") {
-		t.Fail()
-	}
-	if s.IsHidden("/home/ben/nothing.txt") {
-		t.Fail()
-	}
-	if !s.IsHidden("/home/.ben/nothing.txt") {
-		t.Fail()
-	}
-	if !s.IsHidden("/home/ben/.nothing.txt") {
-		t.Fail()
-	}
-	if !s.IsHidden("/home/.ben/.nothing.txt") {
-		t.Fail()
-	}
-}
+// stdoutWriter writes to whatever os.Stdout currently is, so that a search's
+// output can be captured by captureOutput
+type stdoutWriter struct{}
 
-func TestDefaultExcludePaths(t *testing.T) {
-	s := NewSettings()
-	for _, entry := range defaultExcludeDirs {
-		path := filepath.Join("/tmp/project", entry)
-		if !s.ShouldExcludeDir(path) {
-			t.Fatalf("expected %s to be excluded by default as directory", entry)
-		}
-		if !s.ShouldExcludeFile(path) {
-			t.Fatalf("expected %s to be excluded by default as file", entry)
-		}
-	}
-	if s.ShouldExcludeDir("./src") {
-		t.Fatalf("did not expect src to be excluded by default")
-	}
-	if s.ShouldExcludeFile("./src/file.go") {
-		t.Fatalf("did not expect src/file.go to be excluded by default")
-	}
+func (stdoutWriter) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
 }
 
-func TestDisableDefaultExcludes(t *testing.T) {
-	s := NewSettings()
-	s.UseDefaultExcludes = false
-	for _, dir := range defaultExcludeDirs {
-		path := filepath.Join("/tmp/project", dir)
-		if s.ShouldExcludeDir(path) {
-			t.Fatalf("did not expect %s to be excluded when defaults disabled", dir)
-		}
-	}
-	s.AddExcludeDirs("vendor")
-	if !s.ShouldExcludeDir(filepath.Join("/tmp/project", "vendor")) {
-		t.Fatalf("expected vendor to be excluded when user provided")
-	}
-}
-
-func TestShouldExcludeDirUserProvided(t *testing.T) {
-	s := NewSettings()
-	s.AddExcludeDirs("vendor", "./build/")
-	if !s.ShouldExcludeDir("/tmp/project/vendor") {
-		t.Fatalf("expected vendor directory to be excluded when provided")
-	}
-	if !s.ShouldExcludeDir("./build") {
-		t.Fatalf("expected build directory to be excluded when provided")
-	}
-	if s.ShouldExcludeDir("/tmp/project/src") {
-		t.Fatalf("did not expect src directory to be excluded")
-	}
-}
-
-func TestShouldExcludeFileUserProvided(t *testing.T) {
-	s := NewSettings()
-	s.AddExcludes("README.md", "src/generated.go")
-	if !s.ShouldExcludeFile("/tmp/project/docs/README.md") {
-		t.Fatalf("expected README.md files to be excluded when provided")
-	}
-	if !s.ShouldExcludeFile("/tmp/project/src/generated.go") {
-		t.Fatalf("expected generated.go to be excluded when provided with relative path")
-	}
-	if s.ShouldExcludeFile("/tmp/project/src/generated_test.go") {
-		t.Fatalf("did not expect generated_test.go to be excluded")
-	}
-}
-
-func TestGetMatchRegex(t *testing.T) {
-	r1 := mustGetMatchRegex(t, false, false, "HEllo")
-	if !r1.MatchString("HEllo") {
-		t.Fail()
-	}
-	if r1.MatchString("hello") {
-		t.Fail()
-	}
-	r2 := mustGetMatchRegex(t, false, false, "hello")
-	//  verify smart case works
-	if !r2.MatchString("abcHEllo") {
-		t.Fail()
-	}
-	if !r2.MatchString("abchello") {
-		t.Fail()
-	}
-	if !r2.MatchString("abcHELLO") {
-		t.Fail()
-	}
-	if r2.MatchString("abc") {
-		t.Fail()
-	}
-	r3 := mustGetMatchRegex(t, true, false, "hello")
-	if !r3.MatchString("HEllo") {
-		t.Fail()
-	}
-	if !r3.MatchString("abchello") {
-		t.Fail()
-	}
-	if !r3.MatchString("abcHELLO") {
-		t.Fail()
-	}
-	if r3.MatchString("abc") {
-		t.Fail()
-	}
-	r4 := mustGetMatchRegex(t, false, true, "hello")
-	if r4.MatchString("HEllo") {
-		t.Fail()
-	}
-	if !r4.MatchString("abchello") {
-		t.Fail()
-	}
-	if r4.MatchString("abcHELLO") {
-		t.Fail()
-	}
-	if r4.MatchString("abc") {
-		t.Fail()
-	}
-}
-
-func TestGetMatchRegexInvalid(t *testing.T) {
-	_, err := getMatchRegex(false, false, "(")
-	if err == nil {
-		t.Fatalf("expected invalid regex to return an error")
-	}
-	if !strings.Contains(err.Error(), "invalid match regex") {
-		t.Fatalf("expected error to mention invalid match regex, got %q", err.Error())
-	}
-}
-
-func TestCheckForMatches(t *testing.T) {
-	resetTestState(t)
-	tmpDir := t.TempDir()
-	testFile := filepath.Join(tmpDir, "notes.txt")
-	content := []byte("no match here\nTODO: finish tests\n")
-	if err := os.WriteFile(testFile, content, 0644); err != nil {
-		t.Fatalf("writing temp file: %v", err)
-	}
-	settings.MatchRegex = regexp.MustCompile("TODO")
-
-	matches := checkForMatches(testFile)
-	if statistics.LineCount() != 2 {
-		t.Fatalf("expected 2 lines scanned, got %d", statistics.LineCount())
-	}
-	if statistics.MatchCount() != 1 {
-		t.Fatalf("expected 1 match recorded, got %d", statistics.MatchCount())
-	}
-
-	found := false
-	for _, match := range matches {
-		if match.hasMatch() {
-			found = true
-			if match.Path != testFile {
-				t.Fatalf("expected match path %q, got %q", testFile, match.Path)
-			}
-			if match.LineNumber != 2 {
-				t.Fatalf("expected match on line 2, got %d", match.LineNumber)
-			}
-			break
-		}
-	}
-	if !found {
-		t.Fatalf("expected at least one match in returned slice")
-	}
-}
-
-func TestProcessFile(t *testing.T) {
-	resetTestState(t)
-	tmpDir := t.TempDir()
-
-	hiddenDir := filepath.Join(tmpDir, ".git")
-	if err := os.Mkdir(hiddenDir, 0755); err != nil {
-		t.Fatalf("creating hidden dir: %v", err)
-	}
-	hiddenInfo, err := os.Stat(hiddenDir)
-	if err != nil {
-		t.Fatalf("stat hidden dir: %v", err)
-	}
-	if ret := processFile(hiddenDir, hiddenInfo, nil); ret != filepath.SkipDir {
-		t.Fatalf("expected hidden dir to return filepath.SkipDir, got %v", ret)
-	}
-
-	visibleFile := filepath.Join(tmpDir, "main.go")
-	if err := os.WriteFile(visibleFile, []byte("package main\n"), 0644); err != nil {
-		t.Fatalf("writing temp file: %v", err)
-	}
-	fileInfo, err := os.Stat(visibleFile)
-	if err != nil {
-		t.Fatalf("stat temp file: %v", err)
-	}
-	if ret := processFile(visibleFile, fileInfo, nil); ret != FILE_PROCESSING_COMPLETE {
-		t.Fatalf("expected file processing to return nil, got %v", ret)
-	}
-	if len(filesToScan) != 1 {
-		t.Fatalf("expected one file queued, got %d", len(filesToScan))
-	}
-	if filesToScan[0].Path != visibleFile {
-		t.Fatalf("expected queued path %q, got %q", visibleFile, filesToScan[0].Path)
-	}
-	if statistics.FilesToScanCount() != 1 {
-		t.Fatalf("expected statistics to record 1 file to scan, got %d", statistics.FilesToScanCount())
-	}
-	if statistics.FileCount() != 1 {
-		t.Fatalf("expected statistics to record 1 processed file, got %d", statistics.FileCount())
-	}
+func resetTestState(t *testing.T) {
+	t.Helper()
+	cmd = newCommand(stdoutWriter{})
+	colors.RestoreColors()
+	stdinIsPiped = func() bool { return testStdinPiped }
+	exit = func(code int) { testExitCode = code }
 }
 
-type stubFileInfo struct {
-	size int64
-	mode os.FileMode
-}
+// testExitCode holds the status main would have exited with
+var testExitCode int
 
-func (s stubFileInfo) Name() string       { return "stub" }
-func (s stubFileInfo) Size() int64        { return s.size }
-func (s stubFileInfo) Mode() os.FileMode  { return s.mode }
-func (s stubFileInfo) ModTime() time.Time { return time.Time{} }
-func (s stubFileInfo) IsDir() bool        { return s.mode.IsDir() }
-func (s stubFileInfo) Sys() interface{}   { return nil }
-
-func TestScannerBufferLimits(t *testing.T) {
-	cases := []struct {
-		name        string
-		info        os.FileInfo
-		setup       func(t *testing.T)
-		wantInitial int
-		wantMax     int
-	}{
-		{
-			name: "small file respects minimum cap",
-			info: stubFileInfo{size: 1024},
-			setup: func(t *testing.T) {
-				resetTestState(t)
-			},
-			wantInitial: scannerMinimumInitialCap,
-			wantMax:     scannerMinimumInitialCap,
-		},
-		{
-			name: "large file uses file size",
-			info: stubFileInfo{size: 10 * 1024 * 1024},
-			setup: func(t *testing.T) {
-				resetTestState(t)
-			},
-			wantInitial: scannerDefaultInitialCap,
-			wantMax:     10 * 1024 * 1024,
-		},
-		{
-			name: "huge file clamps to max token cap",
-			info: stubFileInfo{size: int64(scannerMaxTokenCap) + (32 * 1024 * 1024)},
-			setup: func(t *testing.T) {
-				resetTestState(t)
-			},
-			wantInitial: scannerDefaultInitialCap,
-			wantMax:     scannerMaxTokenCap,
-		},
-		{
-			name: "no stat info with no max line length uses max token cap",
-			info: nil,
-			setup: func(t *testing.T) {
-				resetTestState(t)
-				settings.NoMaxLineLength = true
-			},
-			wantInitial: scannerDefaultInitialCap,
-			wantMax:     scannerMaxTokenCap,
-		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			tc.setup(t)
-			t.Cleanup(func() {
-				resetTestState(t)
-			})
-			initial, max := scannerBufferLimits(tc.info)
-			if initial != tc.wantInitial {
-				t.Fatalf("initial cap = %d, want %d", initial, tc.wantInitial)
-			}
-			if max != tc.wantMax {
-				t.Fatalf("max token = %d, want %d", max, tc.wantMax)
-			}
-		})
-	}
-}
+// testStdinPiped controls whether tests see stdin as piped input, so that
+// results don't depend on how the test binary was launched.
+var testStdinPiped = false
 
 func TestIntegrationExcludeFlag(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -422,7 +103,7 @@ func runFindrefMain(t *testing.T, args []string) (string, string) {
 	oldUsage := flag.Usage
 
 	stdout, stderr := captureOutput(func() {
-		main()
+		runCLI(cmd)
 	})
 
 	flag.CommandLine = oldCommandLine
@@ -539,91 +220,6 @@ func expectNotContains(t *testing.T, lines []string, target string) {
 	}
 }
 
-func TestExcludePatternBasic(t *testing.T) {
-	s := NewSettings()
-	if err := s.AddExcludePatterns(`_test\.go$`); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !s.ShouldExcludeFile("src/foo_test.go") {
-		t.Fatalf("expected _test.go file to be excluded by pattern")
-	}
-	if s.ShouldExcludeFile("src/foo.go") {
-		t.Fatalf("did not expect foo.go to be excluded by pattern")
-	}
-}
-
-func TestExcludePatternDir(t *testing.T) {
-	s := NewSettings()
-	if err := s.AddExcludePatterns(`(^|/)generated($|/)`); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !s.ShouldExcludeDir("src/generated") {
-		t.Fatalf("expected generated dir to be excluded by pattern")
-	}
-	if s.ShouldExcludeDir("src/generator") {
-		t.Fatalf("did not expect generator dir to be excluded")
-	}
-}
-
-func TestExcludePatternInvalid(t *testing.T) {
-	s := NewSettings()
-	err := s.AddExcludePatterns("(")
-	if err == nil {
-		t.Fatalf("expected error for invalid regex pattern")
-	}
-	if !strings.Contains(err.Error(), "invalid exclude-pattern") {
-		t.Fatalf("expected error to mention invalid exclude-pattern, got %q", err.Error())
-	}
-}
-
-func TestExcludePatternMultiple(t *testing.T) {
-	s := NewSettings()
-	if err := s.AddExcludePatterns(`_test\.go$`, `\.min\.js$`); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !s.ShouldExcludeFile("src/app_test.go") {
-		t.Fatalf("expected test file to be excluded")
-	}
-	if !s.ShouldExcludeFile("dist/bundle.min.js") {
-		t.Fatalf("expected minified JS to be excluded")
-	}
-	if s.ShouldExcludeFile("src/app.go") {
-		t.Fatalf("did not expect app.go to be excluded")
-	}
-}
-
-func TestExcludePatternCombinedWithLiteral(t *testing.T) {
-	s := NewSettings()
-	s.UseDefaultExcludes = false
-	s.AddExcludes("myvendor")
-	if err := s.AddExcludePatterns(`_test\.go$`); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !s.ShouldExcludeDir("project/myvendor") {
-		t.Fatalf("expected myvendor to be excluded by literal")
-	}
-	if !s.ShouldExcludeFile("src/app_test.go") {
-		t.Fatalf("expected test file to be excluded by pattern")
-	}
-	if s.ShouldExcludeFile("src/app.go") {
-		t.Fatalf("did not expect app.go to be excluded")
-	}
-}
-
-func TestExcludePatternAccessor(t *testing.T) {
-	s := NewSettings()
-	if err := s.AddExcludePatterns(`_test\.go$`, `\.min\.js$`); err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	patterns := s.ExcludePatterns()
-	if len(patterns) != 2 {
-		t.Fatalf("expected 2 patterns, got %d", len(patterns))
-	}
-	if patterns[0] != `_test\.go$` {
-		t.Fatalf("expected first pattern %q, got %q", `_test\.go$`, patterns[0])
-	}
-}
-
 func TestIntegrationExcludePatternFlag(t *testing.T) {
 	tmpDir := t.TempDir()
 	rootFile := filepath.Join(tmpDir, "main.go")
@@ -695,6 +291,47 @@ func TestIntegrationExcludePatternShortFlag(t *testing.T) {
 	expectNotContains(t, lines, testFile)
 }
 
+func TestIntegrationListFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainGo := filepath.Join(tmpDir, "main.go")
+	mustWriteFile(t, mainGo, "package main\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "notes.txt"), "TODO\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "vendor", "dep.go"), "package dep\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--files", "--exclude", "vendor", tmpDir, `\.go$`})
+	lines := splitLines(stdout)
+	if len(lines) != 1 {
+		t.Fatalf("expected only main.go to be listed, got %v", lines)
+	}
+	expectContains(t, lines, mainGo)
+	if testExitCode != ExitCodeMatch {
+		t.Errorf("expected exit status %d, got %d", ExitCodeMatch, testExitCode)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--files", "--json", "-p", tmpDir, `\.txt$`})
+	lines = splitLines(stdout)
+	if len(lines) != 2 {
+		t.Fatalf("expected a file and a summary event, got %v", lines)
+	}
+	var file struct {
+		Type     string `json:"type"`
+		Path     string `json:"path"`
+		Size     int64  `json:"size"`
+		Modified string `json:"modified"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &file); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if file.Type != "file" || file.Path != filepath.Join(tmpDir, "notes.txt") || file.Size != 5 || file.Modified == "" {
+		t.Errorf("unexpected file event %+v", file)
+	}
+
+	runFindrefMain(t, []string{"--files", tmpDir, `\.rs$`})
+	if testExitCode != ExitCodeNoMatch {
+		t.Errorf("expected exit status %d when nothing is listed, got %d", ExitCodeNoMatch, testExitCode)
+	}
+}
+
 func TestConfigFileExcludePattern(t *testing.T) {
 	base := t.TempDir()
 	workDir := filepath.Join(base, "work")
@@ -848,8 +485,8 @@ func TestConfigFileCliOverrides(t *testing.T) {
 	if stdout == "" {
 		t.Fatalf("expected some output due to match")
 	}
-	if settings.MaxLineLength != 50 {
-		t.Fatalf("expected CLI max line length 50 to override config, got %d", settings.MaxLineLength)
+	if cmd.settings.MaxLineLength != 50 {
+		t.Fatalf("expected CLI max line length 50 to override config, got %d", cmd.settings.MaxLineLength)
 	}
 	resetTestState(t)
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --glob, --iglob, config and MCP
+// ---------------------------------------------------------------------------
+
+func TestIntegrationGlobFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "store.go"), "// TODO store\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "store_mock.go"), "// TODO mock\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "NOTES.TXT"), "TODO notes\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "testdata", "fixture.go"), "// TODO fixture\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--glob", "**/*.go", "--glob", "!**/*_mock.go", "--iglob", "*.txt", "--glob", "!testdata", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "store.go")+":1:// TODO store")
+	expectContains(t, lines, filepath.Join(tmpDir, "NOTES.TXT")+":1:TODO notes")
+	if len(lines) != 2 {
+		t.Errorf("expected 2 matches, got %v", lines)
+	}
+}
+
+func TestIntegrationGlobAnchoredAtRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "src", "a.go"), "// TODO a\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "lib", "src", "b.go"), "// TODO b\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "lib", "c.go"), "// TODO c\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--glob", "src/*.go", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "src", "a.go")+":1:// TODO a")
+	if len(lines) != 1 {
+		t.Errorf("expected only src/a.go to match, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--glob", "*.go", "--glob", "!lib/**", "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "src", "a.go")+":1:// TODO a")
+	if len(lines) != 1 {
+		t.Errorf("expected lib to be pruned, got %v", lines)
+	}
+}
+
+func TestIntegrationGlobFileRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "src", "a.go")
+	mustWriteFile(t, file, "// TODO a\n")
+
+	// A file named as the root is matched against the globs by its name
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--glob", "*.go", "TODO", file})
+	expectContains(t, splitLines(stdout), file+":1:// TODO a")
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--glob", "!*.go", "TODO", file})
+	if stdout != "" {
+		t.Errorf("expected the negated glob to exclude the file, got %q", stdout)
+	}
+}
+
+func TestConfigFileGlobs(t *testing.T) {
+	base := t.TempDir()
+	workDir := filepath.Join(base, "work")
+	homeDir := filepath.Join(base, "home")
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(homeDir, ".config"))
+
+	mustWriteFile(t, filepath.Join(workDir, ".findref.yaml"), "match_regex: TODO\nglob:\n  - '!**/*_mock.go'\n")
+	mustWriteFile(t, filepath.Join(workDir, "a.go"), "// TODO a\n")
+	mustWriteFile(t, filepath.Join(workDir, "a_mock.go"), "// TODO mock\n")
+
+	stdout, _ := runFindrefMainInDir(t, []string{"--no-color"}, workDir)
+	lines := splitLines(stdout)
+	expectContains(t, lines, "a.go:1:// TODO a")
+	if len(lines) != 1 {
+		t.Errorf("expected only a.go to match, got %v", lines)
+	}
+}
+
+func TestMCPSearchGlobs(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.go"), "TODO a\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "a_mock.go"), "TODO mock\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "B.PY"), "TODO b\n")
+
+	dir, _ := json.Marshal(tmpDir)
+	args := json.RawMessage(`{"pattern": "TODO", "directory": ` + string(dir) + `, "filename_only": true,
+		"glob": ["*.go", {"pattern": "*.PY", "ignore_case": true}, "!*_mock.go"]}`)
+	result, _ := handleSearch(context.Background(), args)
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 2 {
+		t.Fatalf("expected a.go and B.PY, got %v", filenames)
+	}
+	for _, f := range filenames {
+		if strings.Contains(f, "mock") {
+			t.Errorf("did not expect %q", f)
+		}
+	}
+
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, Glob: []globArg{{Pattern: "[a-"}}})
+	result, _ = handleSearch(context.Background(), args)
+	if !result.IsError {
+		t.Error("expected an error for an invalid glob")
+	}
+}
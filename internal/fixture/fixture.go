@@ -0,0 +1,139 @@
+// Package fixture builds the files, compressed streams and archives that the
+// tests of findref and of its search package search through
+package fixture
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// Entry is a file stored in an archive
+type Entry struct {
+	Name    string
+	Content string
+}
+
+// Zip returns a zip archive of the entries
+func Zip(t testing.TB, entries ...Entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		f, err := w.Create(e.Name)
+		if err != nil {
+			t.Fatalf("zip create %q: %v", e.Name, err)
+		}
+		f.Write([]byte(e.Content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Tar returns a tar archive of the entries
+func Tar(t testing.TB, entries ...Entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.Name, Mode: 0o644, Size: int64(len(e.Content)), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(hdr); err != nil {
+			t.Fatalf("tar header %q: %v", e.Name, err)
+		}
+		w.Write([]byte(e.Content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// Gzip returns content compressed with gzip
+func Gzip(t testing.TB, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// Zlib returns content compressed with zlib
+func Zlib(t testing.TB, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("zlib write: %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+// Bzip2 is "first\nTODO bz\n" compressed with bzip2, since the standard
+// library can only decompress that format
+const Bzip2 = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x05\xa2\x61\xf2\x00\x00\x04\x57\x80\x00\x10\x40\x00\x04\x00\x84\x00\x11\x20\x1c\x10\x20\x00\x22\x01\xa0\x68\x40\xd0\x34\x38\x84\x14\x29\xb3\x78\x9f\x17\x72\x45\x38\x50\x90\x05\xa2\x61\xf2"
+
+// UTF16 returns content encoded as UTF-16, optionally big endian and with a
+// byte order mark
+func UTF16(content string, bigEndian bool, bom bool) []byte {
+	var buf bytes.Buffer
+	units := utf16.Encode([]rune(content))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	for _, u := range units {
+		if bigEndian {
+			buf.WriteByte(byte(u >> 8))
+			buf.WriteByte(byte(u))
+		} else {
+			buf.WriteByte(byte(u))
+			buf.WriteByte(byte(u >> 8))
+		}
+	}
+	return buf.Bytes()
+}
+
+// WriteBytes writes content to path, creating its directory
+func WriteBytes(t testing.TB, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("creating directory for %q: %v", path, err)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("writing file %q: %v", path, err)
+	}
+}
+
+// Symlink links link to target, skipping the test where symlinks aren't
+// supported
+func Symlink(t testing.TB, target string, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks not supported on this system")
+	}
+}
+
+// Monorepo lays out packages/app with a link to a shared package that lives
+// outside the search root, plus a link back up to the root to form a loop
+func Monorepo(t testing.TB) (root string, shared string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "repo")
+	shared = filepath.Join(base, "shared")
+	WriteBytes(t, filepath.Join(root, "packages", "app", "main.go"), []byte("// TODO app\n"))
+	WriteBytes(t, filepath.Join(shared, "lib.go"), []byte("// TODO shared\n"))
+	Symlink(t, shared, filepath.Join(root, "packages", "app", "shared"))
+	Symlink(t, root, filepath.Join(root, "packages", "loop"))
+	return root, shared
+}
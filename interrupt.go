@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/freedomben/findref/search"
+)
+
+// interruptionExitCode returns the status findref exits with after an
+// interruption, the same as timeout(1) for timeouts and a shell's 128+SIGINT
+// for signals
+func interruptionExitCode(i search.Interruption) int {
+	if i == search.InterruptedByTimeout {
+		return ExitCodeTimeout
+	}
+	return ExitCodeInterrupted
+}
+
+// stopOnSignal stops the search on SIGINT or SIGTERM, so that the matches
+// found so far and the statistics are still printed.  A second signal exits
+// straight away.  Calling the returned function restores the default signal
+// handling.
+func (c *command) stopOnSignal(results *search.Results) func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			c.debug(colors.Blue, "Received", sig, "stopping the search", colors.Restore)
+			results.Stop()
+		case <-done:
+			return
+		}
+		select {
+		case <-signals:
+			os.Exit(ExitCodeInterrupted)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freedomben/findref/search"
+)
+
+// stallingReader returns head, then blocks for stall before reaching the
+// end, standing in for a slow file or pipe
+type stallingReader struct {
+	head  io.Reader
+	stall time.Duration
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	if n, err := r.head.Read(p); err != io.EOF {
+		return n, err
+	}
+	time.Sleep(r.stall)
+	return 0, io.EOF
+}
+
+func TestExitStatusInterrupted(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+	cmd.stats = searchStats(t, "TODO", tmpDir)
+	cmd.stats.Interrupt(search.InterruptedByCancel)
+	if got := cmd.exitStatus(); got != ExitCodeInterrupted {
+		t.Errorf("expected %d after a signal, got %d", ExitCodeInterrupted, got)
+	}
+
+	resetTestState(t)
+	cmd.stats.Interrupt(search.InterruptedByTimeout)
+	if got := cmd.exitStatus(); got != ExitCodeTimeout {
+		t.Errorf("expected %d after a timeout, got %d", ExitCodeTimeout, got)
+	}
+}
+
+func TestInterruptOnSignal(t *testing.T) {
+	resetTestState(t)
+	cmd.settings.MatchRegex = regexp.MustCompile("TODO")
+	results, err := search.Search(context.Background(), search.Options{
+		Input:    &stallingReader{head: strings.NewReader("TODO first\n"), stall: 100 * time.Millisecond},
+		Settings: cmd.settings,
+	})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	stats := results.Stats()
+	stop := cmd.stopOnSignal(results)
+	defer stop()
+	defer results.Wait()
+
+	self, _ := os.FindProcess(os.Getpid())
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skip("sending signals is not supported on this system")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !stats.Interrupted() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stats.Interruption() != search.InterruptedByCancel {
+		t.Errorf("expected SIGINT to interrupt the search, got %v", stats.Interruption())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Integration: --timeout and MCP timeout_ms
+// ---------------------------------------------------------------------------
+
+func TestIntegrationTimeoutKeepsPartialResults(t *testing.T) {
+	testStdinPiped = true
+	oldReader := stdinReader
+	stdinReader = &stallingReader{head: strings.NewReader("TODO first\n" + strings.Repeat("filler\n", 1000)), stall: 500 * time.Millisecond}
+	t.Cleanup(func() {
+		stdinReader = oldReader
+		testStdinPiped = false
+	})
+
+	stdout, stderr := runFindrefMain(t, []string{"--no-color", "--stats", "--timeout", "50ms", "TODO"})
+	lines := splitLines(stdout)
+	expectContains(t, lines, "(standard input):1:TODO first")
+	expectContains(t, lines, "Partial:       yes, the search timed out")
+	if testExitCode != ExitCodeTimeout {
+		t.Errorf("expected %d, got %d", ExitCodeTimeout, testExitCode)
+	}
+	if !strings.Contains(stderr, "results are partial") {
+		t.Errorf("expected a warning about partial results, got %q", stderr)
+	}
+}
+
+func TestMCPSearchTimeout(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+
+	timeout := 60000
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, TimeoutMs: &timeout})
+	result, _ := handleSearch(context.Background(), args)
+	var output struct {
+		TotalMatches int  `json:"total_matches"`
+		TimedOut     bool `json:"timed_out"`
+	}
+	json.Unmarshal([]byte(result.Content[0].Text), &output)
+	if output.TotalMatches != 1 || output.TimedOut {
+		t.Errorf("expected the search to finish in time, got %+v", output)
+	}
+
+	timeout = -1
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, TimeoutMs: &timeout})
+	if result, _ = handleSearch(context.Background(), args); !result.IsError {
+		t.Error("expected an error for a negative timeout_ms")
+	}
+}
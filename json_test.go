@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationJSONOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "a.txt")
+	mustWriteFile(t, f, "one\nx TODO\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "nothing\n")
+
+	stdout, stderr := runFindrefMain(t, []string{"--json", "TODO", tmpDir})
+	if stderr != "" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+	lines := splitLines(stdout)
+	if len(lines) != 4 {
+		t.Fatalf("expected begin, match, end and summary lines, got %v", lines)
+	}
+
+	var match struct {
+		Type       string `json:"type"`
+		Path       string `json:"path"`
+		LineNumber int    `json:"line_number"`
+		Text       string `json:"text"`
+		MatchStart int    `json:"match_start"`
+		MatchEnd   int    `json:"match_end"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &match); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[1], err)
+	}
+	if match.Type != "match" || match.Path != f || match.LineNumber != 2 || match.Text != "x TODO" ||
+		match.MatchStart != 2 || match.MatchEnd != 6 {
+		t.Errorf("unexpected match event %+v", match)
+	}
+
+	var summary struct {
+		Type         string `json:"type"`
+		FilesScanned int    `json:"files_scanned"`
+		TotalMatches int    `json:"total_matches"`
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &summary); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[3], err)
+	}
+	if summary.Type != "summary" || summary.FilesScanned != 2 || summary.TotalMatches != 1 {
+		t.Errorf("unexpected summary event %+v", summary)
+	}
+	if testExitCode != ExitCodeMatch {
+		t.Errorf("expected exit status %d, got %d", ExitCodeMatch, testExitCode)
+	}
+}
+
+func TestIntegrationJSONOutputWithErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "x TODO\n")
+	missing := filepath.Join(tmpDir, "missing")
+
+	// Errors go to stderr, so every line of stdout is still an event
+	stdout, stderr := runFindrefMain(t, []string{"--no-color", "--json", "-p", tmpDir, "-p", missing, "TODO"})
+	lines := splitLines(stdout)
+	if len(lines) == 0 {
+		t.Fatal("expected JSON events on stdout")
+	}
+	for _, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Errorf("stdout line %q is not JSON: %v", line, err)
+		}
+	}
+	if !strings.Contains(stderr, "[error]: ") || !strings.Contains(stderr, missing) {
+		t.Errorf("expected the error for the missing path on stderr, got %q", stderr)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --max-count and --max-results
+// ---------------------------------------------------------------------------
+
+func TestIntegrationMaxCount(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO 1\nTODO 2\nTODO 3\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "TODO 1\nTODO 2\nTODO 3\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--max-count", "2", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	if len(lines) != 4 {
+		t.Errorf("expected 2 matches from each file, got %v", lines)
+	}
+	expectNotContains(t, lines, filepath.Join(tmpDir, "a.txt")+":3:TODO 3")
+	if cmd.stats.Truncated() {
+		t.Error("did not expect --max-count to truncate the results")
+	}
+}
+
+func TestIntegrationMaxResults(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		mustWriteFile(t, filepath.Join(tmpDir, fmt.Sprintf("%d.txt", i)), "TODO 1\nTODO 2\n")
+	}
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--max-results", "3", "TODO", tmpDir})
+	if lines := splitLines(stdout); len(lines) != 3 {
+		t.Errorf("expected 3 matches, got %v", lines)
+	}
+	if !cmd.stats.Truncated() {
+		t.Error("expected the results to be truncated")
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--max-results", "2", "--filename-only", "TODO", tmpDir})
+	if lines := splitLines(stdout); len(lines) != 2 {
+		t.Errorf("expected 2 filenames, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--stats", "--max-results", "3", "TODO", tmpDir})
+	expectContains(t, splitLines(stdout), "Truncated:     yes, --max-results was reached")
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--stats", "--max-results", "10", "TODO", tmpDir})
+	if cmd.stats.Truncated() {
+		t.Error("did not expect a limit above the number of matches to truncate the results")
+	}
+	expectNotContains(t, splitLines(stdout), "Truncated:     yes, --max-results was reached")
+}
+
+func TestIntegrationMaxResultsSkipsRemainingFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	const files = 300
+	for i := 0; i < files; i++ {
+		mustWriteFile(t, filepath.Join(tmpDir, fmt.Sprintf("%03d.txt", i)), "TODO\n")
+	}
+
+	runFindrefMain(t, []string{"--no-color", "--max-results", "1", "TODO", tmpDir})
+	if cmd.stats.LineCount() >= files {
+		t.Errorf("expected the remaining files to be skipped, but %d lines were scanned", cmd.stats.LineCount())
+	}
+}
+
+func TestMCPSearchMaxResults(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO 1\nTODO 2\nTODO 3\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "TODO 1\n")
+
+	var output struct {
+		TotalMatches int  `json:"total_matches"`
+		Truncated    bool `json:"truncated"`
+	}
+	limit := 2
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxResults: &limit})
+	result, _ := handleSearch(context.Background(), args)
+	json.Unmarshal([]byte(result.Content[0].Text), &output)
+	if output.TotalMatches != 2 || !output.Truncated {
+		t.Errorf("expected 2 truncated matches, got %+v", output)
+	}
+
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxResults: &limit, FilenameOnly: true})
+	result, _ = handleSearch(context.Background(), args)
+	if len(result.Content) != 1 || strings.Contains(result.Content[0].Text, "truncated") {
+		t.Errorf("did not expect 2 files to be truncated by a limit of 2, got %+v", result.Content)
+	}
+
+	limit = 1
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxResults: &limit, FilenameOnly: true})
+	result, _ = handleSearch(context.Background(), args)
+	if len(result.Content) != 2 || !strings.Contains(result.Content[1].Text, `"truncated": true`) {
+		t.Errorf("expected the filenames to be flagged as truncated, got %+v", result.Content)
+	}
+
+	count := 1
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxCount: &count})
+	result, _ = handleSearch(context.Background(), args)
+	json.Unmarshal([]byte(result.Content[0].Text), &output)
+	if output.TotalMatches != 2 || output.Truncated {
+		t.Errorf("expected one untruncated match per file, got %+v", output)
+	}
+
+	limit = -1
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxResults: &limit})
+	result, _ = handleSearch(context.Background(), args)
+	if !result.IsError {
+		t.Error("expected an error for a negative max_results")
+	}
+}
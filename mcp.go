@@ -2,15 +2,17 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"regexp"
-	"runtime"
-	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/freedomben/findref/search"
 )
 
 // mcpOut is the real stdout, used exclusively for JSON-RPC responses.
@@ -91,17 +93,54 @@ type mcpToolResult struct {
 // ---------------------------------------------------------------------------
 
 type searchArgs struct {
-	Pattern        string   `json:"pattern"`
-	Directory      string   `json:"directory"`
-	FilePattern    string   `json:"file_pattern"`
-	Exclude        []string `json:"exclude"`
-	ExcludePattern []string `json:"exclude_pattern"`
-	IgnoreCase     bool     `json:"ignore_case"`
-	MatchCase      bool     `json:"match_case"`
-	IncludeHidden  bool     `json:"include_hidden"`
-	All            bool     `json:"all"`
-	FilenameOnly   bool     `json:"filename_only"`
-	MaxLineLength  *int     `json:"max_line_length"`
+	Pattern        string    `json:"pattern"`
+	Directory      string    `json:"directory"`
+	Directories    []string  `json:"directories"`
+	FilePattern    string    `json:"file_pattern"`
+	Exclude        []string  `json:"exclude"`
+	ExcludePattern []string  `json:"exclude_pattern"`
+	Include        []string  `json:"include"`
+	IncludePattern []string  `json:"include_pattern"`
+	FileTypes      []string  `json:"file_types"`
+	ExcludeTypes   []string  `json:"exclude_file_types"`
+	Glob           []globArg `json:"glob"`
+	IgnoreCase     bool      `json:"ignore_case"`
+	MatchCase      bool      `json:"match_case"`
+	IncludeHidden  bool      `json:"include_hidden"`
+	All            bool      `json:"all"`
+	FilenameOnly   bool      `json:"filename_only"`
+	MaxLineLength  *int      `json:"max_line_length"`
+	SearchZip      bool      `json:"search_zip"`
+	SearchArchives bool      `json:"search_archives"`
+	Follow         bool      `json:"follow"`
+	ArchiveDepth   *int      `json:"archive_depth"`
+	Encoding       string    `json:"encoding"`
+	MaxFilesize    string    `json:"max_filesize"`
+	MinFilesize    string    `json:"min_filesize"`
+	NewerThan      string    `json:"newer_than"`
+	OlderThan      string    `json:"older_than"`
+	MaxDepth       *int      `json:"max_depth"`
+	MaxCount       *int      `json:"max_count"`
+	MaxResults     *int      `json:"max_results"`
+	TimeoutMs      *int      `json:"timeout_ms"`
+	Limit          *int      `json:"limit"`
+	Cursor         string    `json:"cursor"`
+}
+
+// globArg is one entry of the ordered glob list: either a plain glob string
+// or an object that also sets ignore_case, so that case-insensitive globs keep
+// their place among the others
+type globArg struct {
+	Pattern    string `json:"pattern"`
+	IgnoreCase bool   `json:"ignore_case,omitempty"`
+}
+
+func (g *globArg) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &g.Pattern); err == nil {
+		return nil
+	}
+	type object globArg
+	return json.Unmarshal(data, (*object)(g))
 }
 
 type searchResultEntry struct {
@@ -123,27 +162,16 @@ func mcpServe() {
 	// of corrupting the JSON-RPC channel.
 	mcpOut = os.Stdout
 	os.Stdout = os.Stderr
+	colors.ZeroColors()
 
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-
-		var req jsonRPCRequest
-		if err := json.Unmarshal([]byte(line), &req); err != nil {
-			fmt.Fprintf(os.Stderr, "findref-mcp: invalid JSON: %v\n", err)
-			continue
-		}
-
-		// Notifications have no id and get no response.
-		if req.ID == nil {
-			continue
-		}
+	// Requests are read in the background so that a cancellation can reach
+	// the request it names while that request is still being worked on.
+	calls := &mcpCalls{cancels: map[string]context.CancelFunc{}}
+	requests := make(chan mcpCall, 64)
+	go readMCPRequests(os.Stdin, calls, requests)
 
+	for call := range requests {
+		req := call.req
 		resp := jsonRPCResponse{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -155,7 +183,7 @@ func mcpServe() {
 		case "tools/list":
 			resp.Result = handleToolsList()
 		case "tools/call":
-			result, err := handleToolsCall(req.Params)
+			result, err := handleToolsCall(call.ctx, req.Params)
 			if err != nil {
 				resp.Error = &jsonRPCError{Code: -32603, Message: err.Error()}
 			} else {
@@ -168,11 +196,98 @@ func mcpServe() {
 			}
 		}
 
+		if calls.finish(req.ID) {
+			// The client has stopped waiting for the response
+			continue
+		}
 		out, _ := json.Marshal(resp)
 		fmt.Fprintf(mcpOut, "%s\n", out)
 	}
 }
 
+// mcpCall is a request waiting to be handled, with the context that is
+// cancelled if the client withdraws it
+type mcpCall struct {
+	req jsonRPCRequest
+	ctx context.Context
+}
+
+// mcpCalls tracks the requests that can still be cancelled, by their id
+type mcpCalls struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func (c *mcpCalls) start(id json.RawMessage) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancels[string(id)] = cancel
+	return ctx
+}
+
+// cancel handles a notifications/cancelled message from the client
+func (c *mcpCalls) cancel(params json.RawMessage) {
+	var cancelled struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &cancelled); err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cancel, ok := c.cancels[string(cancelled.RequestID)]; ok {
+		cancel()
+		// The entry is kept, so that finish knows the request was cancelled
+		c.cancels[string(cancelled.RequestID)] = nil
+	}
+}
+
+// finish stops tracking a request once it has been handled, and reports
+// whether the client cancelled it, in which case it gets no response
+func (c *mcpCalls) finish(id json.RawMessage) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cancel, ok := c.cancels[string(id)]
+	delete(c.cancels, string(id))
+	if cancel != nil {
+		cancel()
+	}
+	return ok && cancel == nil
+}
+
+// readMCPRequests passes the JSON-RPC requests read from in to requests,
+// and handles the cancellation notifications itself
+func readMCPRequests(in io.Reader, calls *mcpCalls, requests chan<- mcpCall) {
+	defer close(requests)
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var req jsonRPCRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			fmt.Fprintf(os.Stderr, "findref-mcp: invalid JSON: %v\n", err)
+			continue
+		}
+
+		// Notifications have no id and get no response.
+		if req.ID == nil {
+			if req.Method == "notifications/cancelled" {
+				calls.cancel(req.Params)
+			}
+			continue
+		}
+
+		requests <- mcpCall{req: req, ctx: calls.start(req.ID)}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // MCP method handlers
 // ---------------------------------------------------------------------------
@@ -197,6 +312,11 @@ func handleToolsList() mcpToolsListResult {
 				"type": "string",
 				"description": "Starting directory to search (default: current working directory)."
 			},
+			"directories": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Multiple directories or individual files to search, combined with 'directory' if both are given. Overlapping entries are searched only once."
+			},
 			"file_pattern": {
 				"type": "string",
 				"description": "RE2 regex to filter which files to scan (matched against the file path). Example: '\\.go$' for Go files, '\\.(js|ts)$' for JavaScript/TypeScript."
@@ -211,6 +331,31 @@ func handleToolsList() mcpToolsListResult {
 				"items": {"type": "string"},
 				"description": "RE2 regex patterns; paths matching any pattern are excluded."
 			},
+			"include": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Search only files whose name is one of these (exact basename match)."
+			},
+			"include_pattern": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "RE2 regex patterns; only files whose path matches one of them are searched. Combined with 'include', a file matching either is searched."
+			},
+			"glob": {
+				"type": "array",
+				"items": {"anyOf": [{"type": "string"}, {"type": "object", "properties": {"pattern": {"type": "string"}, "ignore_case": {"type": "boolean"}}, "required": ["pattern"]}]},
+				"description": "Globs evaluated in order (last match wins). Plain globs include only matching files; a leading '!' excludes matching files and directories. A glob containing '/' is anchored at the search directory, one without matches file names at any depth, and '**' matches any number of directories, e.g. ['*.go', '!**/*_mock.go', {'pattern': '*.MD', 'ignore_case': true}]."
+			},
+			"file_types": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Search only files of these built-in types, matched by extension, filename and #! line (e.g. [\"go\", \"py\"]). Known types: ` + strings.Join(search.BuiltinFileTypeNames(), ", ") + `."
+			},
+			"exclude_file_types": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Skip files of these built-in types, e.g. [\"markdown\", \"json\"]."
+			},
 			"ignore_case": {
 				"type": "boolean",
 				"description": "Force case-insensitive matching (overrides smart-case). Default false."
@@ -234,6 +379,67 @@ func handleToolsList() mcpToolsListResult {
 			"max_line_length": {
 				"type": "integer",
 				"description": "Maximum line length in characters before clipping (default 2000)."
+			},
+			"search_zip": {
+				"type": "boolean",
+				"description": "Search inside gzip, bzip2 and zlib compressed files (e.g. rotated logs) by decompressing them on the fly. Default false."
+			},
+			"search_archives": {
+				"type": "boolean",
+				"description": "Search the members of zip/jar/war, tar and tar.gz archives. Matches are reported as 'archive.zip!inner/path'. Filters apply to member paths. Default false."
+			},
+			"follow": {
+				"type": "boolean",
+				"description": "Descend into symlinked directories, skipping any already searched (symlink loops). Default false."
+			},
+			"archive_depth": {
+				"type": "integer",
+				"description": "Maximum nesting of archives within archives to open with search_archives (default 2)."
+			},
+			"max_filesize": {
+				"type": "string",
+				"description": "Skip files larger than this size: bytes, or with a K, M, G or T suffix (e.g. '512K', '10M')."
+			},
+			"min_filesize": {
+				"type": "string",
+				"description": "Skip files smaller than this size (same format as max_filesize)."
+			},
+			"newer_than": {
+				"type": "string",
+				"description": "Only search files modified after this duration ago ('90m', '36h', '7d', '2w') or date ('2024-05-01', RFC 3339)."
+			},
+			"older_than": {
+				"type": "string",
+				"description": "Only search files last modified before this duration ago or date."
+			},
+			"max_depth": {
+				"type": "integer",
+				"description": "Descend at most this many directories below each search directory. 1 searches only the files directly inside it."
+			},
+			"max_count": {
+				"type": "integer",
+				"description": "Stop searching a file after this many matching lines."
+			},
+			"timeout_ms": {
+				"type": "integer",
+				"description": "Stop the search after this many milliseconds and return the matches found so far, with timed_out and truncated set."
+			},
+			"limit": {
+				"type": "integer",
+				"description": "Return at most this many matches (or filenames with filename_only), sorted by path and line. If there are more, the response includes a next_cursor to fetch the next page with. Default 0 (no limit)."
+			},
+			"cursor": {
+				"type": "string",
+				"description": "The next_cursor of a previous response, to fetch the page after it. The other arguments must be the same as in that call, apart from limit. A cursor expires once its results are no longer cached, and the search has to be run again without it."
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Stop the search after this many matches (or files with filename_only). The result reports truncated: true when the limit cut the search short, a hint to refine the query."
+			},
+			"encoding": {
+				"type": "string",
+				"enum": ["auto", "utf-8", "utf-16le", "utf-16be", "latin1"],
+				"description": "Encoding of the searched files. 'auto' (default) detects byte order marks, BOM-less UTF-16 and Latin-1 and transcodes them to UTF-8 before matching."
 			}
 		},
 		"required": ["pattern"]
@@ -245,6 +451,162 @@ func handleToolsList() mcpToolsListResult {
 		"additionalProperties": false
 	}`)
 
+	listFilesSchema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"directory": {
+				"type": "string",
+				"description": "Starting directory to list (default: current working directory)."
+			},
+			"directories": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Multiple directories or individual files to list, combined with 'directory' if both are given. Overlapping entries are listed only once."
+			},
+			"file_pattern": {
+				"type": "string",
+				"description": "RE2 regex the file path must match. Example: '\\.go$' for Go files."
+			},
+			"exclude": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Directory or file names to exclude (exact basename match). These are added on top of the defaults."
+			},
+			"exclude_pattern": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "RE2 regex patterns; paths matching any pattern are excluded."
+			},
+			"include": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "List only files whose name is one of these (exact basename match)."
+			},
+			"include_pattern": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "RE2 regex patterns; only files whose path matches one of them are listed. Combined with 'include', a file matching either is listed."
+			},
+			"glob": {
+				"type": "array",
+				"items": {"anyOf": [{"type": "string"}, {"type": "object", "properties": {"pattern": {"type": "string"}, "ignore_case": {"type": "boolean"}}, "required": ["pattern"]}]},
+				"description": "Globs evaluated in order (last match wins), as for search, e.g. ['*.go', '!vendor/**']."
+			},
+			"file_types": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "List only files of these built-in types, e.g. [\"go\", \"py\"]."
+			},
+			"exclude_file_types": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Skip files of these built-in types, e.g. [\"markdown\", \"json\"]."
+			},
+			"include_hidden": {
+				"type": "boolean",
+				"description": "Include hidden files and directories (names starting with '.'). Default false."
+			},
+			"all": {
+				"type": "boolean",
+				"description": "Include hidden files and disable the default excludes. Default false."
+			},
+			"search_archives": {
+				"type": "boolean",
+				"description": "List zip/jar/war, tar and tar.gz archives whatever their names, since search_archives would search their members. Default false."
+			},
+			"follow": {
+				"type": "boolean",
+				"description": "Descend into symlinked directories, skipping any already listed (symlink loops). Default false."
+			},
+			"max_filesize": {
+				"type": "string",
+				"description": "Skip files larger than this size: bytes, or with a K, M, G or T suffix (e.g. '512K', '10M')."
+			},
+			"min_filesize": {
+				"type": "string",
+				"description": "Skip files smaller than this size (same format as max_filesize)."
+			},
+			"newer_than": {
+				"type": "string",
+				"description": "Only list files modified after this duration ago ('90m', '36h', '7d', '2w') or date ('2024-05-01', RFC 3339)."
+			},
+			"older_than": {
+				"type": "string",
+				"description": "Only list files last modified before this duration ago or date."
+			},
+			"max_depth": {
+				"type": "integer",
+				"description": "Descend at most this many directories below each directory. 1 lists only the files directly inside it."
+			},
+			"max_results": {
+				"type": "integer",
+				"description": "Return at most this many files. The result reports truncated: true when there were more."
+			},
+			"timeout_ms": {
+				"type": "integer",
+				"description": "Stop listing after this many milliseconds and return the files found so far, with timed_out and truncated set."
+			},
+			"details": {
+				"type": "boolean",
+				"description": "Include each file's size in bytes and modification time (RFC 3339, UTC). Default false."
+			}
+		}
+	}`)
+
+	readFileSchema := json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {
+				"type": "string",
+				"description": "File to read, e.g. a file returned by search. UTF-16 and Latin-1 files are decoded to UTF-8 as search decodes them."
+			},
+			"start_line": {
+				"type": "integer",
+				"description": "First line to return, counting from 1. Default 1."
+			},
+			"end_line": {
+				"type": "integer",
+				"description": "Last line to return. Default start_line + 199."
+			},
+			"max_bytes": {
+				"type": "integer",
+				"description": "Stop once the returned text reaches this many bytes, setting truncated. Default 65536."
+			},
+			"directory": {
+				"type": "string",
+				"description": "Directory the file must be within, as for search (default: current working directory)."
+			},
+			"directories": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Directories the file may be within, combined with 'directory' if both are given."
+			},
+			"exclude": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "Directory or file names to exclude, as for search."
+			},
+			"exclude_pattern": {
+				"type": "array",
+				"items": {"type": "string"},
+				"description": "RE2 regex patterns of paths to exclude, as for search."
+			},
+			"include_hidden": {
+				"type": "boolean",
+				"description": "Allow hidden files and directories. Default false."
+			},
+			"all": {
+				"type": "boolean",
+				"description": "Allow hidden files and files excluded by default, like search's 'all'. Default false."
+			},
+			"follow": {
+				"type": "boolean",
+				"description": "Allow files reached through symlinked directories. Default false."
+			}
+		},
+		"required": ["path"]
+	}`)
+
 	return mcpToolsListResult{
 		Tools: []mcpTool{
 			{
@@ -252,6 +614,16 @@ func handleToolsList() mcpToolsListResult {
 				Description: "Search for text patterns in files using RE2 regular expressions. Recursively scans directories, automatically skipping binary files, VCS metadata, lock files, and common build artifacts by default.",
 				InputSchema: searchSchema,
 			},
+			{
+				Name:        "list_files",
+				Description: "List the files a search would scan, applying the same filters (default excludes, hidden files, include/exclude, file_pattern, globs, file types, size and time limits) without reading them. Optionally returns sizes and modification times.",
+				InputSchema: listFilesSchema,
+			},
+			{
+				Name:        "read_file",
+				Description: "Read a range of lines of a file, with line numbers, e.g. to see the code around a search match. Only files that a search of the given directories would read are allowed; binary files are refused.",
+				InputSchema: readFileSchema,
+			},
 			{
 				Name:        "list_default_excludes",
 				Description: "List the directories and files excluded from search by default (VCS dirs, lock files, build artifacts). Useful for understanding what is filtered before running a search.",
@@ -261,7 +633,7 @@ func handleToolsList() mcpToolsListResult {
 	}
 }
 
-func handleToolsCall(params json.RawMessage) (*mcpToolResult, error) {
+func handleToolsCall(ctx context.Context, params json.RawMessage) (*mcpToolResult, error) {
 	var call mcpToolCallParams
 	if err := json.Unmarshal(params, &call); err != nil {
 		return nil, fmt.Errorf("invalid tool call params: %w", err)
@@ -269,7 +641,11 @@ func handleToolsCall(params json.RawMessage) (*mcpToolResult, error) {
 
 	switch call.Name {
 	case "search":
-		return handleSearch(call.Arguments)
+		return handleSearch(ctx, call.Arguments)
+	case "list_files":
+		return handleListFiles(ctx, call.Arguments)
+	case "read_file":
+		return handleReadFile(call.Arguments)
 	case "list_default_excludes":
 		return handleListDefaultExcludes()
 	default:
@@ -278,13 +654,13 @@ func handleToolsCall(params json.RawMessage) (*mcpToolResult, error) {
 }
 
 func handleListDefaultExcludes() (*mcpToolResult, error) {
-	result, _ := json.Marshal(defaultExcludeDirs)
+	result, _ := json.Marshal(search.DefaultExcludes())
 	return &mcpToolResult{
 		Content: []mcpContent{{Type: "text", Text: string(result)}},
 	}, nil
 }
 
-func handleSearch(argsJSON json.RawMessage) (*mcpToolResult, error) {
+func handleSearch(ctx context.Context, argsJSON json.RawMessage) (*mcpToolResult, error) {
 	var args searchArgs
 	if err := json.Unmarshal(argsJSON, &args); err != nil {
 		return &mcpToolResult{
@@ -300,36 +676,35 @@ func handleSearch(argsJSON json.RawMessage) (*mcpToolResult, error) {
 		}, nil
 	}
 
-	// Reset global state for this search invocation.
-	settings = NewSettings()
-	statistics = NewStatistics()
-	colors.ZeroColors()
-	filenameOnlyFiles = make([]string, 0, 100)
-	filesToScan = make([]FileToScan, 0, 100)
-
-	// Apply arguments to settings.
-	allEnabled := args.All
-	settings.IncludeHidden = args.IncludeHidden || allEnabled
-	settings.FilenameOnly = args.FilenameOnly
-	settings.UseDefaultExcludes = !allEnabled
-
-	if args.MaxLineLength != nil {
-		settings.MaxLineLength = *args.MaxLineLength
-	}
-	if len(args.Exclude) > 0 {
-		settings.AddExcludes(args.Exclude...)
+	settings, result := filterSettings(args)
+	if result != nil {
+		return result, nil
 	}
-	if len(args.ExcludePattern) > 0 {
-		if err := settings.AddExcludePatterns(args.ExcludePattern...); err != nil {
+	settings.FilenameOnly = args.FilenameOnly
+	settings.SearchZip = args.SearchZip
+	if args.ArchiveDepth != nil {
+		if *args.ArchiveDepth < 1 {
 			return &mcpToolResult{
-				Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("invalid exclude_pattern: %v", err)}},
+				Content: []mcpContent{{Type: "text", Text: "archive_depth must be at least 1"}},
 				IsError: true,
 			}, nil
 		}
+		settings.ArchiveDepth = *args.ArchiveDepth
+	}
+	encoding, err := search.ParseEncoding(args.Encoding)
+	if err != nil {
+		return &mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}, nil
+	}
+	settings.Encoding = encoding
+	if args.MaxLineLength != nil {
+		settings.MaxLineLength = *args.MaxLineLength
 	}
 
-	ignoreCase := args.IgnoreCase || allEnabled
-	matchRegex, err := getMatchRegex(ignoreCase, args.MatchCase, args.Pattern)
+	ignoreCase := args.IgnoreCase || args.All
+	matchRegex, err := search.CompileMatchRegex(ignoreCase, args.MatchCase, args.Pattern)
 	if err != nil {
 		return &mcpToolResult{
 			Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("invalid pattern: %v", err)}},
@@ -337,86 +712,259 @@ func handleSearch(argsJSON json.RawMessage) (*mcpToolResult, error) {
 		}, nil
 	}
 	settings.MatchRegex = matchRegex
+	roots := args.roots()
 
-	if args.FilePattern != "" {
-		filenameRegex, err := regexp.Compile(args.FilePattern)
-		if err != nil {
+	limit := 0
+	if args.Limit != nil {
+		if *args.Limit < 0 {
 			return &mcpToolResult{
-				Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("invalid file_pattern: %v", err)}},
+				Content: []mcpContent{{Type: "text", Text: "limit must not be negative"}},
 				IsError: true,
 			}, nil
 		}
-		settings.FilenameRegex = filenameRegex
-	}
-
-	rootDir := "."
-	if args.Directory != "" {
-		rootDir = args.Directory
+		limit = *args.Limit
 	}
 
-	statistics.startTime = time.Now()
-
-	// Walk the directory tree to collect eligible files.
-	filepath.Walk(rootDir, processFile)
-
-	// Fan out to worker goroutines.
-	runtime.GOMAXPROCS(runtime.NumCPU())
-	jobs := make(chan string, len(filesToScan))
-	results := make(chan []Match, 100)
-
-	numWorkers := runtime.NumCPU()
-	for w := 0; w < numWorkers; w++ {
-		go worker(w, jobs, results)
-	}
-	for _, val := range filesToScan {
-		jobs <- val.Path
-	}
-	close(jobs)
-
-	// Collect match results.
-	var allMatches []searchResultEntry
-	for r := 0; r < len(filesToScan); r++ {
-		batch := <-results
-		for _, m := range batch {
-			if m.hasMatch() {
-				allMatches = append(allMatches, searchResultEntry{
-					File:       m.Path,
-					Line:       m.LineNumber,
-					Text:       string(m.Line),
-					MatchStart: m.Match[0],
-					MatchEnd:   m.Match[1],
-				})
+	// A cursor continues from a result set found by an earlier call, and
+	// expires once that set has dropped out of the cache, rather than
+	// silently paging through a new search whose results may have changed
+	key := args.queryKey()
+	offset := 0
+	var set *mcpResultSet
+	if args.Cursor != "" {
+		cursorKey, cursorOffset, err := decodeCursor(args.Cursor)
+		if err == nil && cursorKey != key {
+			err = fmt.Errorf("%s", "cursor belongs to a search with different arguments")
+		}
+		if err == nil {
+			var cached bool
+			if set, cached = mcpResults.get(key); !cached {
+				err = errCursorExpired
 			}
 		}
+		if err != nil {
+			return &mcpToolResult{
+				Content: []mcpContent{{Type: "text", Text: err.Error()}},
+				IsError: true,
+			}, nil
+		}
+		offset = cursorOffset
+	}
+	if set == nil {
+		results, err := search.Search(ctx, search.Options{Paths: roots, Settings: settings})
+		if err != nil {
+			return &mcpToolResult{
+				Content: []mcpContent{{Type: "text", Text: err.Error()}},
+				IsError: true,
+			}, nil
+		}
+		set = collectResultSet(results)
+		if ctx.Err() == nil {
+			mcpResults.put(key, set)
+		}
 	}
 
 	// Filename-only mode: return sorted unique filenames.
 	if settings.FilenameOnly {
-		filenames := uniq(filenameOnlyFiles)
-		sort.Strings(filenames)
-		resultJSON, _ := json.Marshal(filenames)
-		return &mcpToolResult{
-			Content: []mcpContent{{Type: "text", Text: string(resultJSON)}},
-		}, nil
+		start, end, nextCursor := page(key, len(set.filenames), offset, limit)
+		resultJSON, _ := json.Marshal(set.filenames[start:end])
+		content := []mcpContent{{Type: "text", Text: string(resultJSON)}}
+		if set.truncated || nextCursor != "" {
+			// The filenames stay a plain array, so flag the truncation and
+			// the next page separately
+			fields := []string{}
+			if set.truncated {
+				fields = append(fields, `"truncated": true`)
+			}
+			if nextCursor != "" {
+				fields = append(fields, fmt.Sprintf(`"next_cursor": %q`, nextCursor))
+			}
+			content = append(content, mcpContent{Type: "text", Text: "{" + strings.Join(fields, ", ") + "}"})
+		}
+		return &mcpToolResult{Content: content}, nil
 	}
 
 	// Normal mode: return structured match data.
-	if allMatches == nil {
-		allMatches = []searchResultEntry{}
-	}
+	start, end, nextCursor := page(key, len(set.matches), offset, limit)
 	output := struct {
 		Matches      []searchResultEntry `json:"matches"`
 		TotalFiles   int                 `json:"total_files_scanned"`
 		TotalLines   int                 `json:"total_lines_scanned"`
 		TotalMatches int                 `json:"total_matches"`
+		Truncated    bool                `json:"truncated"`
+		TimedOut     bool                `json:"timed_out"`
+		NextCursor   string              `json:"next_cursor,omitempty"`
 	}{
-		Matches:      allMatches,
-		TotalFiles:   statistics.FileCount(),
-		TotalLines:   statistics.LineCount(),
-		TotalMatches: len(allMatches),
+		Matches:      set.matches[start:end],
+		TotalFiles:   set.filesScanned,
+		TotalLines:   set.linesScanned,
+		TotalMatches: len(set.matches),
+		Truncated:    set.truncated,
+		TimedOut:     set.timedOut,
+		NextCursor:   nextCursor,
 	}
 	resultJSON, _ := json.Marshal(output)
 	return &mcpToolResult{
 		Content: []mcpContent{{Type: "text", Text: string(resultJSON)}},
 	}, nil
 }
+
+// filterSettings returns the settings that choose which files a call covers,
+// shared by search and list_files: the hidden, exclude, include, filename
+// and type filters, the size, time, depth and result limits, and the timeout
+func filterSettings(args searchArgs) (*search.Settings, *mcpToolResult) {
+	toolError := func(text string) (*search.Settings, *mcpToolResult) {
+		return nil, &mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: text}},
+			IsError: true,
+		}
+	}
+
+	// Each call gets settings of its own, so calls can run side by side
+	settings := search.NewSettings()
+	// MCP clients get plain text, including in the debug output on stderr
+	settings.Colors.ZeroColors()
+	settings.IncludeHidden = args.IncludeHidden || args.All
+	settings.UseDefaultExcludes = !args.All
+	settings.SearchArchives = args.SearchArchives
+	settings.FollowSymlinks = args.Follow
+	if result := applyMetadataArgs(settings, args); result != nil {
+		return nil, result
+	}
+
+	if len(args.Exclude) > 0 {
+		settings.AddExcludes(args.Exclude...)
+	}
+	if len(args.ExcludePattern) > 0 {
+		if err := settings.AddExcludePatterns(args.ExcludePattern...); err != nil {
+			return toolError(fmt.Sprintf("invalid exclude_pattern: %v", err))
+		}
+	}
+	settings.AddIncludes(args.Include...)
+	if err := settings.AddIncludePatterns(args.IncludePattern...); err != nil {
+		return toolError(fmt.Sprintf("invalid include_pattern: %v", err))
+	}
+	for _, g := range args.Glob {
+		if err := settings.AddGlobs(g.IgnoreCase, g.Pattern); err != nil {
+			return toolError(err.Error())
+		}
+	}
+	if err := settings.SelectTypes(args.FileTypes...); err != nil {
+		return toolError(fmt.Sprintf("invalid file_types: %v", err))
+	}
+	if err := settings.ExcludeTypes(args.ExcludeTypes...); err != nil {
+		return toolError(fmt.Sprintf("invalid exclude_file_types: %v", err))
+	}
+
+	if args.FilePattern != "" {
+		filenameRegex, err := regexp.Compile(args.FilePattern)
+		if err != nil {
+			return toolError(fmt.Sprintf("invalid file_pattern: %v", err))
+		}
+		settings.FilenameRegex = filenameRegex
+	}
+
+	if args.TimeoutMs != nil {
+		if *args.TimeoutMs < 0 {
+			return toolError("timeout_ms must not be negative")
+		}
+		settings.Timeout = time.Duration(*args.TimeoutMs) * time.Millisecond
+	}
+	return settings, nil
+}
+
+// roots returns the directories and files a call covers, directory first
+func (args searchArgs) roots() []string {
+	roots := []string{}
+	if args.Directory != "" {
+		roots = append(roots, args.Directory)
+	}
+	return append(roots, args.Directories...)
+}
+
+// collectResultSet gathers the results of a search, sorted by path and then
+// line so that pages of them can be returned in a stable order
+func collectResultSet(results *search.Results) *mcpResultSet {
+	collector := &search.Collector{}
+	stats := results.DeliverSorted(collector)
+
+	set := &mcpResultSet{
+		matches:      []searchResultEntry{},
+		filenames:    collector.Files,
+		filesScanned: stats.FileCount(),
+		linesScanned: stats.LineCount(),
+		truncated:    stats.Truncated() || stats.Interrupted(),
+		timedOut:     stats.Interruption() == search.InterruptedByTimeout,
+	}
+	if set.filenames == nil {
+		set.filenames = []string{}
+	}
+	for _, m := range collector.Matches {
+		if m.Binary {
+			continue
+		}
+		set.matches = append(set.matches, searchResultEntry{
+			File:       m.Path,
+			Line:       m.LineNumber,
+			Text:       string(m.Line),
+			MatchStart: m.Match[0],
+			MatchEnd:   m.Match[1],
+		})
+		set.size += len(m.Path) + len(m.Line)
+	}
+	for _, filename := range set.filenames {
+		set.size += len(filename)
+	}
+	return set
+}
+
+// applyMetadataArgs applies the size, time, depth and result limits of a search,
+// returning an error result if any of them is invalid
+func applyMetadataArgs(settings *search.Settings, args searchArgs) *mcpToolResult {
+	var err error
+	toolError := func(name string, err error) *mcpToolResult {
+		return &mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("invalid %s: %v", name, err)}},
+			IsError: true,
+		}
+	}
+	if args.MaxFilesize != "" {
+		if settings.MaxFilesize, err = search.ParseSize(args.MaxFilesize); err != nil {
+			return toolError("max_filesize", err)
+		}
+	}
+	if args.MinFilesize != "" {
+		if settings.MinFilesize, err = search.ParseSize(args.MinFilesize); err != nil {
+			return toolError("min_filesize", err)
+		}
+	}
+	if args.NewerThan != "" {
+		if settings.NewerThan, err = search.ParseTimeBound(args.NewerThan, time.Now()); err != nil {
+			return toolError("newer_than", err)
+		}
+	}
+	if args.OlderThan != "" {
+		if settings.OlderThan, err = search.ParseTimeBound(args.OlderThan, time.Now()); err != nil {
+			return toolError("older_than", err)
+		}
+	}
+	if args.MaxDepth != nil {
+		if *args.MaxDepth < 0 {
+			return toolError("max_depth", fmt.Errorf("must not be negative"))
+		}
+		settings.MaxDepth = *args.MaxDepth
+	}
+	if args.MaxCount != nil {
+		if *args.MaxCount < 0 {
+			return toolError("max_count", fmt.Errorf("must not be negative"))
+		}
+		settings.MaxCount = *args.MaxCount
+	}
+	if args.MaxResults != nil {
+		if *args.MaxResults < 0 {
+			return toolError("max_results", fmt.Errorf("must not be negative"))
+		}
+		settings.MaxResults = *args.MaxResults
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// mcpCachedResultSets and mcpCachedResultBytes bound how many complete search
+// results the MCP server keeps, and how much of their text, so that the later
+// pages of a search don't scan the files again
+const (
+	mcpCachedResultSets  = 8
+	mcpCachedResultBytes = 64 << 20
+)
+
+// errCursorExpired is returned for a cursor whose result set has dropped out
+// of the cache
+var errCursorExpired = fmt.Errorf("%s", "cursor expired: its results are no longer cached, so run the search again without a cursor")
+
+// mcpResultSet is the complete, sorted result of an MCP search, which the
+// pages of a paginated response are cut from
+type mcpResultSet struct {
+	matches      []searchResultEntry
+	filenames    []string
+	filesScanned int
+	linesScanned int
+	truncated    bool
+	timedOut     bool
+	// size is roughly how many bytes the paths and lines of the set take up
+	size int
+}
+
+// mcpResultCache holds the most recent result sets by the key of their query,
+// up to maxSets of them and maxBytes of their sizes in total
+type mcpResultCache struct {
+	mu       sync.Mutex
+	keys     []string
+	cache    map[string]*mcpResultSet
+	bytes    int
+	maxSets  int
+	maxBytes int
+}
+
+func newMCPResultCache(maxSets int, maxBytes int) *mcpResultCache {
+	return &mcpResultCache{cache: map[string]*mcpResultSet{}, maxSets: maxSets, maxBytes: maxBytes}
+}
+
+var mcpResults = newMCPResultCache(mcpCachedResultSets, mcpCachedResultBytes)
+
+func (c *mcpResultCache) get(key string) (*mcpResultSet, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	set, ok := c.cache[key]
+	return set, ok
+}
+
+// put stores set under key, dropping the oldest sets until the cache is back
+// within its bounds.  A set larger than the whole cache isn't stored.
+func (c *mcpResultCache) put(key string, set *mcpResultSet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if set.size > c.maxBytes {
+		return
+	}
+	if old, ok := c.cache[key]; ok {
+		c.bytes -= old.size
+	} else {
+		c.keys = append(c.keys, key)
+	}
+	c.cache[key] = set
+	c.bytes += set.size
+	for len(c.keys) > c.maxSets || c.bytes > c.maxBytes {
+		c.bytes -= c.cache[c.keys[0]].size
+		delete(c.cache, c.keys[0])
+		c.keys = c.keys[1:]
+	}
+}
+
+// queryKey identifies the results a search asks for, ignoring the arguments
+// that only choose which page of them is returned
+func (args searchArgs) queryKey() string {
+	args.Limit = nil
+	args.Cursor = ""
+	query, _ := json.Marshal(args)
+	sum := sha256.Sum256(query)
+	return hex.EncodeToString(sum[:8])
+}
+
+// encodeCursor returns the opaque cursor of the page of the query key that
+// starts at offset
+func encodeCursor(key string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + ":" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (key string, offset int, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	key, offsetText, found := strings.Cut(string(decoded), ":")
+	offset, err = strconv.Atoi(offsetText)
+	if !found || err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return key, offset, nil
+}
+
+// page returns the limit results of a set of n starting at offset, and the
+// cursor of the page after it, which is empty on the last page.  A limit of
+// 0 returns all the remaining results.
+func page(key string, n int, offset int, limit int) (start int, end int, nextCursor string) {
+	start = min(offset, n)
+	end = n
+	if limit > 0 && start+limit < n {
+		end = start + limit
+		nextCursor = encodeCursor(key, end)
+	}
+	return start, end, nextCursor
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type pagedOutput struct {
+	Matches      []searchResultEntry `json:"matches"`
+	TotalMatches int                 `json:"total_matches"`
+	NextCursor   string              `json:"next_cursor"`
+}
+
+func searchPage(t *testing.T, args searchArgs) pagedOutput {
+	t.Helper()
+	argsJSON, _ := json.Marshal(args)
+	result, err := handleSearch(context.Background(), argsJSON)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %+v", err, result)
+	}
+	var output pagedOutput
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &output); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	return output
+}
+
+func TestMCPSearchPagination(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	for _, name := range []string{"c.txt", "a.txt", "b.txt"} {
+		mustWriteFile(t, filepath.Join(tmpDir, name), "TODO 1\nnothing\nTODO 3\n")
+	}
+
+	limit := 4
+	args := searchArgs{Pattern: "TODO", Directory: tmpDir, Limit: &limit}
+	first := searchPage(t, args)
+	if len(first.Matches) != 4 || first.TotalMatches != 6 || first.NextCursor == "" {
+		t.Fatalf("expected the first 4 of 6 matches and a cursor, got %+v", first)
+	}
+
+	// The next page comes from the cache, so a file removed in between is
+	// still part of it
+	os.Remove(filepath.Join(tmpDir, "c.txt"))
+	args.Cursor = first.NextCursor
+	second := searchPage(t, args)
+	if len(second.Matches) != 2 || second.NextCursor != "" {
+		t.Fatalf("expected the last 2 matches and no cursor, got %+v", second)
+	}
+
+	var got []string
+	for _, m := range append(first.Matches, second.Matches...) {
+		got = append(got, fmt.Sprintf("%s:%d", filepath.Base(m.File), m.Line))
+	}
+	want := "a.txt:1,a.txt:3,b.txt:1,b.txt:3,c.txt:1,c.txt:3"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected the pages to be sorted by path and line, got %v", got)
+	}
+}
+
+func TestMCPSearchPaginationAfterEviction(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO 1\nTODO 2\nTODO 3\n")
+
+	limit := 2
+	args := searchArgs{Pattern: "TODO", Directory: tmpDir, Limit: &limit}
+	first := searchPage(t, args)
+
+	// Push the result set out of the cache, so its cursor has expired
+	for i := 0; i < mcpCachedResultSets; i++ {
+		searchPage(t, searchArgs{Pattern: fmt.Sprintf("other%d", i), Directory: tmpDir})
+	}
+	if _, ok := mcpResults.get(args.queryKey()); ok {
+		t.Fatal("expected the first result set to have been evicted")
+	}
+
+	args.Cursor = first.NextCursor
+	argsJSON, _ := json.Marshal(args)
+	result, err := handleSearch(context.Background(), argsJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "cursor expired") {
+		t.Errorf("expected a cursor expired error result, got %+v", result)
+	}
+}
+
+func TestMCPResultCacheBoundedBySize(t *testing.T) {
+	cache := newMCPResultCache(8, 100)
+	cache.put("a", &mcpResultSet{size: 40})
+	cache.put("b", &mcpResultSet{size: 40})
+	cache.put("c", &mcpResultSet{size: 40})
+	if _, ok := cache.get("a"); ok {
+		t.Error("expected the oldest set to be evicted once the cache is over its size")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := cache.get(key); !ok {
+			t.Errorf("expected set %q to still be cached", key)
+		}
+	}
+
+	// Replacing a set counts only its new size
+	cache.put("c", &mcpResultSet{size: 60})
+	if _, ok := cache.get("b"); !ok || cache.bytes != 100 {
+		t.Errorf("expected sets b and c to fill the cache, got %d bytes", cache.bytes)
+	}
+
+	cache.put("huge", &mcpResultSet{size: 101})
+	if _, ok := cache.get("huge"); ok {
+		t.Error("expected a set larger than the cache not to be stored")
+	}
+	if _, ok := cache.get("b"); !ok {
+		t.Error("expected a set too large to store not to evict the others")
+	}
+}
+
+func TestMCPSearchPaginationFilenameOnly(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		mustWriteFile(t, filepath.Join(tmpDir, name), "TODO\n")
+	}
+
+	limit := 2
+	args := searchArgs{Pattern: "TODO", Directory: tmpDir, FilenameOnly: true, Limit: &limit}
+	argsJSON, _ := json.Marshal(args)
+	result, _ := handleSearch(context.Background(), argsJSON)
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 2 || len(result.Content) != 2 {
+		t.Fatalf("expected 2 filenames and a cursor, got %+v", result.Content)
+	}
+	var extra struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	json.Unmarshal([]byte(result.Content[1].Text), &extra)
+
+	args.Cursor = extra.NextCursor
+	argsJSON, _ = json.Marshal(args)
+	result, _ = handleSearch(context.Background(), argsJSON)
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 1 || filepath.Base(filenames[0]) != "c.txt" || len(result.Content) != 1 {
+		t.Errorf("expected only c.txt on the last page, got %+v", result.Content)
+	}
+}
+
+func TestMCPSearchPaginationErrors(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	negative := -1
+	otherQuery := encodeCursor(searchArgs{Pattern: "other"}.queryKey(), 2)
+	for _, tc := range []struct {
+		args searchArgs
+		want string
+	}{
+		{searchArgs{Pattern: "TODO", Directory: tmpDir, Limit: &negative}, "limit must not be negative"},
+		{searchArgs{Pattern: "TODO", Directory: tmpDir, Cursor: "not a cursor"}, "invalid cursor"},
+		{searchArgs{Pattern: "TODO", Directory: tmpDir, Cursor: otherQuery}, "different arguments"},
+	} {
+		args, _ := json.Marshal(tc.args)
+		result, err := handleSearch(context.Background(), args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].Text, tc.want) {
+			t.Errorf("expected a %q error result, got %+v", tc.want, result)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/freedomben/findref/search"
+)
+
+// listFilesArgs takes the file selecting arguments of search, plus details
+// to return each file's size and modification time
+type listFilesArgs struct {
+	searchArgs
+	Details bool `json:"details"`
+}
+
+type listedFile struct {
+	Path     string `json:"path"`
+	Size     *int64 `json:"size,omitempty"`
+	Modified string `json:"modified,omitempty"`
+}
+
+func handleListFiles(ctx context.Context, argsJSON json.RawMessage) (*mcpToolResult, error) {
+	var args listFilesArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return &mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: fmt.Sprintf("invalid arguments: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	settings, result := filterSettings(args.searchArgs)
+	if result != nil {
+		return result, nil
+	}
+	files, stats := search.ListFiles(ctx, search.Options{Paths: args.roots(), Settings: settings})
+
+	totalFiles := len(files)
+	truncated := stats.Interrupted()
+	if settings.MaxResults > 0 && len(files) > settings.MaxResults {
+		files = files[:settings.MaxResults]
+		truncated = true
+	}
+	entries := make([]listedFile, 0, len(files))
+	for _, f := range files {
+		entry := listedFile{Path: f.Path}
+		if args.Details && f.Info != nil {
+			size := f.Info.Size()
+			entry.Size = &size
+			entry.Modified = f.Info.ModTime().UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+
+	output := struct {
+		Files      []listedFile `json:"files"`
+		TotalFiles int          `json:"total_files"`
+		Truncated  bool         `json:"truncated"`
+		TimedOut   bool         `json:"timed_out"`
+	}{
+		Files:      entries,
+		TotalFiles: totalFiles,
+		Truncated:  truncated,
+		TimedOut:   stats.Interruption() == search.InterruptedByTimeout,
+	}
+	resultJSON, _ := json.Marshal(output)
+	return &mcpToolResult{
+		Content: []mcpContent{{Type: "text", Text: string(resultJSON)}},
+	}, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+type listFilesOutput struct {
+	Files      []listedFile `json:"files"`
+	TotalFiles int          `json:"total_files"`
+	Truncated  bool         `json:"truncated"`
+	TimedOut   bool         `json:"timed_out"`
+}
+
+func listFiles(t *testing.T, argsJSON string) (listFilesOutput, *mcpToolResult) {
+	t.Helper()
+	result, err := handleListFiles(context.Background(), json.RawMessage(argsJSON))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var output listFilesOutput
+	if !result.IsError {
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &output); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+	}
+	return output, result
+}
+
+func listedPaths(output listFilesOutput) []string {
+	paths := []string{}
+	for _, f := range output.Files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestMCPListFilesFilters(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "main.go"), "package main\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "README.md"), "# readme\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "vendor", "dep", "dep.go"), "package dep\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "internal", "util.go"), "package internal\n")
+	mustWriteFile(t, filepath.Join(tmpDir, ".hidden", "secret.go"), "package secret\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "node_modules", "lib.js"), "lib\n")
+
+	dir, _ := json.Marshal(tmpDir)
+	output, result := listFiles(t, `{"directory": `+string(dir)+`, "file_types": ["go"], "exclude": ["vendor"]}`)
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", result.Content[0].Text)
+	}
+	want := []string{filepath.Join(tmpDir, "internal", "util.go"), filepath.Join(tmpDir, "main.go")}
+	got := listedPaths(output)
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if output.TotalFiles != 2 || output.Truncated {
+		t.Errorf("expected 2 files and no truncation, got %+v", output)
+	}
+	if output.Files[0].Size != nil || output.Files[0].Modified != "" {
+		t.Errorf("expected no details unless asked for, got %+v", output.Files[0])
+	}
+
+	output, _ = listFiles(t, `{"directory": `+string(dir)+`, "file_pattern": "\\.md$"}`)
+	if got := listedPaths(output); len(got) != 1 || got[0] != filepath.Join(tmpDir, "README.md") {
+		t.Errorf("expected only README.md, got %v", got)
+	}
+
+	output, _ = listFiles(t, `{"directory": `+string(dir)+`, "all": true, "file_pattern": "\\.go$"}`)
+	if len(output.Files) != 4 {
+		t.Errorf("expected hidden and vendored Go files with all, got %v", listedPaths(output))
+	}
+}
+
+func TestMCPListFilesDetailsAndLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "12345\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "b\n")
+
+	dir, _ := json.Marshal(tmpDir)
+	output, _ := listFiles(t, `{"directory": `+string(dir)+`, "details": true, "max_results": 1}`)
+	if len(output.Files) != 1 || output.TotalFiles != 2 || !output.Truncated {
+		t.Fatalf("expected 1 of 2 files with truncated set, got %+v", output)
+	}
+	f := output.Files[0]
+	if f.Path != filepath.Join(tmpDir, "a.txt") || f.Size == nil || *f.Size != 6 || f.Modified == "" {
+		t.Errorf("expected a.txt with its size and modification time, got %+v", f)
+	}
+}
+
+func TestMCPListFilesInvalidArgs(t *testing.T) {
+	for _, args := range []string{
+		`{"file_pattern": "("}`,
+		`{"file_types": ["nosuchtype"]}`,
+		`{"max_depth": -1}`,
+		`{"timeout_ms": -5}`,
+	} {
+		if _, result := listFiles(t, args); !result.IsError {
+			t.Errorf("expected an error result for %s", args)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/freedomben/findref/search"
+)
+
+const (
+	// readFileDefaultLines is how many lines read_file returns when no
+	// end_line is given
+	readFileDefaultLines = 200
+	// readFileDefaultMaxBytes bounds the text read_file returns when no
+	// max_bytes is given
+	readFileDefaultMaxBytes = 64 * 1024
+)
+
+type readFileArgs struct {
+	Path           string   `json:"path"`
+	StartLine      *int     `json:"start_line"`
+	EndLine        *int     `json:"end_line"`
+	MaxBytes       *int     `json:"max_bytes"`
+	Directory      string   `json:"directory"`
+	Directories    []string `json:"directories"`
+	Exclude        []string `json:"exclude"`
+	ExcludePattern []string `json:"exclude_pattern"`
+	IncludeHidden  bool     `json:"include_hidden"`
+	All            bool     `json:"all"`
+	Follow         bool     `json:"follow"`
+}
+
+type readFileLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+func handleReadFile(argsJSON json.RawMessage) (*mcpToolResult, error) {
+	toolError := func(text string) (*mcpToolResult, error) {
+		return &mcpToolResult{
+			Content: []mcpContent{{Type: "text", Text: text}},
+			IsError: true,
+		}, nil
+	}
+
+	var args readFileArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return toolError(fmt.Sprintf("invalid arguments: %v", err))
+	}
+	if args.Path == "" {
+		return toolError("path is required")
+	}
+
+	startLine := 1
+	if args.StartLine != nil {
+		if *args.StartLine < 1 {
+			return toolError("start_line must be at least 1")
+		}
+		startLine = *args.StartLine
+	}
+	endLine := startLine + readFileDefaultLines - 1
+	if args.EndLine != nil {
+		if *args.EndLine < startLine {
+			return toolError("end_line must not be before start_line")
+		}
+		endLine = *args.EndLine
+	}
+	maxBytes := readFileDefaultMaxBytes
+	if args.MaxBytes != nil {
+		if *args.MaxBytes < 1 {
+			return toolError("max_bytes must be at least 1")
+		}
+		maxBytes = *args.MaxBytes
+	}
+
+	settings := search.NewSettings()
+	settings.IncludeHidden = args.IncludeHidden || args.All
+	settings.UseDefaultExcludes = !args.All
+	settings.FollowSymlinks = args.Follow
+	settings.AddExcludes(args.Exclude...)
+	if err := settings.AddExcludePatterns(args.ExcludePattern...); err != nil {
+		return toolError(fmt.Sprintf("invalid exclude_pattern: %v", err))
+	}
+
+	roots := []string{}
+	if args.Directory != "" {
+		roots = append(roots, args.Directory)
+	}
+	roots = append(roots, args.Directories...)
+	path, ok := searchablePath(settings, search.NormalizeRoots(roots), args.Path)
+	if !ok {
+		return toolError(fmt.Sprintf("%s is not a file that a search of the given directories would read (outside them, excluded or hidden)", args.Path))
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return toolError(err.Error())
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return toolError(fmt.Sprintf("%s is a directory", args.Path))
+	}
+
+	buffered := bufio.NewReader(file)
+	if head, _ := buffered.Peek(search.BinarySniffSize); search.LooksBinary(head) {
+		return toolError(fmt.Sprintf("%s is a binary file", args.Path))
+	}
+
+	// The lines are decoded like a search decodes them, so that UTF-16 and
+	// Latin-1 files read the same as their matches
+	reader := bufio.NewReader(search.NewDecodingReader(buffered, search.EncodingAuto))
+	lines, truncated, endOfFile, err := readLineRange(reader, startLine, endLine, maxBytes)
+	if err != nil {
+		return toolError(fmt.Sprintf("reading %s: %v", args.Path, err))
+	}
+	output := struct {
+		Path      string         `json:"path"`
+		StartLine int            `json:"start_line"`
+		EndLine   int            `json:"end_line"`
+		Lines     []readFileLine `json:"lines"`
+		Truncated bool           `json:"truncated"`
+		EndOfFile bool           `json:"end_of_file"`
+	}{
+		Path:      path,
+		StartLine: startLine,
+		EndLine:   startLine + len(lines) - 1,
+		Lines:     lines,
+		Truncated: truncated,
+		EndOfFile: endOfFile,
+	}
+	resultJSON, _ := json.Marshal(output)
+	return &mcpToolResult{
+		Content: []mcpContent{{Type: "text", Text: string(resultJSON)}},
+	}, nil
+}
+
+// searchablePath returns path as a search of one of roots would reach it,
+// and whether any of them would read it at all.  No roots stands for the
+// current directory, like it does for a search.
+func searchablePath(settings *search.Settings, roots []string, path string) (string, bool) {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	for _, root := range roots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(absRoot, absPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		walked := filepath.Join(root, rel)
+		if settings.PathExcluded(root, walked) || (!settings.FollowSymlinks && linksThroughDir(root, rel)) {
+			continue
+		}
+		return walked, true
+	}
+	return "", false
+}
+
+// linksThroughDir reports whether one of the directories on the way from
+// root to rel is a symlink, which a search only descends into with --follow
+func linksThroughDir(root string, rel string) bool {
+	dir := root
+	for _, name := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if name == "." {
+			continue
+		}
+		dir = filepath.Join(dir, name)
+		if info, err := os.Lstat(dir); err != nil || info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// readLineRange reads lines startLine to endLine, stopping early once their
+// text reaches maxBytes.  truncated is set if it stopped early, and endOfFile
+// if the file ended before endLine.
+func readLineRange(reader *bufio.Reader, startLine int, endLine int, maxBytes int) (lines []readFileLine, truncated bool, endOfFile bool, err error) {
+	lines = []readFileLine{}
+	budget := maxBytes
+	for lineNumber := 1; lineNumber <= endLine; lineNumber++ {
+		var line []byte
+		wanted := lineNumber >= startLine
+		for {
+			chunk, err := reader.ReadSlice('\n')
+			if wanted && len(line) <= budget {
+				line = append(line, chunk[:min(len(chunk), budget+1-len(line))]...)
+			}
+			if err == bufio.ErrBufferFull {
+				continue
+			}
+			if err == io.EOF {
+				if len(chunk) == 0 && (len(line) == 0 || !wanted) {
+					return lines, false, true, nil
+				}
+				endOfFile = true
+			} else if err != nil {
+				return lines, false, false, err
+			}
+			break
+		}
+		if !wanted {
+			continue
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > budget {
+			if len(lines) == 0 {
+				// Part of a line is better than nothing at all, as long as
+				// no character is cut in half
+				cut := budget
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				lines = append(lines, readFileLine{Line: lineNumber, Text: string(line[:cut])})
+			}
+			return lines, true, false, nil
+		}
+		budget -= len(line)
+		lines = append(lines, readFileLine{Line: lineNumber, Text: string(line)})
+		if endOfFile {
+			return lines, false, true, nil
+		}
+	}
+	return lines, false, false, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+type readFileOutput struct {
+	Path      string         `json:"path"`
+	StartLine int            `json:"start_line"`
+	EndLine   int            `json:"end_line"`
+	Lines     []readFileLine `json:"lines"`
+	Truncated bool           `json:"truncated"`
+	EndOfFile bool           `json:"end_of_file"`
+}
+
+func readFile(t *testing.T, args readFileArgs) (readFileOutput, *mcpToolResult) {
+	t.Helper()
+	argsJSON, _ := json.Marshal(args)
+	result, err := handleReadFile(argsJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var output readFileOutput
+	if !result.IsError {
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &output); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+	}
+	return output, result
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestMCPReadFileRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "main.go")
+	mustWriteFile(t, f, "one\r\ntwo\nthree\nfour\nfive")
+
+	output, _ := readFile(t, readFileArgs{Path: f, Directory: tmpDir, StartLine: intPtr(2), EndLine: intPtr(3)})
+	if output.StartLine != 2 || output.EndLine != 3 || output.EndOfFile || output.Truncated {
+		t.Errorf("unexpected range %+v", output)
+	}
+	if len(output.Lines) != 2 || output.Lines[0] != (readFileLine{2, "two"}) || output.Lines[1] != (readFileLine{3, "three"}) {
+		t.Errorf("unexpected lines %+v", output.Lines)
+	}
+
+	output, _ = readFile(t, readFileArgs{Path: f, Directory: tmpDir, StartLine: intPtr(4)})
+	if len(output.Lines) != 2 || output.Lines[1] != (readFileLine{5, "five"}) || !output.EndOfFile {
+		t.Errorf("expected the rest of the file, got %+v", output)
+	}
+
+	output, _ = readFile(t, readFileArgs{Path: f, Directory: tmpDir})
+	if output.Lines[0].Text != "one" || output.EndLine != 5 {
+		t.Errorf("expected the whole file with CRLF stripped, got %+v", output)
+	}
+
+	output, _ = readFile(t, readFileArgs{Path: f, Directory: tmpDir, StartLine: intPtr(10)})
+	if len(output.Lines) != 0 || !output.EndOfFile {
+		t.Errorf("expected no lines past the end of the file, got %+v", output)
+	}
+}
+
+func TestMCPReadFileMaxBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "a.txt")
+	mustWriteFile(t, f, "12345\n67890\nabcde\n")
+
+	output, _ := readFile(t, readFileArgs{Path: f, Directory: tmpDir, MaxBytes: intPtr(12)})
+	if len(output.Lines) != 2 || !output.Truncated || output.EndLine != 2 {
+		t.Errorf("expected 2 lines within 12 bytes, got %+v", output)
+	}
+
+	long := filepath.Join(tmpDir, "long.txt")
+	mustWriteFile(t, long, strings.Repeat("é", 100)+"\n")
+	output, _ = readFile(t, readFileArgs{Path: long, Directory: tmpDir, MaxBytes: intPtr(5)})
+	if len(output.Lines) != 1 || output.Lines[0].Text != "éé" || !output.Truncated {
+		t.Errorf("expected the start of the long line, got %+v", output)
+	}
+}
+
+func TestMCPReadFileUTF16(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, tc := range []struct {
+		name      string
+		bigEndian bool
+		bom       bool
+	}{
+		{"le.txt", false, false},
+		{"be-bom.txt", true, true},
+	} {
+		f := filepath.Join(tmpDir, tc.name)
+		fixture.WriteBytes(t, f, fixture.UTF16("one\nTODO two\nthree\n", tc.bigEndian, tc.bom))
+
+		// The lines read the same as the matches a search reports in them
+		output, result := readFile(t, readFileArgs{Path: f, Directory: tmpDir, StartLine: intPtr(2), EndLine: intPtr(2)})
+		if result.IsError || len(output.Lines) != 1 || output.Lines[0] != (readFileLine{2, "TODO two"}) {
+			t.Errorf("%s: expected the decoded second line, got %+v", tc.name, result)
+		}
+	}
+}
+
+func TestMCPReadFileRestricted(t *testing.T) {
+	tmpDir := t.TempDir()
+	root := filepath.Join(tmpDir, "root")
+	mustWriteFile(t, filepath.Join(root, "ok.txt"), "fine\n")
+	mustWriteFile(t, filepath.Join(root, ".env"), "SECRET=1\n")
+	mustWriteFile(t, filepath.Join(root, "node_modules", "dep.js"), "dep\n")
+	mustWriteFile(t, filepath.Join(root, "build", "out.txt"), "out\n")
+	mustWriteFile(t, filepath.Join(root, "app.bin"), "TODO\x00binary\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "outside.txt"), "outside\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "other", "linked.txt"), "linked\n")
+	if err := os.Symlink(filepath.Join(tmpDir, "other"), filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	if _, result := readFile(t, readFileArgs{Path: filepath.Join(root, "ok.txt"), Directory: root}); result.IsError {
+		t.Errorf("expected ok.txt to be readable, got %+v", result)
+	}
+	for name, args := range map[string]readFileArgs{
+		"outside the root":     {Path: filepath.Join(tmpDir, "outside.txt"), Directory: root},
+		"escaping with ..":     {Path: filepath.Join(root, "..", "outside.txt"), Directory: root},
+		"hidden":               {Path: filepath.Join(root, ".env"), Directory: root},
+		"excluded by default":  {Path: filepath.Join(root, "node_modules", "dep.js"), Directory: root},
+		"excluded":             {Path: filepath.Join(root, "build", "out.txt"), Directory: root, Exclude: []string{"build"}},
+		"excluded by pattern":  {Path: filepath.Join(root, "ok.txt"), Directory: root, ExcludePattern: []string{`ok\.txt$`}},
+		"through a linked dir": {Path: filepath.Join(root, "link", "linked.txt"), Directory: root},
+		"binary":               {Path: filepath.Join(root, "app.bin"), Directory: root},
+		"a directory":          {Path: filepath.Join(root, "build"), Directory: root},
+		"missing":              {Path: filepath.Join(root, "missing.txt"), Directory: root},
+		"without a path":       {Directory: root},
+		"a bad line range":     {Path: filepath.Join(root, "ok.txt"), Directory: root, StartLine: intPtr(3), EndLine: intPtr(2)},
+		"a bad max_bytes":      {Path: filepath.Join(root, "ok.txt"), Directory: root, MaxBytes: intPtr(0)},
+	} {
+		if _, result := readFile(t, args); !result.IsError {
+			t.Errorf("expected an error result for a file %s, got %+v", name, result)
+		}
+	}
+
+	allowed := map[string]readFileArgs{
+		"hidden with include_hidden": {Path: filepath.Join(root, ".env"), Directory: root, IncludeHidden: true},
+		"excluded by default, all":   {Path: filepath.Join(root, "node_modules", "dep.js"), Directory: root, All: true},
+		"linked with follow":         {Path: filepath.Join(root, "link", "linked.txt"), Directory: root, Follow: true},
+		"in a second directory":      {Path: filepath.Join(tmpDir, "outside.txt"), Directories: []string{root, filepath.Join(tmpDir, "outside.txt")}},
+	}
+	for name, args := range allowed {
+		if _, result := readFile(t, args); result.IsError {
+			t.Errorf("expected a file %s to be readable, got %+v", name, result)
+		}
+	}
+}
+
+func TestMCPReadFileRelativeToWorkingDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "src", "a.go"), "package src\n")
+	defer chdirHelper(t, tmpDir)()
+
+	output, result := readFile(t, readFileArgs{Path: "src/a.go"})
+	if result.IsError || output.Path != filepath.Join("src", "a.go") || output.Lines[0].Text != "package src" {
+		t.Errorf("expected src/a.go to be read relative to the working directory, got %+v", result)
+	}
+}
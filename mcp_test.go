@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/freedomben/findref/search"
 )
 
 // ---------------------------------------------------------------------------
@@ -37,8 +42,8 @@ func TestMCPInitialize(t *testing.T) {
 func TestMCPToolsList(t *testing.T) {
 	result := handleToolsList()
 
-	if len(result.Tools) != 2 {
-		t.Fatalf("expected 2 tools, got %d", len(result.Tools))
+	if len(result.Tools) != 4 {
+		t.Fatalf("expected 4 tools, got %d", len(result.Tools))
 	}
 
 	names := map[string]bool{}
@@ -60,6 +65,12 @@ func TestMCPToolsList(t *testing.T) {
 	if !names["list_default_excludes"] {
 		t.Error("expected list_default_excludes tool")
 	}
+	if !names["read_file"] {
+		t.Error("expected read_file tool")
+	}
+	if !names["list_files"] {
+		t.Error("expected list_files tool")
+	}
 }
 
 func TestMCPSearchToolSchema(t *testing.T) {
@@ -87,6 +98,7 @@ func TestMCPSearchToolSchema(t *testing.T) {
 		"pattern", "directory", "file_pattern", "exclude",
 		"exclude_pattern", "ignore_case", "match_case",
 		"include_hidden", "all", "filename_only", "max_line_length",
+		"include", "include_pattern", "file_types", "exclude_file_types",
 	}
 	for _, prop := range expectedProps {
 		if _, exists := props[prop]; !exists {
@@ -109,7 +121,7 @@ func TestMCPSearchToolSchema(t *testing.T) {
 
 func TestMCPToolsCallUnknownTool(t *testing.T) {
 	params, _ := json.Marshal(mcpToolCallParams{Name: "nonexistent", Arguments: json.RawMessage(`{}`)})
-	_, err := handleToolsCall(params)
+	_, err := handleToolsCall(context.Background(), params)
 	if err == nil {
 		t.Fatal("expected error for unknown tool")
 	}
@@ -119,7 +131,7 @@ func TestMCPToolsCallUnknownTool(t *testing.T) {
 }
 
 func TestMCPToolsCallInvalidJSON(t *testing.T) {
-	_, err := handleToolsCall(json.RawMessage(`{invalid`))
+	_, err := handleToolsCall(context.Background(), json.RawMessage(`{invalid`))
 	if err == nil {
 		t.Fatal("expected error for invalid JSON")
 	}
@@ -148,8 +160,8 @@ func TestMCPListDefaultExcludes(t *testing.T) {
 	if err := json.Unmarshal([]byte(result.Content[0].Text), &excludes); err != nil {
 		t.Fatalf("content is not valid JSON array: %v", err)
 	}
-	if len(excludes) != len(defaultExcludeDirs) {
-		t.Errorf("expected %d excludes, got %d", len(defaultExcludeDirs), len(excludes))
+	if len(excludes) != len(search.DefaultExcludes()) {
+		t.Errorf("expected %d excludes, got %d", len(search.DefaultExcludes()), len(excludes))
 	}
 
 	// Spot check a few entries
@@ -178,7 +190,7 @@ func TestMCPSearchBasic(t *testing.T) {
 		Pattern:   "^func",
 		Directory: tmpDir,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -242,7 +254,7 @@ func TestMCPSearchFilePattern(t *testing.T) {
 		Directory:   tmpDir,
 		FilePattern: `\.go$`,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -276,7 +288,7 @@ func TestMCPSearchExclude(t *testing.T) {
 		Directory: tmpDir,
 		Exclude:   []string{"skipme"},
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -308,7 +320,7 @@ func TestMCPSearchExcludePattern(t *testing.T) {
 		Directory:      tmpDir,
 		ExcludePattern: []string{`_test\.go$`},
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -329,6 +341,66 @@ func TestMCPSearchExcludePattern(t *testing.T) {
 	}
 }
 
+func TestMCPSearchInclude(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "main.go"), "TODO main\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "util.go"), "TODO util\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "docs", "notes.md"), "TODO notes\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "README.txt"), "TODO readme\n")
+
+	searchFiles := func(args searchArgs) []string {
+		t.Helper()
+		args.Pattern, args.Directory, args.FilenameOnly = "TODO", tmpDir, true
+		argsJSON, _ := json.Marshal(args)
+		result, err := handleSearch(context.Background(), argsJSON)
+		if err != nil || result.IsError {
+			t.Fatalf("unexpected error: %v %+v", err, result)
+		}
+		var filenames []string
+		json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+		for i, f := range filenames {
+			filenames[i], _ = filepath.Rel(tmpDir, f)
+		}
+		return filenames
+	}
+
+	if got := searchFiles(searchArgs{Include: []string{"main.go"}}); strings.Join(got, ",") != "main.go" {
+		t.Errorf("expected only main.go with include, got %v", got)
+	}
+	if got := searchFiles(searchArgs{IncludePattern: []string{`\.md$`}}); strings.Join(got, ",") != filepath.Join("docs", "notes.md") {
+		t.Errorf("expected only the markdown file with include_pattern, got %v", got)
+	}
+	got := searchFiles(searchArgs{Include: []string{"README.txt"}, IncludePattern: []string{`util`}})
+	if strings.Join(got, ",") != "README.txt,util.go" {
+		t.Errorf("expected a file matching either include to be searched, got %v", got)
+	}
+	got = searchFiles(searchArgs{ExcludeTypes: []string{"go", "markdown"}})
+	if strings.Join(got, ",") != "README.txt" {
+		t.Errorf("expected exclude_file_types to skip the Go and markdown files, got %v", got)
+	}
+}
+
+func TestMCPSearchInvalidIncludeAndTypes(t *testing.T) {
+	resetTestState(t)
+	for _, tc := range []struct {
+		args searchArgs
+		want string
+	}{
+		{searchArgs{Pattern: "TODO", IncludePattern: []string{"("}}, "invalid include_pattern"},
+		{searchArgs{Pattern: "TODO", ExcludeTypes: []string{"nope"}}, "invalid exclude_file_types"},
+	} {
+		args, _ := json.Marshal(tc.args)
+		result, err := handleSearch(context.Background(), args)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].Text, tc.want) {
+			t.Errorf("expected a %q error result, got %+v", tc.want, result)
+		}
+	}
+}
+
 // ---------------------------------------------------------------------------
 // handleSearch: filename_only mode
 // ---------------------------------------------------------------------------
@@ -345,7 +417,7 @@ func TestMCPSearchFilenameOnly(t *testing.T) {
 		Directory:    tmpDir,
 		FilenameOnly: true,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -388,7 +460,7 @@ func TestMCPSearchIgnoreCase(t *testing.T) {
 		Directory:  tmpDir,
 		IgnoreCase: true,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -413,7 +485,7 @@ func TestMCPSearchMatchCase(t *testing.T) {
 		Directory: tmpDir,
 		MatchCase: true,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -438,7 +510,7 @@ func TestMCPSearchSmartCase(t *testing.T) {
 		Pattern:   "hello",
 		Directory: tmpDir,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -457,7 +529,7 @@ func TestMCPSearchSmartCase(t *testing.T) {
 		Pattern:   "Hello",
 		Directory: tmpDir,
 	})
-	result2, _ := handleSearch(args2)
+	result2, _ := handleSearch(context.Background(), args2)
 	var output2 struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -483,7 +555,7 @@ func TestMCPSearchIncludeHidden(t *testing.T) {
 		Pattern:   "TODO",
 		Directory: tmpDir,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 	var output struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -498,7 +570,7 @@ func TestMCPSearchIncludeHidden(t *testing.T) {
 		Directory:     tmpDir,
 		IncludeHidden: true,
 	})
-	result2, _ := handleSearch(args2)
+	result2, _ := handleSearch(context.Background(), args2)
 	var output2 struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -524,7 +596,7 @@ func TestMCPSearchAllMode(t *testing.T) {
 		Directory: tmpDir,
 		All:       true,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 	var output struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -548,7 +620,7 @@ func TestMCPSearchNoMatches(t *testing.T) {
 		Pattern:   "ZZZZNOTFOUND",
 		Directory: tmpDir,
 	})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -577,7 +649,7 @@ func TestMCPSearchNoMatches(t *testing.T) {
 func TestMCPSearchEmptyPattern(t *testing.T) {
 	resetTestState(t)
 	args, _ := json.Marshal(searchArgs{Pattern: ""})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -592,7 +664,7 @@ func TestMCPSearchEmptyPattern(t *testing.T) {
 func TestMCPSearchInvalidPattern(t *testing.T) {
 	resetTestState(t)
 	args, _ := json.Marshal(searchArgs{Pattern: "["})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -607,7 +679,7 @@ func TestMCPSearchInvalidPattern(t *testing.T) {
 func TestMCPSearchInvalidFilePattern(t *testing.T) {
 	resetTestState(t)
 	args, _ := json.Marshal(searchArgs{Pattern: "TODO", FilePattern: "["})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -622,7 +694,7 @@ func TestMCPSearchInvalidFilePattern(t *testing.T) {
 func TestMCPSearchInvalidExcludePattern(t *testing.T) {
 	resetTestState(t)
 	args, _ := json.Marshal(searchArgs{Pattern: "TODO", ExcludePattern: []string{"("}})
-	result, err := handleSearch(args)
+	result, err := handleSearch(context.Background(), args)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -635,7 +707,7 @@ func TestMCPSearchInvalidExcludePattern(t *testing.T) {
 }
 
 func TestMCPSearchInvalidArguments(t *testing.T) {
-	result, err := handleSearch(json.RawMessage(`{invalid`))
+	result, err := handleSearch(context.Background(), json.RawMessage(`{invalid`))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -658,7 +730,7 @@ func TestMCPSearchBinarySkipped(t *testing.T) {
 		Pattern:   "TODO",
 		Directory: tmpDir,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 	var output struct {
 		Matches []searchResultEntry `json:"matches"`
 	}
@@ -686,7 +758,7 @@ func TestMCPSearchDefaultExcludes(t *testing.T) {
 		Pattern:   "TODO",
 		Directory: tmpDir,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 	var output struct {
 		Matches []searchResultEntry `json:"matches"`
 	}
@@ -720,7 +792,7 @@ func TestMCPSearchMaxLineLength(t *testing.T) {
 		Directory:     tmpDir,
 		MaxLineLength: &maxLen,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 	var output struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -742,7 +814,7 @@ func TestMCPSearchStateReset(t *testing.T) {
 
 	// First search
 	args1, _ := json.Marshal(searchArgs{Pattern: "alpha", Directory: tmpDir})
-	result1, _ := handleSearch(args1)
+	result1, _ := handleSearch(context.Background(), args1)
 	var output1 struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -753,7 +825,7 @@ func TestMCPSearchStateReset(t *testing.T) {
 
 	// Second search — should not carry over state from first
 	args2, _ := json.Marshal(searchArgs{Pattern: "beta", Directory: tmpDir})
-	result2, _ := handleSearch(args2)
+	result2, _ := handleSearch(context.Background(), args2)
 	var output2 struct {
 		TotalMatches int `json:"total_matches"`
 	}
@@ -763,6 +835,38 @@ func TestMCPSearchStateReset(t *testing.T) {
 	}
 }
 
+func TestMCPSearchConcurrent(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		mustWriteFile(t, filepath.Join(tmpDir, fmt.Sprintf("%02d.txt", i)), "alpha\nbeta\nbeta\n")
+	}
+
+	// Each call has its own Searcher, so running them at once mustn't mix results
+	var wg sync.WaitGroup
+	errs := make(chan string, 20)
+	for i := 0; i < 10; i++ {
+		for pattern, want := range map[string]int{"alpha": 20, "beta": 20} {
+			wg.Add(1)
+			go func(pattern string, want int) {
+				defer wg.Done()
+				args, _ := json.Marshal(searchArgs{Pattern: pattern, Directory: tmpDir, FilenameOnly: true})
+				result, _ := handleSearch(context.Background(), args)
+				var filenames []string
+				json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+				if len(filenames) != want {
+					errs <- fmt.Sprintf("%s: expected %d files, got %d", pattern, want, len(filenames))
+				}
+			}(pattern, want)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // handleSearch: nested directories
 // ---------------------------------------------------------------------------
@@ -778,7 +882,7 @@ func TestMCPSearchNestedDirs(t *testing.T) {
 		Directory:    tmpDir,
 		FilenameOnly: true,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 
 	var filenames []string
 	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
@@ -800,7 +904,7 @@ func TestMCPSearchEmptyDir(t *testing.T) {
 		Pattern:   "TODO",
 		Directory: tmpDir,
 	})
-	result, _ := handleSearch(args)
+	result, _ := handleSearch(context.Background(), args)
 
 	var output struct {
 		Matches      []searchResultEntry `json:"matches"`
@@ -912,3 +1016,114 @@ func TestMCPServeRoundTrip(t *testing.T) {
 		t.Error("expected error for unknown method")
 	}
 }
+
+func TestMCPCallsCancel(t *testing.T) {
+	calls := &mcpCalls{cancels: map[string]context.CancelFunc{}}
+	kept := calls.start(json.RawMessage(`2`))
+	cancelled := calls.start(json.RawMessage(`"abc"`))
+
+	calls.cancel(json.RawMessage(`{"requestId":"abc","reason":"user gave up"}`))
+	calls.cancel(json.RawMessage(`{"requestId":99}`))
+	if cancelled.Err() == nil {
+		t.Error("expected the named request to be cancelled")
+	}
+	if kept.Err() != nil {
+		t.Error("did not expect the other request to be cancelled")
+	}
+
+	if !calls.finish(json.RawMessage(`"abc"`)) {
+		t.Error("expected the cancelled request to get no response")
+	}
+	if calls.finish(json.RawMessage(`2`)) {
+		t.Error("expected the finished request to get a response")
+	}
+	if len(calls.cancels) != 0 {
+		t.Errorf("expected finished requests to be forgotten, got %v", calls.cancels)
+	}
+}
+
+func TestMCPSearchCancelled(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir})
+	result, err := handleSearch(ctx, args)
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %+v", err, result)
+	}
+	var output struct {
+		Truncated bool `json:"truncated"`
+		TimedOut  bool `json:"timed_out"`
+	}
+	json.Unmarshal([]byte(result.Content[0].Text), &output)
+	if !output.Truncated {
+		t.Errorf("expected a cancelled search to be reported as truncated, got %s", result.Content[0].Text)
+	}
+	if output.TimedOut {
+		t.Errorf("did not expect a cancelled search to be reported as timed out, got %s", result.Content[0].Text)
+	}
+}
+
+func TestReadMCPRequestsCancellation(t *testing.T) {
+	resetTestState(t)
+	calls := &mcpCalls{cancels: map[string]context.CancelFunc{}}
+	requests := make(chan mcpCall, 64)
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list","params":{}}`,
+	}, "\n")
+	readMCPRequests(strings.NewReader(input), calls, requests)
+
+	var ids []string
+	for call := range requests {
+		ids = append(ids, string(call.req.ID))
+		if string(call.req.ID) == "1" && call.ctx.Err() == nil {
+			t.Error("expected request 1 to be cancelled")
+		}
+		if string(call.req.ID) == "2" && call.ctx.Err() != nil {
+			t.Error("did not expect request 2 to be cancelled")
+		}
+	}
+	if strings.Join(ids, ",") != "1,2" {
+		t.Errorf("expected requests 1 and 2 to be queued, got %v", ids)
+	}
+	if !calls.finish(json.RawMessage(`1`)) || calls.finish(json.RawMessage(`2`)) {
+		t.Error("expected only request 1 to go without a response")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// handleSearch: directories
+// ---------------------------------------------------------------------------
+
+func TestMCPSearchDirectories(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "src", "a.go"), "TODO a\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "test", "b.go"), "TODO b\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "other", "c.go"), "TODO c\n")
+
+	args, _ := json.Marshal(searchArgs{
+		Pattern:      "TODO",
+		Directory:    filepath.Join(tmpDir, "src"),
+		Directories:  []string{filepath.Join(tmpDir, "test"), filepath.Join(tmpDir, "src")},
+		FilenameOnly: true,
+	})
+	result, _ := handleSearch(context.Background(), args)
+
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+
+	if len(filenames) != 2 {
+		t.Fatalf("expected 2 filenames, got %v", filenames)
+	}
+	for _, f := range filenames {
+		if strings.Contains(f, "other") {
+			t.Errorf("did not expect %q to be searched", f)
+		}
+	}
+}
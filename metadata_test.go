@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: size, time and depth flags
+// ---------------------------------------------------------------------------
+
+func TestIntegrationFilesizeFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "small.txt"), "TODO small\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "big.txt"), "TODO big\n"+strings.Repeat("x", 4096)+"\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--max-filesize", "1K", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "small.txt")+":1:TODO small")
+	if len(lines) != 1 {
+		t.Errorf("expected only small.txt, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--min-filesize", "1K", "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectContains(t, lines, filepath.Join(tmpDir, "big.txt")+":1:TODO big")
+	if len(lines) != 1 {
+		t.Errorf("expected only big.txt, got %v", lines)
+	}
+}
+
+func TestIntegrationModifiedFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	fresh := filepath.Join(tmpDir, "fresh.go")
+	stale := filepath.Join(tmpDir, "stale.go")
+	mustWriteFile(t, fresh, "// TODO fresh\n")
+	mustWriteFile(t, stale, "// TODO stale\n")
+	old := time.Now().AddDate(0, 0, -30)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--newer-than", "7d", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, fresh+":1:// TODO fresh")
+	if len(lines) != 1 {
+		t.Errorf("expected only fresh.go, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--older-than", old.AddDate(0, 0, 1).Format("2006-01-02"), "TODO", tmpDir})
+	lines = splitLines(stdout)
+	expectContains(t, lines, stale+":1:// TODO stale")
+	if len(lines) != 1 {
+		t.Errorf("expected only stale.go, got %v", lines)
+	}
+}
+
+func TestIntegrationMaxDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "top.txt"), "TODO top\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "mid.txt"), "TODO mid\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "b", "deep.txt"), "TODO deep\n")
+
+	for depth, want := range map[string]int{"0": 0, "1": 1, "2": 2, "3": 3} {
+		stdout, _ := runFindrefMain(t, []string{"--no-color", "--max-depth", depth, "TODO", tmpDir})
+		if lines := splitLines(stdout); len(lines) != want {
+			t.Errorf("--max-depth %s: expected %d matches, got %v", depth, want, lines)
+		}
+	}
+
+	stdout, _ := runFindrefMainInDir(t, []string{"--no-color", "--max-depth", "1", "TODO"}, tmpDir)
+	if lines := splitLines(stdout); len(lines) != 1 || lines[0] != "top.txt:1:TODO top" {
+		t.Errorf("expected only top.txt from '.', got %v", lines)
+	}
+}
+
+func TestMCPSearchMetadataFilters(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "top.txt"), "TODO top\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "a", "deep.txt"), "TODO deep\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "big.txt"), "TODO big\n"+strings.Repeat("x", 2048)+"\n")
+
+	depth := 1
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, MaxDepth: &depth, MaxFilesize: "1K", FilenameOnly: true})
+	result, _ := handleSearch(context.Background(), args)
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 1 || !strings.HasSuffix(filenames[0], "top.txt") {
+		t.Errorf("expected only top.txt, got %v", filenames)
+	}
+
+	args, _ = json.Marshal(searchArgs{Pattern: "TODO", Directory: tmpDir, NewerThan: "last tuesday"})
+	result, _ = handleSearch(context.Background(), args)
+	if !result.IsError {
+		t.Error("expected an error for an invalid newer_than")
+	}
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+const (
+	niceLowest       = 19
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerPriority gives findref the lowest CPU priority and the idle I/O
+// scheduling class.  Both only apply to a single thread on Linux, so every
+// thread of the process is changed; threads started later inherit the
+// priority of the thread that starts them.
+func lowerPriority() error {
+	tids := []string{"0"}
+	if entries, err := os.ReadDir("/proc/self/task"); err == nil {
+		tids = tids[:0]
+		for _, entry := range entries {
+			tids = append(tids, entry.Name())
+		}
+	}
+	// Otherwise only the calling thread can be changed
+	return lowerThreadPriorities(tids)
+}
+
+// lowerThreadPriorities lowers the priority of each of the threads tids.  A
+// thread that has exited since it was listed is skipped.
+func lowerThreadPriorities(tids []string) error {
+	for _, name := range tids {
+		tid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceLowest); err == syscall.ESRCH {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("lowering CPU priority: %w", err)
+		}
+		ioprio := uintptr(ioprioClassIdle << ioprioClassShift)
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprio); errno == syscall.ESRCH {
+			continue
+		} else if errno != 0 {
+			return fmt.Errorf("lowering I/O priority: %w", errno)
+		}
+	}
+	return nil
+}
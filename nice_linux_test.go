@@ -0,0 +1,47 @@
+//go:build linux
+
+package main
+
+import (
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// lowerPriorityEnv makes the test binary lower its own priority, so that
+// TestLowerPriority can check lowerPriority without slowing down the rest of
+// the tests
+const lowerPriorityEnv = "FINDREF_TEST_LOWER_PRIORITY"
+
+func TestLowerPriority(t *testing.T) {
+	if os.Getenv(lowerPriorityEnv) != "" {
+		checkLowerPriority(t)
+		return
+	}
+	child := exec.Command(os.Args[0], "-test.run=^TestLowerPriority$")
+	child.Env = append(os.Environ(), lowerPriorityEnv+"=1")
+	if output, err := child.CombinedOutput(); err != nil {
+		t.Fatalf("lowering the priority of a subprocess: %v\n%s", err, output)
+	}
+}
+
+func checkLowerPriority(t *testing.T) {
+	// A thread that exits after /proc/self/task is read is skipped
+	if err := lowerThreadPriorities([]string{"0", strconv.Itoa(math.MaxInt32)}); err != nil {
+		t.Fatalf("lowering the priority of an exited thread: %v", err)
+	}
+	if err := lowerPriority(); err != nil {
+		t.Fatalf("lowerPriority: %v", err)
+	}
+	// The raw syscall returns 20 - nice
+	priority, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatalf("getpriority: %v", err)
+	}
+	if nice := 20 - priority; nice != niceLowest {
+		t.Errorf("expected a niceness of %d, got %d", niceLowest, nice)
+	}
+}
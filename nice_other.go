@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// lowerPriority is only implemented on Linux
+func lowerPriority() error {
+	return errors.New("--nice is only supported on Linux")
+}
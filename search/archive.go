@@ -0,0 +1,218 @@
+package search
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveDepthDefault is how many levels of archives are opened by default:
+// the archive itself, plus archives nested directly inside it (e.g. a jar in
+// a war).
+const ArchiveDepthDefault = 2
+
+// archiveSeparator joins an archive's path with the path of a member inside it
+const archiveSeparator = "!"
+
+const (
+	tarMagicOffset = 257
+	tarMagic       = "ustar"
+
+	// Enough compressed bytes to inflate the first tar header of a .tar.gz
+	archiveSniffSize = 4096
+)
+
+var archiveExtensions = []string{
+	".zip", ".jar", ".war", ".ear", ".apk", ".whl", ".nupkg",
+	".tar", ".tar.gz", ".tgz",
+}
+
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveZip
+	archiveTar
+	archiveTarGzip
+)
+
+// hasArchiveExtension reports whether path is named like an archive.  Such
+// files are queued by the walker even if they don't pass the include and
+// filename filters, since those filters apply to the archive's members.
+func hasArchiveExtension(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffArchive identifies zip, tar and gzipped tar content by its magic bytes
+// without consuming any input from br
+func sniffArchive(br *bufio.Reader) archiveKind {
+	head, _ := br.Peek(archiveSniffSize)
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")) || bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return archiveZip
+	case isTarHeader(head):
+		return archiveTar
+	case detectCompression(head) == compressionGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(head))
+		if err != nil {
+			return archiveNone
+		}
+		inflated := make([]byte, tarMagicOffset+len(tarMagic))
+		n, _ := io.ReadFull(gz, inflated)
+		if isTarHeader(inflated[:n]) {
+			return archiveTarGzip
+		}
+	}
+	return archiveNone
+}
+
+func isTarHeader(head []byte) bool {
+	return len(head) >= tarMagicOffset+len(tarMagic) &&
+		string(head[tarMagicOffset:tarMagicOffset+len(tarMagic)]) == tarMagic
+}
+
+// scanArchive searches every member of the archive at archivePath.  For zip
+// files readerAt and size may be supplied to avoid reading the whole archive
+// into memory.  depth is the nesting level of the archive's members.
+func (s *Searcher) scanArchive(archivePath string, kind archiveKind, br *bufio.Reader, readerAt io.ReaderAt, size int64, depth int) []Match {
+	s.debug(s.colors.Blue+"Searching archive:"+s.colors.Restore, archivePath)
+	retval := make([]Match, 0, 50)
+
+	switch kind {
+	case archiveZip:
+		if readerAt == nil {
+			data, err := io.ReadAll(br)
+			if err != nil {
+				s.archiveError(archivePath, err)
+				return retval
+			}
+			readerAt = bytes.NewReader(data)
+			size = int64(len(data))
+		}
+		zr, err := zip.NewReader(readerAt, size)
+		if err != nil {
+			s.archiveError(archivePath, err)
+			return retval
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				s.archiveError(archivePath+archiveSeparator+f.Name, err)
+				continue
+			}
+			retval = append(retval, s.scanArchiveMember(archivePath, f.Name, f.FileInfo(), rc, depth)...)
+			rc.Close()
+		}
+
+	case archiveTar, archiveTarGzip:
+		var r io.Reader = br
+		if kind == archiveTarGzip {
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				s.archiveError(archivePath, err)
+				return retval
+			}
+			r = gz
+		}
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				s.archiveError(archivePath, err)
+				break
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			retval = append(retval, s.scanArchiveMember(archivePath, header.Name, header.FileInfo(), tr, depth)...)
+		}
+	}
+
+	return retval
+}
+
+// scanArchiveMember searches a single archive member, descending into it if
+// it is itself an archive and the depth limit allows.  info comes from the
+// archive's own header.
+func (s *Searcher) scanArchiveMember(archivePath string, member string, info os.FileInfo, r io.Reader, depth int) []Match {
+	member = strings.TrimPrefix(path.Clean("/"+member), "/")
+	virtualPath := archivePath + archiveSeparator + member
+	if s.searchStopped() {
+		return []Match{}
+	}
+	br := bufio.NewReader(r)
+	if !s.shouldScanMember(virtualPath, member, br) || !s.passesMetadata(virtualPath, info) {
+		return []Match{}
+	}
+
+	if depth < s.settings.ArchiveDepth {
+		if kind := sniffArchive(br); kind != archiveNone {
+			s.stats.incrArchiveCount()
+			return s.scanArchive(virtualPath, kind, br, nil, 0, depth+1)
+		}
+	}
+
+	s.stats.incrFilesToScan()
+	s.stats.incrFileCount()
+
+	var reader io.Reader = br
+	if s.settings.SearchZip {
+		decompressed, format, err := decompressingReader(br)
+		if err != nil {
+			s.archiveError(virtualPath, err)
+			return []Match{}
+		}
+		if format != compressionNone {
+			s.stats.incrDecompressedCount()
+		}
+		reader = decompressed
+	}
+	return s.scanForMatches(virtualPath, reader, nil)
+}
+
+// shouldScanMember applies the walker's filters to an archive member.  The
+// member's directories are checked like the walker checks directories, and
+// the file filters are applied to the full virtual path.  The #! line the
+// type filter may need is peeked from the member's content in br.
+func (s *Searcher) shouldScanMember(virtualPath string, member string, br *bufio.Reader) bool {
+	for dir := path.Dir(member); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if s.settings.ShouldExcludeDir(dir) || s.settings.ExcludedByGlob(dir) || s.settings.IsHidden(dir) {
+			s.debug(s.colors.Blue, "Archive member", virtualPath, "is in an excluded or hidden directory and will be skipped", s.colors.Restore)
+			return false
+		}
+	}
+	if s.settings.IsHidden(member) {
+		s.debug(s.colors.Blue + "Hidden archive member '" + s.colors.Restore + virtualPath + s.colors.Blue + "' not processed")
+		return false
+	}
+	interpreter := func() string {
+		head, _ := br.Peek(shebangSniffSize)
+		return headInterpreter(head)
+	}
+	return s.shouldScanFile(virtualPath, member, false, interpreter)
+}
+
+func (s *Searcher) archiveError(archivePath string, err error) {
+	s.debug(s.colors.Red+"Error reading archive '"+archivePath+"'. Err: "+s.colors.Restore, err)
+	s.stats.incrErroredFilesCount()
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+func archiveMatchPaths(matches []Match) map[string]int {
+	paths := map[string]int{}
+	for _, m := range matches {
+		if m.hasMatch() {
+			paths[m.Path] = m.LineNumber
+		}
+	}
+	return paths
+}
+
+// ---------------------------------------------------------------------------
+// sniffArchive / hasArchiveExtension
+// ---------------------------------------------------------------------------
+
+func TestSniffArchive(t *testing.T) {
+	tarData := fixture.Tar(t, fixture.Entry{Name: "a.txt", Content: "x"})
+	cases := []struct {
+		name string
+		data []byte
+		want archiveKind
+	}{
+		{"zip", fixture.Zip(t, fixture.Entry{Name: "a.txt", Content: "x"}), archiveZip},
+		{"tar", tarData, archiveTar},
+		{"tar.gz", fixture.Gzip(t, tarData), archiveTarGzip},
+		{"plain gzip", fixture.Gzip(t, []byte("just text\n")), archiveNone},
+		{"text", []byte("PK is not enough"), archiveNone},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := bufio.NewReader(bytes.NewReader(tc.data))
+			if got := sniffArchive(br); got != tc.want {
+				t.Errorf("sniffArchive() = %v, want %v", got, tc.want)
+			}
+			if rest, _ := io.ReadAll(br); !bytes.Equal(rest, tc.data) {
+				t.Errorf("expected sniffing not to consume input")
+			}
+		})
+	}
+}
+
+func TestHasArchiveExtension(t *testing.T) {
+	for _, p := range []string{"a.zip", "lib/x.JAR", "release.tar.gz", "r.tgz", "b.tar"} {
+		if !hasArchiveExtension(p) {
+			t.Errorf("expected %q to look like an archive", p)
+		}
+	}
+	for _, p := range []string{"a.go", "zip", "notes.gz"} {
+		if hasArchiveExtension(p) {
+			t.Errorf("did not expect %q to look like an archive", p)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// checkForMatches with SearchArchives
+// ---------------------------------------------------------------------------
+
+func TestCheckForMatchesZipArchive(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.SearchArchives = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "release.zip")
+	fixture.WriteBytes(t, f, fixture.Zip(t,
+		fixture.Entry{Name: "src/main.go", Content: "package main\n// TODO main\n"},
+		fixture.Entry{Name: "vendor/dep.go", Content: "// TODO vendored\n"},
+		fixture.Entry{Name: ".hidden/x.go", Content: "// TODO hidden\n"},
+		fixture.Entry{Name: "README", Content: "nothing\n"},
+	))
+
+	paths := archiveMatchPaths(searcher.checkForMatches(f))
+	want := f + "!src/main.go"
+	if len(paths) != 1 || paths[want] != 2 {
+		t.Fatalf("expected only %q on line 2, got %v", want, paths)
+	}
+	if searcher.stats.ArchiveCount() != 1 {
+		t.Errorf("expected 1 archive, got %d", searcher.stats.ArchiveCount())
+	}
+}
+
+func TestCheckForMatchesTarGzArchive(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.SearchArchives = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	searcher.settings.FilenameRegex = regexp.MustCompile(`\.go$`)
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "release.tar.gz")
+	fixture.WriteBytes(t, f, fixture.Gzip(t, fixture.Tar(t,
+		fixture.Entry{Name: "./pkg/a.go", Content: "// TODO a\n"},
+		fixture.Entry{Name: "pkg/notes.txt", Content: "TODO txt\n"},
+	)))
+
+	paths := archiveMatchPaths(searcher.checkForMatches(f))
+	want := f + "!pkg/a.go"
+	if len(paths) != 1 || paths[want] != 1 {
+		t.Fatalf("expected only %q, got %v", want, paths)
+	}
+}
+
+func TestCheckForMatchesArchiveMemberShebang(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.SearchArchives = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	if err := searcher.settings.SelectTypes("py"); err != nil {
+		t.Fatalf("selecting type: %v", err)
+	}
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "tools.zip")
+	fixture.WriteBytes(t, f, fixture.Zip(t,
+		fixture.Entry{Name: "bin/deploy", Content: "#!/usr/bin/env python3\n# TODO deploy\n"},
+		fixture.Entry{Name: "bin/build", Content: "#!/bin/sh\n# TODO build\n"},
+	))
+
+	// The #! line is read from the member itself, and the member is still
+	// searched from its first line
+	paths := archiveMatchPaths(searcher.checkForMatches(f))
+	want := f + "!bin/deploy"
+	if len(paths) != 1 || paths[want] != 2 {
+		t.Fatalf("expected only %q on line 2, got %v", want, paths)
+	}
+}
+
+func TestCheckForMatchesNestedArchiveDepth(t *testing.T) {
+	inner := fixture.Zip(t, fixture.Entry{Name: "com/x/Y.java", Content: "// TODO inner\n"})
+	outer := fixture.Zip(t, fixture.Entry{Name: "WEB-INF/lib/inner.jar", Content: string(inner)}, fixture.Entry{Name: "index.html", Content: "TODO outer\n"})
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "app.war")
+	fixture.WriteBytes(t, f, outer)
+
+	resetTestState(t)
+	searcher.settings.SearchArchives = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	paths := archiveMatchPaths(searcher.checkForMatches(f))
+	nested := f + "!WEB-INF/lib/inner.jar!com/x/Y.java"
+	if _, ok := paths[nested]; !ok {
+		t.Errorf("expected nested match %q, got %v", nested, paths)
+	}
+	if _, ok := paths[f+"!index.html"]; !ok {
+		t.Errorf("expected outer match, got %v", paths)
+	}
+
+	resetTestState(t)
+	searcher.settings.SearchArchives = true
+	searcher.settings.ArchiveDepth = 1
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	paths = archiveMatchPaths(searcher.checkForMatches(f))
+	if _, ok := paths[nested]; ok {
+		t.Errorf("expected nested archive not to be opened at depth 1, got %v", paths)
+	}
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type BinaryPolicy int
+
+const (
+	// BinarySkip ignores binary files entirely
+	BinarySkip BinaryPolicy = iota
+	// BinaryMatchOnly reports that a binary file matches without printing lines
+	BinaryMatchOnly
+	// BinaryText searches binary files as if they were text
+	BinaryText
+)
+
+// binaryMaxInvalidRatio is the share of the sniffed block that may be invalid
+// UTF-8 or control characters before a file is considered binary
+const binaryMaxInvalidRatio = 0.3
+
+// binaryMagic lists the leading bytes of common binary formats that may not
+// contain a NUL near the start of the file
+var binaryMagic = [][]byte{
+	[]byte("\x7fELF"),             // ELF executables and libraries
+	[]byte("\xca\xfe\xba\xbe"),    // Java classes and Mach-O fat binaries
+	[]byte("\xcf\xfa\xed\xfe"),    // Mach-O 64-bit
+	[]byte("\xce\xfa\xed\xfe"),    // Mach-O 32-bit
+	[]byte("\x00asm"),             // WebAssembly
+	[]byte("\x89PNG\r\n\x1a\n"),   // PNG
+	[]byte("\xff\xd8\xff"),        // JPEG
+	[]byte("GIF87a"),              // GIF
+	[]byte("GIF89a"),              // GIF
+	[]byte("%PDF-"),               // PDF
+	[]byte("PK\x03\x04"),          // zip and friends
+	[]byte("\xfd7zXZ\x00"),        // xz
+	[]byte("\x28\xb5\x2f\xfd"),    // zstd
+	[]byte("7z\xbc\xaf\x27\x1c"),  // 7-Zip
+	[]byte("SQLite format 3\x00"), // SQLite
+}
+
+func (p BinaryPolicy) String() string {
+	switch p {
+	case BinaryMatchOnly:
+		return "match-only"
+	case BinaryText:
+		return "text"
+	}
+	return "skip"
+}
+
+// ParseBinaryPolicy converts the value of --binary to a BinaryPolicy
+func ParseBinaryPolicy(value string) (BinaryPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "skip":
+		return BinarySkip, nil
+	case "match-only", "match_only", "matchonly":
+		return BinaryMatchOnly, nil
+	case "text":
+		return BinaryText, nil
+	}
+	return BinarySkip, fmt.Errorf("invalid binary policy %q (use skip, match-only or text)", value)
+}
+
+// BinarySniffSize is how much of the start of a file is examined to decide
+// whether it is binary
+const BinarySniffSize = encodingSniffSize
+
+// LooksBinary reports whether head, the first BinarySniffSize bytes of a
+// file, belongs to a binary file, using the same checks as a search does
+// before skipping a file
+func LooksBinary(head []byte) bool {
+	_, enc := decodingReader(bytes.NewReader(head), EncodingAuto)
+	return looksBinary(head, enc)
+}
+
+// looksBinary reports whether head, the first bytes of a file, belongs to a
+// binary file.  enc is the encoding the file will be decoded with; UTF-16
+// text is full of NULs, so only the magic numbers are checked for it.
+func looksBinary(head []byte, enc Encoding) bool {
+	if _, bomLength := detectEncoding(head); bomLength > 0 {
+		// A byte order mark is a strong sign of text
+		return false
+	}
+	for _, magic := range binaryMagic {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	if detectCompression(head) != compressionNone {
+		// Compressed files that weren't decompressed by --search-zip
+		return true
+	}
+	if enc == EncodingUTF16LE || enc == EncodingUTF16BE {
+		return false
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	head = trimPartialRune(head)
+	suspicious := 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			// Latin-1 text has these too, but sparsely
+			suspicious++
+		case r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' && r != '\v' && r != 0x1b:
+			suspicious++
+		}
+		i += size
+	}
+	return len(head) > 0 && float64(suspicious) > float64(len(head))*binaryMaxInvalidRatio
+}
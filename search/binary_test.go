@@ -0,0 +1,130 @@
+package search
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// looksBinary / ParseBinaryPolicy
+// ---------------------------------------------------------------------------
+
+func TestLooksBinary(t *testing.T) {
+	cases := []struct {
+		name string
+		head []byte
+		enc  Encoding
+		want bool
+	}{
+		{"plain text", []byte("package main\n\nfunc main() {}\n"), EncodingUTF8, false},
+		{"utf-8 text", []byte("naïve café résumé\n"), EncodingUTF8, false},
+		{"ansi colors", []byte("\x1b[31mred\x1b[0m\n"), EncodingUTF8, false},
+		{"latin1 text", []byte("un caf\xe9 cr\xe8me br\xfbl\xe9e\n"), EncodingLatin1, false},
+		{"utf-16 text", fixture.UTF16("hello\n", false, false), EncodingUTF16LE, false},
+		{"utf-8 bom", []byte("\xef\xbb\xbfTODO\n"), EncodingUTF8, false},
+		{"empty", []byte{}, EncodingUTF8, false},
+		{"nul byte", []byte("TODO\x00binary\n"), EncodingUTF8, true},
+		{"elf magic", []byte("\x7fELF\x02\x01\x01 TODO"), EncodingUTF8, true},
+		{"png magic", []byte("\x89PNG\r\n\x1a\nTODO"), EncodingLatin1, true},
+		{"pdf magic", []byte("%PDF-1.7\nTODO\n"), EncodingUTF8, true},
+		{"gzip magic", fixture.Gzip(t, []byte("TODO\n")), EncodingLatin1, true},
+		{"elf magic decoded as utf-16", []byte("\x7fELF\x02\x01\x01\x00"), EncodingUTF16LE, true},
+		{"mostly invalid utf-8", []byte("\xc8\x91\xff\xfe\x83\xa7\xf1\xd3\xee\x90TODO"), EncodingLatin1, true},
+		{"control characters", []byte("\x01\x02\x03\x04\x05\x06TODO\x07\x08"), EncodingUTF8, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := looksBinary(tc.head, tc.enc); got != tc.want {
+				t.Errorf("looksBinary(%q, %v) = %v, want %v", tc.head, tc.enc, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLooksBinaryDetectsEncoding(t *testing.T) {
+	if LooksBinary([]byte("plain text\n")) {
+		t.Errorf("did not expect plain text to look binary")
+	}
+	if !LooksBinary([]byte("ELF\x00\x01\x02")) {
+		t.Errorf("expected a NUL byte to look binary")
+	}
+	utf16 := []byte{0xff, 0xfe, 't', 0, 'e', 0, 'x', 0, 't', 0}
+	if LooksBinary(utf16) {
+		t.Errorf("did not expect UTF-16 text to look binary")
+	}
+}
+
+func TestParseBinaryPolicy(t *testing.T) {
+	for value, want := range map[string]BinaryPolicy{
+		"":           BinarySkip,
+		"skip":       BinarySkip,
+		"match-only": BinaryMatchOnly,
+		"MATCH_ONLY": BinaryMatchOnly,
+		"text":       BinaryText,
+	} {
+		got, err := ParseBinaryPolicy(value)
+		if err != nil || got != want {
+			t.Errorf("ParseBinaryPolicy(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseBinaryPolicy("hex"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// checkForMatches with binary policies
+// ---------------------------------------------------------------------------
+
+func TestCheckForMatchesMagicWithoutNull(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	f := filepath.Join(t.TempDir(), "image.png")
+	fixture.WriteBytes(t, f, []byte("\x89PNG\r\n\x1a\nTODO in pixels\n"))
+
+	if matches := searcher.checkForMatches(f); len(matches) != 0 {
+		t.Errorf("expected PNG to be skipped, got %+v", matches)
+	}
+	if searcher.stats.SkippedNullCount() != 1 {
+		t.Errorf("expected 1 skipped binary file, got %d", searcher.stats.SkippedNullCount())
+	}
+}
+
+func TestCheckForMatchesBinaryText(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.Binary = BinaryText
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	f := filepath.Join(t.TempDir(), "binary.bin")
+	mustWriteFile(t, f, "header\x00\nTODO inside\n")
+
+	matches := searcher.checkForMatches(f)
+	if len(matches) != 1 || matches[0].LineNumber != 2 {
+		t.Fatalf("expected a match on line 2, got %+v", matches)
+	}
+	if searcher.stats.SkippedNullCount() != 0 {
+		t.Errorf("expected no skipped files, got %d", searcher.stats.SkippedNullCount())
+	}
+}
+
+func TestCheckForMatchesBinaryMatchOnlyFilenameOnly(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.Binary = BinaryMatchOnly
+	searcher.settings.FilenameOnly = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "binary.bin")
+	other := filepath.Join(tmpDir, "other.bin")
+	mustWriteFile(t, f, "TODO\x00one\nTODO two\n")
+	mustWriteFile(t, other, "nothing\x00here\n")
+
+	matches := append(searcher.checkForMatches(f), searcher.checkForMatches(other)...)
+	if len(matches) != 1 || matches[0].Path != f || !matches[0].Binary {
+		t.Errorf("expected only %q to be reported, got %+v", f, matches)
+	}
+	if searcher.stats.FilesMatchedCount() != 1 {
+		t.Errorf("expected 1 matched file, got %d", searcher.stats.FilesMatchedCount())
+	}
+}
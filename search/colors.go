@@ -1,5 +1,8 @@
-package main
+package search
 
+// Colors are the escape codes used by TextSink and in the debug output of a
+// search.  Each Settings has colors of its own, so turning them off with
+// ZeroColors only affects the searches using those settings.
 type Colors struct {
 	Red         string
 	Blue        string
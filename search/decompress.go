@@ -0,0 +1,75 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+type compressionFormat int
+
+const (
+	compressionNone compressionFormat = iota
+	compressionGzip
+	compressionBzip2
+	compressionZlib
+)
+
+func (c compressionFormat) String() string {
+	switch c {
+	case compressionGzip:
+		return "gzip"
+	case compressionBzip2:
+		return "bzip2"
+	case compressionZlib:
+		return "zlib"
+	}
+	return "none"
+}
+
+// detectCompression identifies a compressed stream by its magic bytes
+func detectCompression(head []byte) compressionFormat {
+	switch {
+	case len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b:
+		return compressionGzip
+	case len(head) >= 4 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		return compressionBzip2
+	case len(head) >= 2 && head[0] == 0x78 && (head[1] == 0x01 || head[1] == 0x9c || head[1] == 0xda):
+		// zlib headers are only two bytes, so stick to the ones produced by
+		// the common compression levels.  0x785e is skipped because it is
+		// the plain text "x^".
+		return compressionZlib
+	}
+	return compressionNone
+}
+
+// decompressingReader sniffs the start of r and, if it is a gzip, bzip2 or
+// zlib stream, returns a reader producing the decompressed content.
+// Uncompressed input is returned unchanged (but buffered).
+func decompressingReader(r io.Reader) (io.Reader, compressionFormat, error) {
+	buffered := bufio.NewReader(r)
+	head, _ := buffered.Peek(4)
+
+	format := detectCompression(head)
+	switch format {
+	case compressionGzip:
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, format, fmt.Errorf("reading gzip stream: %w", err)
+		}
+		return gz, format, nil
+	case compressionBzip2:
+		return bzip2.NewReader(buffered), format, nil
+	case compressionZlib:
+		zr, err := zlib.NewReader(buffered)
+		if err != nil {
+			return nil, format, fmt.Errorf("reading zlib stream: %w", err)
+		}
+		return zr, format, nil
+	}
+	return buffered, compressionNone, nil
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// detectCompression / decompressingReader
+// ---------------------------------------------------------------------------
+
+func TestDetectCompression(t *testing.T) {
+	cases := []struct {
+		name string
+		head []byte
+		want compressionFormat
+	}{
+		{"gzip", fixture.Gzip(t, []byte("x")), compressionGzip},
+		{"bzip2", []byte(fixture.Bzip2), compressionBzip2},
+		{"zlib", fixture.Zlib(t, []byte("x")), compressionZlib},
+		{"plain text", []byte("hello"), compressionNone},
+		{"text starting with x^", []byte("x^2 + y^2"), compressionNone},
+		{"BZh without level", []byte("BZhello"), compressionNone},
+		{"empty", []byte{}, compressionNone},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectCompression(tc.head); got != tc.want {
+				t.Errorf("detectCompression() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDecompressingReader(t *testing.T) {
+	inputs := map[string][]byte{
+		"gzip":  fixture.Gzip(t, []byte("first\nTODO bz\n")),
+		"bzip2": []byte(fixture.Bzip2),
+		"zlib":  fixture.Zlib(t, []byte("first\nTODO bz\n")),
+		"none":  []byte("first\nTODO bz\n"),
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			r, format, err := decompressingReader(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if format.String() != name {
+				t.Errorf("expected format %q, got %q", name, format.String())
+			}
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if string(out) != "first\nTODO bz\n" {
+				t.Errorf("unexpected content %q", string(out))
+			}
+		})
+	}
+}
+
+func TestCheckForMatchesSearchZip(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.SearchZip = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "app.log.gz")
+	fixture.WriteBytes(t, f, fixture.Gzip(t, []byte("ok\nTODO compressed\n")))
+
+	var found []Match
+	for _, m := range searcher.checkForMatches(f) {
+		if m.hasMatch() {
+			found = append(found, m)
+		}
+	}
+	if len(found) != 1 || found[0].LineNumber != 2 || found[0].Path != f {
+		t.Fatalf("expected one match on line 2 of %q, got %+v", f, found)
+	}
+	if searcher.stats.DecompressedCount() != 1 {
+		t.Errorf("expected 1 decompressed file, got %d", searcher.stats.DecompressedCount())
+	}
+}
+
+func TestCheckForMatchesCorruptGzip(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.SearchZip = true
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "broken.gz")
+	fixture.WriteBytes(t, f, []byte{0x1f, 0x8b, 0x00})
+
+	for _, m := range searcher.checkForMatches(f) {
+		if m.hasMatch() {
+			t.Fatal("expected no matches in corrupt file")
+		}
+	}
+	if searcher.stats.ErroredFilesCount() != 1 {
+		t.Errorf("expected 1 errored file, got %d", searcher.stats.ErroredFilesCount())
+	}
+}
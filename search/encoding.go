@@ -0,0 +1,219 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type Encoding int
+
+const (
+	EncodingAuto Encoding = iota
+	EncodingUTF8
+	EncodingUTF16LE
+	EncodingUTF16BE
+	EncodingLatin1
+)
+
+// encodingSniffSize is how much of a file is examined to guess its encoding
+const encodingSniffSize = 4096
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "utf-8"
+	case EncodingUTF16LE:
+		return "utf-16le"
+	case EncodingUTF16BE:
+		return "utf-16be"
+	case EncodingLatin1:
+		return "latin1"
+	}
+	return "auto"
+}
+
+// ParseEncoding converts the value of --encoding to an Encoding
+func ParseEncoding(value string) (Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return EncodingAuto, nil
+	case "utf-8", "utf8":
+		return EncodingUTF8, nil
+	case "utf-16le", "utf16le":
+		return EncodingUTF16LE, nil
+	case "utf-16be", "utf16be":
+		return EncodingUTF16BE, nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return EncodingLatin1, nil
+	}
+	return EncodingAuto, fmt.Errorf("invalid encoding %q (use auto, utf-8, utf-16le, utf-16be or latin1)", value)
+}
+
+// detectEncoding guesses the encoding of head, the first bytes of a file.
+// A byte order mark wins; otherwise UTF-16 is recognized by the pattern of
+// NUL bytes in ASCII-heavy text, and text that isn't valid UTF-8 (and
+// contains no NULs, so doesn't look binary) is treated as Latin-1.
+// bomLength is the number of leading bytes that should be skipped.
+func detectEncoding(head []byte) (enc Encoding, bomLength int) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		return EncodingUTF8, 3
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return EncodingUTF16LE, 2
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return EncodingUTF16BE, 2
+	}
+
+	if len(head) < 2 {
+		return EncodingUTF8, 0
+	}
+
+	evenNulls, oddNulls := 0, 0
+	for i, b := range head {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNulls++
+		} else {
+			oddNulls++
+		}
+	}
+	pairs := len(head) / 2
+	switch {
+	case oddNulls*10 >= pairs*4 && evenNulls*10 < pairs:
+		return EncodingUTF16LE, 0
+	case evenNulls*10 >= pairs*4 && oddNulls*10 < pairs:
+		return EncodingUTF16BE, 0
+	case evenNulls+oddNulls > 0:
+		// Probably binary; leave it to the null byte check
+		return EncodingUTF8, 0
+	}
+
+	if !utf8.Valid(trimPartialRune(head)) {
+		return EncodingLatin1, 0
+	}
+	return EncodingUTF8, 0
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence cut off at the end of b
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// NewDecodingReader returns a reader that produces r's content as UTF-8,
+// decoded from encoding the same way a search decodes the files it reads
+func NewDecodingReader(r io.Reader, encoding Encoding) io.Reader {
+	reader, _ := decodingReader(r, encoding)
+	return reader
+}
+
+// decodingReader returns a reader that produces r's content as UTF-8, using
+// encoding or, if that is auto, the encoding detected from the start of r
+func decodingReader(r io.Reader, encoding Encoding) (io.Reader, Encoding) {
+	br := bufio.NewReaderSize(r, encodingSniffSize)
+	head, _ := br.Peek(encodingSniffSize)
+
+	enc, bomLength := detectEncoding(head)
+	if encoding != EncodingAuto {
+		enc = encoding
+		if detected, _ := detectEncoding(head); detected != enc {
+			bomLength = 0
+		}
+	}
+	br.Discard(bomLength)
+
+	switch enc {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return &utf16Reader{r: br, bigEndian: enc == EncodingUTF16BE}, enc
+	case EncodingLatin1:
+		return &latin1Reader{r: br}, enc
+	}
+	return br, EncodingUTF8
+}
+
+// utf16Reader transcodes a UTF-16 stream to UTF-8
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	pending   []byte
+	err       error
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	var pair [2]byte
+	if _, err := io.ReadFull(u.r, pair[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			// A dangling odd byte can't be decoded
+			return utf8.RuneError, nil
+		}
+		return 0, err
+	}
+	if u.bigEndian {
+		return uint16(pair[0])<<8 | uint16(pair[1]), nil
+	}
+	return uint16(pair[1])<<8 | uint16(pair[0]), nil
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.pending) < len(p) && u.err == nil {
+		unit, err := u.readUnit()
+		if err != nil {
+			u.err = err
+			break
+		}
+		r := rune(unit)
+		if utf16.IsSurrogate(r) {
+			next, err := u.readUnit()
+			if err != nil {
+				u.err = err
+				r = utf8.RuneError
+			} else {
+				r = utf16.DecodeRune(r, rune(next))
+			}
+		}
+		u.pending = utf8.AppendRune(u.pending, r)
+	}
+
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+	if n == 0 && u.err != nil {
+		return 0, u.err
+	}
+	return n, nil
+}
+
+// latin1Reader transcodes an ISO-8859-1 stream to UTF-8
+type latin1Reader struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.pending) == 0 {
+		buf := make([]byte, len(p))
+		n, err := l.r.Read(buf)
+		for _, b := range buf[:n] {
+			l.pending = utf8.AppendRune(l.pending, rune(b))
+		}
+		if n == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
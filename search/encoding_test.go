@@ -0,0 +1,147 @@
+package search
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// detectEncoding / decodingReader
+// ---------------------------------------------------------------------------
+
+func TestDetectEncoding(t *testing.T) {
+	cases := []struct {
+		name    string
+		head    []byte
+		want    Encoding
+		wantBOM int
+	}{
+		{"ascii", []byte("plain text\n"), EncodingUTF8, 0},
+		{"utf-8", []byte("caf\xc3\xa9\n"), EncodingUTF8, 0},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhello"), EncodingUTF8, 3},
+		{"utf-16le bom", fixture.UTF16("hello", false, true), EncodingUTF16LE, 2},
+		{"utf-16be bom", fixture.UTF16("hello", true, true), EncodingUTF16BE, 2},
+		{"utf-16le no bom", fixture.UTF16("hello world\n", false, false), EncodingUTF16LE, 0},
+		{"utf-16be no bom", fixture.UTF16("hello world\n", true, false), EncodingUTF16BE, 0},
+		{"latin1", []byte("caf\xe9 au lait\n"), EncodingLatin1, 0},
+		{"binary", []byte("\x00\x01\x02\x00\x00\x00\x7fELF\x00"), EncodingUTF8, 0},
+		{"utf-8 cut mid rune", []byte("caf\xc3"), EncodingUTF8, 0},
+		{"empty", []byte{}, EncodingUTF8, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, bom := detectEncoding(tc.head)
+			if got != tc.want || bom != tc.wantBOM {
+				t.Errorf("detectEncoding(%q) = %v, %d; want %v, %d", tc.head, got, bom, tc.want, tc.wantBOM)
+			}
+		})
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for value, want := range map[string]Encoding{
+		"":         EncodingAuto,
+		"auto":     EncodingAuto,
+		"UTF-8":    EncodingUTF8,
+		"utf16le":  EncodingUTF16LE,
+		"utf-16be": EncodingUTF16BE,
+		"latin1":   EncodingLatin1,
+	} {
+		got, err := ParseEncoding(value)
+		if err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseEncoding("ebcdic"); err == nil {
+		t.Error("expected an error for an unknown encoding")
+	}
+}
+
+func TestDecodingReader(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    []byte
+		override Encoding
+		want     string
+		wantEnc  Encoding
+	}{
+		{"utf-8 passthrough", []byte("caf\xc3\xa9\n"), EncodingAuto, "café\n", EncodingUTF8},
+		{"utf-8 bom stripped", []byte("\xef\xbb\xbfhi\n"), EncodingAuto, "hi\n", EncodingUTF8},
+		{"utf-16le bom", fixture.UTF16("café ☕\n", false, true), EncodingAuto, "café ☕\n", EncodingUTF16LE},
+		{"utf-16be no bom", fixture.UTF16("hello world\n", true, false), EncodingAuto, "hello world\n", EncodingUTF16BE},
+		{"surrogate pair", fixture.UTF16("emoji 😀 here\n", false, true), EncodingAuto, "emoji 😀 here\n", EncodingUTF16LE},
+		{"latin1", []byte("caf\xe9\n"), EncodingAuto, "café\n", EncodingLatin1},
+		{"forced latin1", []byte("caf\xc3\xa9\n"), EncodingLatin1, "cafÃ©\n", EncodingLatin1},
+		{"forced utf-16le keeps bom skip", fixture.UTF16("x\n", false, true), EncodingUTF16LE, "x\n", EncodingUTF16LE},
+		{"forced utf-8 on utf-16", fixture.UTF16("hi", false, false), EncodingUTF8, "h\x00i\x00", EncodingUTF8},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resetTestState(t)
+			r, enc := decodingReader(bytes.NewReader(tc.input), tc.override)
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("reading: %v", err)
+			}
+			if string(got) != tc.want || enc != tc.wantEnc {
+				t.Errorf("got %q (%v), want %q (%v)", got, enc, tc.want, tc.wantEnc)
+			}
+		})
+	}
+}
+
+func TestDecodingReaderLargeUTF16(t *testing.T) {
+	content := strings.Repeat("line of text ñ\n", 2000) + "needle\n"
+	r, _ := decodingReader(bytes.NewReader(fixture.UTF16(content, false, true)), EncodingAuto)
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("transcoded content differs (got %d bytes, want %d)", len(got), len(content))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// checkForMatches with transcoding
+// ---------------------------------------------------------------------------
+
+func TestCheckForMatchesTranscodes(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.MatchRegex = regexp.MustCompile("café")
+	tmpDir := t.TempDir()
+	le := filepath.Join(tmpDir, "le.txt")
+	latin := filepath.Join(tmpDir, "latin.txt")
+	fixture.WriteBytes(t, le, fixture.UTF16("first\nun café\n", false, true))
+	fixture.WriteBytes(t, latin, []byte("caf\xe9 noir\n"))
+
+	if matches := searcher.checkForMatches(le); len(matches) != 1 || matches[0].LineNumber != 2 || string(matches[0].Line) != "un café" {
+		t.Errorf("unexpected UTF-16 matches: %+v", matches)
+	}
+	if matches := searcher.checkForMatches(latin); len(matches) != 1 || string(matches[0].Line) != "café noir" {
+		t.Errorf("unexpected Latin-1 matches: %+v", matches)
+	}
+	if searcher.stats.TranscodedCount() != 2 {
+		t.Errorf("expected 2 transcoded files, got %d", searcher.stats.TranscodedCount())
+	}
+}
+
+func TestCheckForMatchesBinaryNotTranscoded(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.MatchRegex = regexp.MustCompile("ELF")
+	f := filepath.Join(t.TempDir(), "prog")
+	fixture.WriteBytes(t, f, []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00>\x00"))
+
+	if matches := searcher.checkForMatches(f); len(matches) != 0 {
+		t.Errorf("expected binary file to be skipped, got %+v", matches)
+	}
+	if searcher.stats.TranscodedCount() != 0 {
+		t.Errorf("expected no transcoded files, got %d", searcher.stats.TranscodedCount())
+	}
+}
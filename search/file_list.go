@@ -0,0 +1,246 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type FileToScan struct {
+	Path string
+	Info os.FileInfo
+	Err  error
+}
+
+// listedFiles streams the paths read from list onto the returned channel,
+// applying the same exclude/include/filename/hidden filters as the walker.
+// Entries are NUL separated if the start of the list contains a NUL byte and
+// newline separated otherwise.  list is closed once it has been consumed.
+func (s *Searcher) listedFiles(list io.ReadCloser) <-chan string {
+	jobs := make(chan string, 100)
+
+	go func() {
+		defer close(jobs)
+		defer list.Close()
+
+		reader := bufio.NewReaderSize(list, scannerDefaultInitialCap)
+		separator := byte('\n')
+		if head, _ := reader.Peek(scannerDefaultInitialCap); bytes.IndexByte(head, 0) >= 0 {
+			separator = 0
+		}
+
+		scanner := bufio.NewScanner(reader)
+		scanner.Split(splitOn(separator))
+		for scanner.Scan() {
+			if s.stats.Interrupted() {
+				break
+			}
+			entry := scanner.Text()
+			if separator == '\n' {
+				entry = string(bytes.TrimRight([]byte(entry), "\r"))
+			}
+			if entry == "" {
+				continue
+			}
+			path := filepath.Clean(entry)
+
+			info, err := fs.Stat(s.fsys, path)
+			if err != nil {
+				s.debug(s.colors.Red+"Unable to stat listed file '"+path+"'. Err: "+s.colors.Restore, err)
+				if linkInfo, lerr := fs.Lstat(s.fsys, path); lerr == nil && linkInfo.Mode()&fs.ModeSymlink != 0 {
+					s.stats.incrBrokenLinkCount()
+				} else {
+					s.stats.incrErroredFilesCount()
+				}
+				continue
+			}
+			if info.IsDir() {
+				s.debug(s.colors.Blue, "Listed path", path, "is a directory and will be skipped", s.colors.Restore)
+				continue
+			}
+			if !s.passesMetadata(path, info) || !s.shouldScanFile(path, path, false, nil) {
+				continue
+			}
+			s.stats.incrFilesToScan()
+			s.stats.incrFileCount()
+			jobs <- path
+		}
+		if err := scanner.Err(); err != nil && !errors.Is(err, errInterrupted) {
+			s.reportError(fmt.Errorf("reading the file list: %w", err))
+		}
+	}()
+
+	return jobs
+}
+
+// splitOn returns a bufio.SplitFunc that splits on the given separator byte
+func splitOn(separator byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, separator); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// scanInput searches r, e.g. standard input, as if it were a single file
+func (s *Searcher) scanInput(name string, r io.Reader) []Match {
+	s.debug(s.colors.Blue + "Checking " + name + " for matches" + s.colors.Restore)
+	s.stats.incrFilesToScan()
+	s.stats.incrFileCount()
+	if s.settings.SearchZip {
+		decompressed, format, err := decompressingReader(r)
+		if errors.Is(err, errInterrupted) {
+			return []Match{}
+		} else if err != nil {
+			s.reportError(fmt.Errorf("%s: %w", name, err))
+			s.stats.incrErroredFilesCount()
+			return []Match{}
+		}
+		if format != compressionNone {
+			s.debug(s.colors.Blue+"Decompressing "+format.String()+" input:"+s.colors.Restore, name)
+			s.stats.incrDecompressedCount()
+		}
+		r = decompressed
+	}
+	return s.scanForMatches(name, r, nil)
+}
+
+// NormalizeRoots cleans the given start directories and files and drops
+// blank entries and duplicates of an earlier root.  The original (relative)
+// spelling of each kept root is preserved so that reported paths stay
+// relative to it.  Roots nested inside another are kept; the search only
+// skips them if the walk of the other root reaches them.
+func NormalizeRoots(roots []string) []string {
+	retval := make([]string, 0, len(roots))
+	seen := map[string]bool{}
+	for _, r := range roots {
+		trimmed := strings.TrimSpace(r)
+		if trimmed == "" {
+			continue
+		}
+		cleaned := filepath.Clean(trimmed)
+		abs, err := filepath.Abs(cleaned)
+		if err != nil {
+			abs = cleaned
+		}
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		retval = append(retval, cleaned)
+	}
+	return retval
+}
+
+// isWithin reports whether path is strictly inside the directory dir
+func isWithin(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dropReachedRoots drops the roots that the walk of another root reaches, so
+// that no file is searched twice.  A root nested inside another is still
+// walked if that walk would prune it, e.g. because it is below an excluded or
+// hidden directory.
+func (s *Searcher) dropReachedRoots(roots []string) []string {
+	abs := make([]string, len(roots))
+	for i, root := range roots {
+		if a, err := filepath.Abs(root); err == nil {
+			abs[i] = a
+		} else {
+			abs[i] = root
+		}
+	}
+
+	retval := make([]string, 0, len(roots))
+	for i, root := range roots {
+		reached := false
+		for j, outer := range roots {
+			if i != j && isWithin(abs[i], abs[j]) && s.walkReaches(outer, abs[j], abs[i]) {
+				s.debug(s.colors.Blue, "Root", root, "is searched as part of", outer, s.colors.Restore)
+				reached = true
+				break
+			}
+		}
+		if !reached {
+			retval = append(retval, root)
+		}
+	}
+	return retval
+}
+
+// walkReaches reports whether a walk of outer, whose absolute path is
+// outerAbs, descends to innerAbs: none of the directories on the way may be
+// excluded, hidden, a symlink without --follow or at --max-depth
+func (s *Searcher) walkReaches(outer string, outerAbs string, innerAbs string) bool {
+	rel, err := filepath.Rel(outerAbs, innerAbs)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	dirs := parts[:len(parts)-1]
+	info, err := fs.Stat(s.fsys, s.join(outer, rel))
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		dirs = parts
+	}
+	if s.settings.MaxDepth != MaxDepthUnlimited && len(dirs) >= s.settings.MaxDepth {
+		return false
+	}
+
+	dir := outer
+	if s.settings.ShouldExcludeDir(dir) || s.settings.IsHidden(dir) {
+		return false
+	}
+	for _, name := range dirs {
+		dir = s.join(dir, name)
+		if s.settings.ShouldExcludeDir(dir) || s.settings.ExcludedByGlob(globPath(outer, dir)) || s.settings.IsHidden(dir) {
+			return false
+		}
+		if s.settings.FollowSymlinks {
+			continue
+		}
+		if linkInfo, err := fs.Lstat(s.fsys, dir); err != nil || linkInfo.Mode()&fs.ModeSymlink != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// walkRoots walks each root in turn, queueing eligible files onto filesToScan
+func (s *Searcher) walkRoots(roots []string) {
+	visited := map[fileID]bool{}
+	for _, root := range s.dropReachedRoots(roots) {
+		if s.stats.Interrupted() {
+			break
+		}
+		s.debug(s.colors.Blue, "Walking root:", s.colors.Restore, root)
+		walkFn := s.depthLimitedWalkFunc(root)
+		s.walkTree(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil && path == root {
+				// Unlike the files below it, a start directory that can't be read is an error
+				s.reportError(err)
+				s.stats.incrUnreadableRootCount()
+			}
+			return walkFn(path, info, err)
+		}, visited)
+	}
+}
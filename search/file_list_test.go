@@ -0,0 +1,138 @@
+package search
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------------------------------------------------------------------------
+// listedFiles: --files-from parsing and filtering
+// ---------------------------------------------------------------------------
+
+func collectListed(list string) []string {
+	var paths []string
+	for path := range searcher.listedFiles(io.NopCloser(strings.NewReader(list))) {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestListedFilesNewlineSeparated(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	a := filepath.Join(tmpDir, "a.go")
+	b := filepath.Join(tmpDir, "b.go")
+	mustWriteFile(t, a, "a\n")
+	mustWriteFile(t, b, "b\n")
+
+	paths := collectListed(a + "\r\n\n" + b + "\n")
+	if len(paths) != 2 || paths[0] != a || paths[1] != b {
+		t.Fatalf("expected [%s %s], got %v", a, b, paths)
+	}
+	if searcher.stats.FileCount() != 2 {
+		t.Errorf("expected 2 files counted, got %d", searcher.stats.FileCount())
+	}
+}
+
+func TestListedFilesNulSeparated(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	odd := filepath.Join(tmpDir, "with\nnewline.txt")
+	mustWriteFile(t, odd, "x\n")
+
+	paths := collectListed(odd + "\x00")
+	if len(paths) != 1 || paths[0] != odd {
+		t.Fatalf("expected [%q], got %q", odd, paths)
+	}
+}
+
+func TestListedFilesAppliesFilters(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.AddExcludes("skip.go")
+	tmpDir := t.TempDir()
+	keep := filepath.Join(tmpDir, "keep.go")
+	skip := filepath.Join(tmpDir, "skip.go")
+	hidden := filepath.Join(tmpDir, ".hidden.go")
+	missing := filepath.Join(tmpDir, "missing.go")
+	mustWriteFile(t, keep, "x\n")
+	mustWriteFile(t, skip, "x\n")
+	mustWriteFile(t, hidden, "x\n")
+
+	paths := collectListed(strings.Join([]string{keep, skip, hidden, missing, tmpDir}, "\n"))
+	if len(paths) != 1 || paths[0] != keep {
+		t.Fatalf("expected only %q, got %v", keep, paths)
+	}
+	if searcher.stats.ErroredFilesCount() != 1 {
+		t.Errorf("expected missing file to be counted as errored, got %d", searcher.stats.ErroredFilesCount())
+	}
+}
+
+// ---------------------------------------------------------------------------
+// normalizeRoots / multiple start directories
+// ---------------------------------------------------------------------------
+
+func TestNormalizeRootsDeduplicates(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	test := filepath.Join(tmpDir, "test")
+	file := filepath.Join(src, "main.go")
+
+	cases := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"distinct", []string{src, test}, []string{src, test}},
+		{"duplicate", []string{src, src + "/"}, []string{src}},
+		{"nested dir", []string{src, tmpDir}, []string{src, tmpDir}},
+		{"file inside dir", []string{file, src}, []string{file, src}},
+		{"blank entries", []string{"", " ", test}, []string{test}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NormalizeRoots(tc.input)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("NormalizeRoots(%v) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDropReachedRoots(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	file := filepath.Join(src, "main.go")
+	vendored := filepath.Join(tmpDir, "vendor", "pkg")
+	hidden := filepath.Join(tmpDir, ".config", "app")
+	mustWriteFile(t, file, "x\n")
+	mustWriteFile(t, filepath.Join(vendored, "dep.go"), "x\n")
+	mustWriteFile(t, filepath.Join(hidden, "app.go"), "x\n")
+
+	cases := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{"nested dir", []string{src, tmpDir}, []string{tmpDir}},
+		{"file inside dir", []string{file, src}, []string{src}},
+		{"below an excluded dir", []string{tmpDir, vendored}, []string{tmpDir, vendored}},
+		{"below a hidden dir", []string{tmpDir, hidden}, []string{tmpDir, hidden}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := searcher.dropReachedRoots(tc.input)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("dropReachedRoots(%v) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+
+	searcher.settings.MaxDepth = 1
+	if got := searcher.dropReachedRoots([]string{tmpDir, file}); len(got) != 2 {
+		t.Errorf("expected a root beyond --max-depth to be kept, got %v", got)
+	}
+}
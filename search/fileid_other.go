@@ -0,0 +1,22 @@
+//go:build !unix
+
+package search
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileIdentity returns the fully resolved path of the file, since device and
+// inode numbers aren't available on this platform
+func fileIdentity(path string, info os.FileInfo) (fileID, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileID{}, false
+	}
+	abs, err := filepath.Abs(resolved)
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: abs}, true
+}
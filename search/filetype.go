@@ -0,0 +1,215 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileType is a named group of files selected with --type and --type-not.
+// A file belongs to the type if its name ends in one of the extensions, its
+// name matches one of the filename globs, or (for files without an
+// extension) its #! line runs one of the interpreters.
+type FileType struct {
+	Name       string   `yaml:"-"`
+	Extensions []string `yaml:"extensions"`
+	Filenames  []string `yaml:"filenames"`
+	Shebangs   []string `yaml:"shebangs"`
+}
+
+// shebangSniffSize is the longest #! line that is examined
+const shebangSniffSize = 256
+
+var builtinFileTypes = []FileType{
+	{Name: "c", Extensions: []string{".c", ".h"}},
+	{Name: "cpp", Extensions: []string{".cpp", ".cc", ".cxx", ".c++", ".hpp", ".hh", ".hxx", ".h++", ".inl"}},
+	{Name: "csharp", Extensions: []string{".cs", ".csx"}},
+	{Name: "css", Extensions: []string{".css", ".scss", ".sass", ".less"}},
+	{Name: "docker", Filenames: []string{"Dockerfile", "Dockerfile.*", "*.dockerfile", "Containerfile"}},
+	{Name: "elixir", Extensions: []string{".ex", ".exs", ".eex", ".heex", ".leex"}, Shebangs: []string{"elixir"}},
+	{Name: "erlang", Extensions: []string{".erl", ".hrl"}, Shebangs: []string{"escript"}},
+	{Name: "go", Extensions: []string{".go"}, Filenames: []string{"go.mod", "go.work"}},
+	{Name: "html", Extensions: []string{".html", ".htm", ".xhtml"}},
+	{Name: "java", Extensions: []string{".java"}},
+	{Name: "js", Extensions: []string{".js", ".jsx", ".mjs", ".cjs", ".vue"}, Shebangs: []string{"node"}},
+	{Name: "json", Extensions: []string{".json", ".jsonl", ".ndjson", ".geojson"}},
+	{Name: "kotlin", Extensions: []string{".kt", ".kts"}},
+	{Name: "lua", Extensions: []string{".lua"}, Shebangs: []string{"lua"}},
+	{Name: "make", Extensions: []string{".mk", ".mak"}, Filenames: []string{"Makefile", "GNUmakefile", "makefile"}},
+	{Name: "markdown", Extensions: []string{".md", ".markdown", ".mdown", ".mkd", ".mdx"}},
+	{Name: "perl", Extensions: []string{".pl", ".pm", ".t"}, Shebangs: []string{"perl"}},
+	{Name: "php", Extensions: []string{".php", ".phtml"}, Shebangs: []string{"php"}},
+	{Name: "proto", Extensions: []string{".proto"}},
+	{Name: "py", Extensions: []string{".py", ".pyi", ".pyw"}, Shebangs: []string{"python"}},
+	{Name: "ruby", Extensions: []string{".rb", ".rake", ".gemspec", ".erb"}, Filenames: []string{"Gemfile", "Rakefile", "Guardfile"}, Shebangs: []string{"ruby"}},
+	{Name: "rust", Extensions: []string{".rs"}},
+	{Name: "scala", Extensions: []string{".scala", ".sc", ".sbt"}},
+	{Name: "shell", Extensions: []string{".sh", ".bash", ".zsh", ".fish", ".ksh"}, Filenames: []string{".bashrc", ".bash_profile", ".zshrc", ".profile"}, Shebangs: []string{"sh", "bash", "zsh", "fish", "ksh", "dash"}},
+	{Name: "sql", Extensions: []string{".sql"}},
+	{Name: "swift", Extensions: []string{".swift"}},
+	{Name: "toml", Extensions: []string{".toml"}},
+	{Name: "ts", Extensions: []string{".ts", ".tsx", ".mts", ".cts"}, Shebangs: []string{"deno", "ts-node"}},
+	{Name: "xml", Extensions: []string{".xml", ".xsd", ".xsl", ".xslt", ".svg", ".plist"}},
+	{Name: "yaml", Extensions: []string{".yaml", ".yml"}},
+}
+
+// BuiltinFileTypeNames returns the names of the built in types in order
+func BuiltinFileTypeNames() []string {
+	names := make([]string, 0, len(builtinFileTypes))
+	for _, t := range builtinFileTypes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// newFileTypeRegistry returns a registry holding a copy of the built in types
+func newFileTypeRegistry() map[string]*FileType {
+	registry := make(map[string]*FileType, len(builtinFileTypes))
+	for _, t := range builtinFileTypes {
+		copied := t
+		registry[t.Name] = &copied
+	}
+	return registry
+}
+
+// normalizeFileType lowercases the extensions of t and makes sure they start
+// with a dot, so both "go" and ".go" may be used in the config file
+func normalizeFileType(t FileType) (FileType, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" || strings.ContainsAny(t.Name, ", ") {
+		return t, fmt.Errorf("invalid file type name %q", t.Name)
+	}
+
+	extensions := make([]string, 0, len(t.Extensions))
+	for _, ext := range t.Extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	t.Extensions = extensions
+
+	for _, glob := range t.Filenames {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return t, fmt.Errorf("invalid filename %q for file type %q: %w", glob, t.Name, err)
+		}
+	}
+
+	if len(t.Extensions) == 0 && len(t.Filenames) == 0 && len(t.Shebangs) == 0 {
+		return t, fmt.Errorf("file type %q needs at least one of extensions, filenames or shebangs", t.Name)
+	}
+	return t, nil
+}
+
+// matchesName reports whether a file named base belongs to the type without
+// looking at its content
+func (t *FileType) matchesName(base string) bool {
+	lower := strings.ToLower(base)
+	for _, ext := range t.Extensions {
+		if strings.HasSuffix(lower, ext) && len(lower) > len(ext) {
+			return true
+		}
+	}
+	for _, glob := range t.Filenames {
+		if matched, _ := filepath.Match(glob, base); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesInterpreter reports whether the #! interpreter belongs to the type.
+// Versioned interpreters such as python3.12 match "python".
+func (t *FileType) matchesInterpreter(interpreter string) bool {
+	for _, name := range t.Shebangs {
+		if rest, ok := strings.CutPrefix(interpreter, name); ok && strings.Trim(rest, "0123456789.") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *FileType) String() string {
+	parts := make([]string, 0, len(t.Extensions)+len(t.Filenames)+len(t.Shebangs))
+	for _, ext := range t.Extensions {
+		parts = append(parts, "*"+ext)
+	}
+	parts = append(parts, t.Filenames...)
+	for _, interpreter := range t.Shebangs {
+		parts = append(parts, "#!"+interpreter)
+	}
+	return t.Name + ": " + strings.Join(parts, ", ")
+}
+
+// hasExtension reports whether base has an extension, ignoring the leading
+// dot of hidden files
+func hasExtension(base string) bool {
+	return strings.Contains(strings.TrimPrefix(base, "."), ".")
+}
+
+// shebangInterpreter returns the name of the program on the #! line of the
+// file at path, looking through "env", or "" if there isn't one
+func shebangInterpreter(fsys fs.FS, path string) string {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	head, _ := bufio.NewReaderSize(file, shebangSniffSize).Peek(shebangSniffSize)
+	return headInterpreter(head)
+}
+
+// headInterpreter is shebangInterpreter for head, the start of a file
+func headInterpreter(head []byte) string {
+	line, _, _ := strings.Cut(string(head), "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	return interpreter
+}
+
+// splitTypeNames splits repeated and comma separated --type values
+func splitTypeNames(values []string) []string {
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(name); trimmed != "" {
+				names = append(names, trimmed)
+			}
+		}
+	}
+	return names
+}
+
+// sortedFileTypeNames returns the keys of types in a stable order
+func sortedFileTypeNames(types map[string]FileType) []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
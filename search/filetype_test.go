@@ -0,0 +1,155 @@
+package search
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// ---------------------------------------------------------------------------
+// FileType matching
+// ---------------------------------------------------------------------------
+
+func TestFileTypeMatchesName(t *testing.T) {
+	cases := []struct {
+		typeName string
+		base     string
+		want     bool
+	}{
+		{"go", "main.go", true},
+		{"go", "go.mod", true},
+		{"go", "main.gox", false},
+		{"js", "App.JSX", true},
+		{"ts", "types.d.ts", true},
+		{"c", ".h", false},
+		{"docker", "Dockerfile", true},
+		{"docker", "Dockerfile.dev", true},
+		{"make", "Makefile", true},
+		{"markdown", "README.md", true},
+		{"markdown", "README", false},
+		{"shell", ".bashrc", true},
+	}
+	registry := newFileTypeRegistry()
+	for _, tc := range cases {
+		if got := registry[tc.typeName].matchesName(tc.base); got != tc.want {
+			t.Errorf("%s.matchesName(%q) = %v, want %v", tc.typeName, tc.base, got, tc.want)
+		}
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	cases := map[string]string{
+		"#!/bin/sh\necho hi\n":                "sh",
+		"#!/usr/bin/env python3\nprint(1)\n":  "python3",
+		"#!/usr/bin/env -S node --inspect\n":  "node",
+		"#! /usr/local/bin/ruby -w\nputs 1\n": "ruby",
+		"#!/usr/bin/env\n":                    "",
+		"echo no shebang\n":                   "",
+		"":                                    "",
+	}
+	for content, want := range cases {
+		fsys := fstest.MapFS{"script": {Data: []byte(content)}}
+		if got := shebangInterpreter(fsys, "script"); got != want {
+			t.Errorf("shebangInterpreter(%q) = %q, want %q", content, got, want)
+		}
+	}
+	if got := shebangInterpreter(fstest.MapFS{}, "missing"); got != "" {
+		t.Errorf("expected no interpreter for a missing file, got %q", got)
+	}
+}
+
+func TestFileTypeMatchesInterpreter(t *testing.T) {
+	py := newFileTypeRegistry()["py"]
+	for interpreter, want := range map[string]bool{
+		"python":     true,
+		"python3":    true,
+		"python3.12": true,
+		"pythonw":    false,
+		"perl":       false,
+	} {
+		if got := py.matchesInterpreter(interpreter); got != want {
+			t.Errorf("py.matchesInterpreter(%q) = %v, want %v", interpreter, got, want)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Settings type filters
+// ---------------------------------------------------------------------------
+
+func TestPassesTypeFilter(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	script := filepath.Join(tmpDir, "deploy")
+	mustWriteFile(t, script, "#!/usr/bin/env bash\necho deploy\n")
+	notes := filepath.Join(tmpDir, "notes")
+	mustWriteFile(t, notes, "plain notes\n")
+
+	if !searcher.settings.PassesTypeFilter("anything.xyz") {
+		t.Error("expected every file to pass without a type filter")
+	}
+
+	if err := searcher.settings.SelectTypes("go,shell"); err != nil {
+		t.Fatalf("SelectTypes: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"main.go":    true,
+		"run.sh":     true,
+		script:       true,
+		notes:        false,
+		"index.js":   false,
+		"a.zip!x.go": true,
+	} {
+		if got := searcher.settings.PassesTypeFilter(path); got != want {
+			t.Errorf("PassesTypeFilter(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	if err := searcher.settings.ExcludeTypes("shell"); err != nil {
+		t.Fatalf("ExcludeTypes: %v", err)
+	}
+	if searcher.settings.PassesTypeFilter(script) || searcher.settings.PassesTypeFilter("run.sh") {
+		t.Error("expected --type-not to win over --type")
+	}
+	if !searcher.settings.PassesTypeFilter("main.go") {
+		t.Error("expected main.go to still pass")
+	}
+}
+
+func TestSelectTypesUnknown(t *testing.T) {
+	resetTestState(t)
+	err := searcher.settings.SelectTypes("go", "cobol")
+	if err == nil || !strings.Contains(err.Error(), `"cobol"`) {
+		t.Fatalf("expected an unknown type error naming cobol, got %v", err)
+	}
+}
+
+func TestAddFileTypes(t *testing.T) {
+	resetTestState(t)
+	err := searcher.settings.AddFileTypes(map[string]FileType{
+		"terraform": {Extensions: []string{"tf", ".TFVARS"}},
+		"go":        {Extensions: []string{".go"}},
+	})
+	if err != nil {
+		t.Fatalf("AddFileTypes: %v", err)
+	}
+	if err := searcher.settings.SelectTypes("terraform", "go"); err != nil {
+		t.Fatalf("SelectTypes: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"main.tf":           true,
+		"prod.tfvars":       true,
+		"main.go":           true,
+		"go.mod":            false, // the built in go type was replaced
+		"terraform.tfstate": false,
+	} {
+		if got := searcher.settings.PassesTypeFilter(path); got != want {
+			t.Errorf("PassesTypeFilter(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	if err := searcher.settings.AddFileTypes(map[string]FileType{"empty": {}}); err == nil {
+		t.Error("expected an error for a type without extensions, filenames or shebangs")
+	}
+}
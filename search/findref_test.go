@@ -0,0 +1,463 @@
+package search
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// searcher is the search the tests configure and inspect.  resetTestState
+// replaces it with a fresh one.
+var searcher *Searcher
+
+func resetTestState(t *testing.T) {
+	t.Helper()
+	searcher = newSearcher(NewSettings())
+}
+
+func mustGetMatchRegex(t *testing.T, ignoreCase bool, matchCase bool, usersRegex string) *regexp.Regexp {
+	t.Helper()
+	r, err := CompileMatchRegex(ignoreCase, matchCase, usersRegex)
+	if err != nil {
+		t.Fatalf("unexpected error compiling regex %q: %v", usersRegex, err)
+	}
+	return r
+}
+
+func mustWriteFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("creating directory for %q: %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing file %q: %v", path, err)
+	}
+}
+
+func TestContainsNullByte(t *testing.T) {
+	if containsNullByte([]byte("Here is a string....")) {
+		t.Fail()
+	}
+	if !containsNullByte([]byte("Here is a \x00string....")) {
+		t.Fail()
+	}
+}
+
+func TestPassesFileFilter(t *testing.T) {
+	s := NewSettings()
+	s.FilenameRegex = regexp.MustCompile("abcd")
+	if !s.PassesFileFilter("abcd.txt") {
+		t.Fail()
+	}
+	if s.PassesFileFilter("abc.txt") {
+		t.Fail()
+	}
+	if !s.PassesFileFilter("abcd") {
+		t.Fail()
+	}
+	s.FilenameRegex = regexp.MustCompile(`.*\.txt`)
+	if !s.PassesFileFilter("abcd.txt") {
+		t.Fail()
+	}
+	if s.PassesFileFilter("abc.tx") {
+		t.Fail()
+	}
+	if s.PassesFileFilter("abcd") {
+		t.Fail()
+	}
+}
+
+func TestIsHidden(t *testing.T) {
+	s := NewSettings()
+	if s.IsHidden("/home/ben") {
+		t.Fail()
+	}
+	if s.IsHidden("/home/ben/nothing.txt") {
+		t.Fail()
+	}
+	if !s.IsHidden("/home/.ben/nothing.txt") {
+		t.Fail()
+	}
+	if !s.IsHidden("/home/ben/.nothing.txt") {
+		t.Fail()
+	}
+	if !s.IsHidden("/home/.ben/.nothing.txt") {
+		t.Fail()
+	}
+}
+
+func TestDefaultExcludePaths(t *testing.T) {
+	s := NewSettings()
+	for _, entry := range defaultExcludeDirs {
+		path := filepath.Join("/tmp/project", entry)
+		if !s.ShouldExcludeDir(path) {
+			t.Fatalf("expected %s to be excluded by default as directory", entry)
+		}
+		if !s.ShouldExcludeFile(path) {
+			t.Fatalf("expected %s to be excluded by default as file", entry)
+		}
+	}
+	if s.ShouldExcludeDir("./src") {
+		t.Fatalf("did not expect src to be excluded by default")
+	}
+	if s.ShouldExcludeFile("./src/file.go") {
+		t.Fatalf("did not expect src/file.go to be excluded by default")
+	}
+}
+
+func TestDisableDefaultExcludes(t *testing.T) {
+	s := NewSettings()
+	s.UseDefaultExcludes = false
+	for _, dir := range defaultExcludeDirs {
+		path := filepath.Join("/tmp/project", dir)
+		if s.ShouldExcludeDir(path) {
+			t.Fatalf("did not expect %s to be excluded when defaults disabled", dir)
+		}
+	}
+	s.AddExcludeDirs("vendor")
+	if !s.ShouldExcludeDir(filepath.Join("/tmp/project", "vendor")) {
+		t.Fatalf("expected vendor to be excluded when user provided")
+	}
+}
+
+func TestShouldExcludeDirUserProvided(t *testing.T) {
+	s := NewSettings()
+	s.AddExcludeDirs("vendor", "./build/")
+	if !s.ShouldExcludeDir("/tmp/project/vendor") {
+		t.Fatalf("expected vendor directory to be excluded when provided")
+	}
+	if !s.ShouldExcludeDir("./build") {
+		t.Fatalf("expected build directory to be excluded when provided")
+	}
+	if s.ShouldExcludeDir("/tmp/project/src") {
+		t.Fatalf("did not expect src directory to be excluded")
+	}
+}
+
+func TestShouldExcludeFileUserProvided(t *testing.T) {
+	s := NewSettings()
+	s.AddExcludes("README.md", "src/generated.go")
+	if !s.ShouldExcludeFile("/tmp/project/docs/README.md") {
+		t.Fatalf("expected README.md files to be excluded when provided")
+	}
+	if !s.ShouldExcludeFile("/tmp/project/src/generated.go") {
+		t.Fatalf("expected generated.go to be excluded when provided with relative path")
+	}
+	if s.ShouldExcludeFile("/tmp/project/src/generated_test.go") {
+		t.Fatalf("did not expect generated_test.go to be excluded")
+	}
+}
+
+func TestPathExcluded(t *testing.T) {
+	s := NewSettings()
+	s.AddExcludes("generated.go")
+	cases := []struct {
+		path string
+		want bool
+	}{
+		{"/tmp/project/src/main.go", false},
+		{"/tmp/project/src/generated.go", true},
+		{"/tmp/project/node_modules/lib/index.js", true},
+		{"/tmp/project/.config/settings.go", true},
+		{"/tmp/project/src/.env", true},
+		{"/tmp/other/main.go", true},
+	}
+	for _, c := range cases {
+		if got := s.PathExcluded("/tmp/project", c.path); got != c.want {
+			t.Errorf("PathExcluded(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+	s.IncludeHidden = true
+	if s.PathExcluded("/tmp/project", "/tmp/project/.config/settings.go") {
+		t.Errorf("did not expect a hidden directory to be excluded when hidden files are included")
+	}
+}
+
+func TestGetMatchRegex(t *testing.T) {
+	r1 := mustGetMatchRegex(t, false, false, "HEllo")
+	if !r1.MatchString("HEllo") {
+		t.Fail()
+	}
+	if r1.MatchString("hello") {
+		t.Fail()
+	}
+	r2 := mustGetMatchRegex(t, false, false, "hello")
+	//  verify smart case works
+	if !r2.MatchString("abcHEllo") {
+		t.Fail()
+	}
+	if !r2.MatchString("abchello") {
+		t.Fail()
+	}
+	if !r2.MatchString("abcHELLO") {
+		t.Fail()
+	}
+	if r2.MatchString("abc") {
+		t.Fail()
+	}
+	r3 := mustGetMatchRegex(t, true, false, "hello")
+	if !r3.MatchString("HEllo") {
+		t.Fail()
+	}
+	if !r3.MatchString("abchello") {
+		t.Fail()
+	}
+	if !r3.MatchString("abcHELLO") {
+		t.Fail()
+	}
+	if r3.MatchString("abc") {
+		t.Fail()
+	}
+	r4 := mustGetMatchRegex(t, false, true, "hello")
+	if r4.MatchString("HEllo") {
+		t.Fail()
+	}
+	if !r4.MatchString("abchello") {
+		t.Fail()
+	}
+	if r4.MatchString("abcHELLO") {
+		t.Fail()
+	}
+	if r4.MatchString("abc") {
+		t.Fail()
+	}
+}
+
+func TestGetMatchRegexInvalid(t *testing.T) {
+	_, err := CompileMatchRegex(false, false, "(")
+	if err == nil {
+		t.Fatalf("expected invalid regex to return an error")
+	}
+	if !strings.Contains(err.Error(), "invalid match regex") {
+		t.Fatalf("expected error to mention invalid match regex, got %q", err.Error())
+	}
+}
+
+func TestCheckForMatches(t *testing.T) {
+	resetTestState(t)
+	searcher.fsys = fstest.MapFS{
+		"notes.txt": {Data: []byte("no match here\nTODO: finish tests\n")},
+	}
+	searcher.settings.MatchRegex = regexp.MustCompile("TODO")
+
+	matches := searcher.checkForMatches("notes.txt")
+	if searcher.stats.LineCount() != 2 {
+		t.Fatalf("expected 2 lines scanned, got %d", searcher.stats.LineCount())
+	}
+	if searcher.stats.MatchCount() != 1 {
+		t.Fatalf("expected 1 match recorded, got %d", searcher.stats.MatchCount())
+	}
+	if len(matches) != 1 || !matches[0].hasMatch() {
+		t.Fatalf("expected one match in returned slice, got %+v", matches)
+	}
+	if matches[0].Path != "notes.txt" {
+		t.Fatalf("expected match path %q, got %q", "notes.txt", matches[0].Path)
+	}
+	if matches[0].LineNumber != 2 {
+		t.Fatalf("expected match on line 2, got %d", matches[0].LineNumber)
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	resetTestState(t)
+	fsys := fstest.MapFS{
+		".git/config": {Data: []byte("[core]\n")},
+		"main.go":     {Data: []byte("package main\n")},
+	}
+	searcher.fsys = fsys
+
+	hiddenInfo, err := fs.Stat(fsys, ".git")
+	if err != nil {
+		t.Fatalf("stat hidden dir: %v", err)
+	}
+	if ret := searcher.processFile("", ".git", hiddenInfo, nil); ret != filepath.SkipDir {
+		t.Fatalf("expected hidden dir to return filepath.SkipDir, got %v", ret)
+	}
+
+	fileInfo, err := fs.Stat(fsys, "main.go")
+	if err != nil {
+		t.Fatalf("stat file: %v", err)
+	}
+	if ret := searcher.processFile("", "main.go", fileInfo, nil); ret != fileProcessingComplete {
+		t.Fatalf("expected file processing to return nil, got %v", ret)
+	}
+	if len(searcher.filesToScan) != 1 {
+		t.Fatalf("expected one file queued, got %d", len(searcher.filesToScan))
+	}
+	if searcher.filesToScan[0].Path != "main.go" {
+		t.Fatalf("expected queued path %q, got %q", "main.go", searcher.filesToScan[0].Path)
+	}
+	if searcher.stats.FilesToScanCount() != 1 {
+		t.Fatalf("expected statistics to record 1 file to scan, got %d", searcher.stats.FilesToScanCount())
+	}
+	if searcher.stats.FileCount() != 1 {
+		t.Fatalf("expected statistics to record 1 processed file, got %d", searcher.stats.FileCount())
+	}
+}
+
+type stubFileInfo struct {
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (s stubFileInfo) Name() string       { return "stub" }
+func (s stubFileInfo) Size() int64        { return s.size }
+func (s stubFileInfo) Mode() os.FileMode  { return s.mode }
+func (s stubFileInfo) ModTime() time.Time { return s.modTime }
+func (s stubFileInfo) IsDir() bool        { return s.mode.IsDir() }
+func (s stubFileInfo) Sys() interface{}   { return nil }
+
+func TestScannerBufferLimits(t *testing.T) {
+	cases := []struct {
+		name        string
+		info        os.FileInfo
+		setup       func(t *testing.T)
+		wantInitial int
+		wantMax     int
+	}{
+		{
+			name: "small file respects minimum cap",
+			info: stubFileInfo{size: 1024},
+			setup: func(t *testing.T) {
+				resetTestState(t)
+			},
+			wantInitial: scannerMinimumInitialCap,
+			wantMax:     scannerMinimumInitialCap,
+		},
+		{
+			name: "large file uses file size",
+			info: stubFileInfo{size: 10 * 1024 * 1024},
+			setup: func(t *testing.T) {
+				resetTestState(t)
+			},
+			wantInitial: scannerDefaultInitialCap,
+			wantMax:     10 * 1024 * 1024,
+		},
+		{
+			name: "huge file clamps to max token cap",
+			info: stubFileInfo{size: int64(scannerMaxTokenCap) + (32 * 1024 * 1024)},
+			setup: func(t *testing.T) {
+				resetTestState(t)
+			},
+			wantInitial: scannerDefaultInitialCap,
+			wantMax:     scannerMaxTokenCap,
+		},
+		{
+			name: "no stat info with no max line length uses max token cap",
+			info: nil,
+			setup: func(t *testing.T) {
+				resetTestState(t)
+				searcher.settings.NoMaxLineLength = true
+			},
+			wantInitial: scannerDefaultInitialCap,
+			wantMax:     scannerMaxTokenCap,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.setup(t)
+			t.Cleanup(func() {
+				resetTestState(t)
+			})
+			initial, max := searcher.scannerBufferLimits(tc.info)
+			if initial != tc.wantInitial {
+				t.Fatalf("initial cap = %d, want %d", initial, tc.wantInitial)
+			}
+			if max != tc.wantMax {
+				t.Fatalf("max token = %d, want %d", max, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestExcludePatternBasic(t *testing.T) {
+	s := NewSettings()
+	if err := s.AddExcludePatterns(`_test\.go$`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.ShouldExcludeFile("src/foo_test.go") {
+		t.Fatalf("expected _test.go file to be excluded by pattern")
+	}
+	if s.ShouldExcludeFile("src/foo.go") {
+		t.Fatalf("did not expect foo.go to be excluded by pattern")
+	}
+}
+
+func TestExcludePatternDir(t *testing.T) {
+	s := NewSettings()
+	if err := s.AddExcludePatterns(`(^|/)generated($|/)`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.ShouldExcludeDir("src/generated") {
+		t.Fatalf("expected generated dir to be excluded by pattern")
+	}
+	if s.ShouldExcludeDir("src/generator") {
+		t.Fatalf("did not expect generator dir to be excluded")
+	}
+}
+
+func TestExcludePatternInvalid(t *testing.T) {
+	s := NewSettings()
+	err := s.AddExcludePatterns("(")
+	if err == nil {
+		t.Fatalf("expected error for invalid regex pattern")
+	}
+	if !strings.Contains(err.Error(), "invalid exclude-pattern") {
+		t.Fatalf("expected error to mention invalid exclude-pattern, got %q", err.Error())
+	}
+}
+
+func TestExcludePatternMultiple(t *testing.T) {
+	s := NewSettings()
+	if err := s.AddExcludePatterns(`_test\.go$`, `\.min\.js$`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.ShouldExcludeFile("src/app_test.go") {
+		t.Fatalf("expected test file to be excluded")
+	}
+	if !s.ShouldExcludeFile("dist/bundle.min.js") {
+		t.Fatalf("expected minified JS to be excluded")
+	}
+	if s.ShouldExcludeFile("src/app.go") {
+		t.Fatalf("did not expect app.go to be excluded")
+	}
+}
+
+func TestExcludePatternCombinedWithLiteral(t *testing.T) {
+	s := NewSettings()
+	s.UseDefaultExcludes = false
+	s.AddExcludes("myvendor")
+	if err := s.AddExcludePatterns(`_test\.go$`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.ShouldExcludeDir("project/myvendor") {
+		t.Fatalf("expected myvendor to be excluded by literal")
+	}
+	if !s.ShouldExcludeFile("src/app_test.go") {
+		t.Fatalf("expected test file to be excluded by pattern")
+	}
+	if s.ShouldExcludeFile("src/app.go") {
+		t.Fatalf("did not expect app.go to be excluded")
+	}
+}
+
+func TestExcludePatternAccessor(t *testing.T) {
+	s := NewSettings()
+	if err := s.AddExcludePatterns(`_test\.go$`, `\.min\.js$`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	patterns := s.ExcludePatterns()
+	if len(patterns) != 2 {
+		t.Fatalf("expected 2 patterns, got %d", len(patterns))
+	}
+	if patterns[0] != `_test\.go$` {
+		t.Fatalf("expected first pattern %q, got %q", `_test\.go$`, patterns[0])
+	}
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxLinkHops is how many symlinks evalFSLinks follows before giving up, the
+// same limit as filepath.EvalSymlinks
+const maxLinkHops = 255
+
+// osFS is the operating system's filesystem as an fs.FS.  Unlike os.DirFS it
+// takes any path the os package does, absolute or relative, so that the paths
+// given on the command line are searched and reported as they are.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFS) ReadLink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// onOSFilesystem reports whether the search is of the operating system's
+// filesystem rather than an fs.FS given in the Options
+func (s *Searcher) onOSFilesystem() bool {
+	_, ok := s.fsys.(osFS)
+	return ok
+}
+
+// join joins a directory and the name of an entry in it, with the separator
+// of the filesystem being searched
+func (s *Searcher) join(dir string, name string) string {
+	if s.onOSFilesystem() {
+		return filepath.Join(dir, name)
+	}
+	return path.Join(dir, name)
+}
+
+// walkFS walks root like filepath.Walk, but within an fs.FS
+func (s *Searcher) walkFS(root string, fn filepath.WalkFunc) error {
+	return fs.WalkDir(s.fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if d == nil {
+			return fn(path, nil, err)
+		}
+		info, infoErr := d.Info()
+		if infoErr != nil {
+			return fn(path, nil, infoErr)
+		}
+		return fn(path, info, err)
+	})
+}
+
+// dirIdentity identifies a directory for symlink cycle detection.  Within an
+// fs.FS, which has no inode numbers, it is the path with its links resolved.
+func (s *Searcher) dirIdentity(path string, info fs.FileInfo) (fileID, bool) {
+	if s.onOSFilesystem() {
+		return fileIdentity(path, info)
+	}
+	resolved, err := evalFSLinks(s.fsys, path)
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: resolved}, true
+}
+
+// evalFSLinks returns name with every symlink in it resolved, like
+// filepath.EvalSymlinks does for the operating system's filesystem
+func evalFSLinks(fsys fs.FS, name string) (string, error) {
+	resolved := "."
+	rest := strings.Split(path.Clean(name), "/")
+	for hops := 0; len(rest) > 0; {
+		part := rest[0]
+		rest = rest[1:]
+		if part == "." || part == "" {
+			continue
+		}
+		next := path.Join(resolved, part)
+		info, err := fs.Lstat(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxLinkHops {
+			return "", fmt.Errorf("resolving %q: too many links", name)
+		}
+		target, err := fs.ReadLink(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", &fs.PathError{Op: "readlink", Path: next, Err: fs.ErrInvalid}
+		}
+		// A relative target is relative to the directory holding the link
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
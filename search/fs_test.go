@@ -0,0 +1,232 @@
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// searchPaths runs a search and returns the sorted paths of its matches
+func searchPaths(t *testing.T, opts Options) []string {
+	t.Helper()
+	results, err := Search(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	paths := []string{}
+	for m := range results.Matches() {
+		paths = append(paths, m.Path)
+	}
+	results.Wait()
+	sort.Strings(paths)
+	return paths
+}
+
+// searchStats runs a search and returns its final statistics
+func searchStats(t *testing.T, opts Options) *Statistics {
+	t.Helper()
+	results, err := Search(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	return results.Wait()
+}
+
+func TestSearchMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":              {Data: []byte("package main\n// TODO main\n")},
+		"docs/notes.md":        {Data: []byte("TODO docs\n")},
+		"node_modules/dep.js":  {Data: []byte("// TODO dependency\n")},
+		".git/config":          {Data: []byte("TODO hidden\n")},
+		"assets/logo.bin":      {Data: []byte("TODO\x00binary\n")},
+		"internal/util/x.go":   {Data: []byte("nothing to see\n")},
+		"internal/util/y_test": {Data: []byte("// TODO test\n")},
+	}
+
+	got := searchPaths(t, Options{Pattern: "TODO", FS: fsys})
+	want := []string{"docs/notes.md", "internal/util/y_test", "main.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	settings := NewSettings()
+	settings.FilenameRegex = mustGetMatchRegex(t, false, false, `\.go$`)
+	got = searchPaths(t, Options{Pattern: "TODO", FS: fsys, Paths: []string{"internal", "main.go"}, Settings: settings})
+	if len(got) != 1 || got[0] != "main.go" {
+		t.Errorf("expected only main.go, got %v", got)
+	}
+}
+
+func TestSearchLeavesSettingsAlone(t *testing.T) {
+	fsys := fstest.MapFS{"a.txt": {Data: []byte("TODO a\nFIXME a\n")}}
+	settings := NewSettings()
+	settings.Debug = true
+	var debug bytes.Buffer
+
+	got := searchPaths(t, Options{Pattern: "TODO", FS: fsys, Settings: settings, DebugOutput: &debug})
+	if len(got) != 1 || settings.MatchRegex != nil {
+		t.Errorf("expected a match without the pattern being written to the settings, got %v and %v", got, settings.MatchRegex)
+	}
+	if !strings.Contains(debug.String(), "a.txt") {
+		t.Errorf("expected the debug output to go to DebugOutput, got %q", debug.String())
+	}
+
+	// The settings can be reused for a search of another pattern
+	if got := searchPaths(t, Options{Pattern: "FIXME", FS: fsys, Settings: settings, DebugOutput: io.Discard}); len(got) != 1 {
+		t.Errorf("expected the settings to be reusable, got %v", got)
+	}
+}
+
+func TestSearchMapFSUnreadableRoot(t *testing.T) {
+	var reported []error
+	results, err := Search(context.Background(), Options{
+		Pattern: "TODO",
+		FS:      fstest.MapFS{"a.txt": {Data: []byte("TODO\n")}},
+		Paths:   []string{"missing"},
+		Errors:  func(err error) { reported = append(reported, err) },
+	})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	stats := results.Wait()
+	if stats.UnreadableRootCount() != 1 || len(reported) != 1 {
+		t.Errorf("expected the missing root to be reported, got %d (%v)", stats.UnreadableRootCount(), reported)
+	}
+}
+
+func TestSearchZipReader(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"src/app.go":   "// TODO app\n",
+		"src/lib.go":   "// done\n",
+		"README.md":    "TODO readme\n",
+		"vendor/x.go":  "// TODO vendored\n",
+		".hidden/y.go": "// TODO hidden\n",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("adding %s: %v", name, err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zip: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	got := searchPaths(t, Options{Pattern: "TODO", FS: zr})
+	want := []string{"README.md", "src/app.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSearchFSFileList(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("TODO a\n")},
+		"dir/b.txt": {Data: []byte("TODO b\n")},
+		"dir/c.txt": {Data: []byte("TODO c\n")},
+	}
+	list := io.NopCloser(strings.NewReader("./a.txt\ndir/c.txt\nmissing.txt\ndir\n"))
+
+	got := searchPaths(t, Options{Pattern: "TODO", FS: fsys, FileList: list})
+	if strings.Join(got, ",") != "a.txt,dir/c.txt" {
+		t.Errorf("expected the listed files, got %v", got)
+	}
+}
+
+func TestSearchFSSymlinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/a.txt":     {Data: []byte("TODO a\n")},
+		"src/loop":      {Mode: fs.ModeSymlink, Data: []byte("../src")},
+		"src/ext":       {Mode: fs.ModeSymlink, Data: []byte("../other")},
+		"src/alias.txt": {Mode: fs.ModeSymlink, Data: []byte("a.txt")},
+		"src/broken":    {Mode: fs.ModeSymlink, Data: []byte("nowhere")},
+		"other/b.txt":   {Data: []byte("TODO b\n")},
+		"link":          {Mode: fs.ModeSymlink, Data: []byte("other")},
+	}
+
+	got := searchPaths(t, Options{Pattern: "TODO", FS: fsys})
+	if strings.Join(got, ",") != "other/b.txt,src/a.txt,src/alias.txt" {
+		t.Errorf("expected linked directories to be skipped, got %v", got)
+	}
+	if stats := searchStats(t, Options{Pattern: "TODO", FS: fsys}); stats.BrokenLinkCount() != 1 {
+		t.Errorf("expected 1 broken link, got %d", stats.BrokenLinkCount())
+	}
+
+	settings := NewSettings()
+	settings.FollowSymlinks = true
+	got = searchPaths(t, Options{Pattern: "TODO", FS: fsys, Paths: []string{"src", "link"}, Settings: settings})
+	if strings.Join(got, ",") != "src/a.txt,src/alias.txt,src/ext/b.txt" {
+		t.Errorf("expected each directory to be searched once, got %v", got)
+	}
+}
+
+func TestEvalFSLinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/file": {Data: []byte("x")},
+		"a/up":     {Mode: fs.ModeSymlink, Data: []byte("..")},
+		"a/down":   {Mode: fs.ModeSymlink, Data: []byte("b")},
+		"self":     {Mode: fs.ModeSymlink, Data: []byte("self")},
+		"abs":      {Mode: fs.ModeSymlink, Data: []byte("/etc")},
+	}
+	for name, want := range map[string]string{
+		"a/b/file":         "a/b/file",
+		"a/down/file":      "a/b/file",
+		"a/up/a/down/file": "a/b/file",
+		"a/up":             ".",
+	} {
+		if got, err := evalFSLinks(fsys, name); err != nil || got != want {
+			t.Errorf("evalFSLinks(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"self", "abs", "a/missing"} {
+		if _, err := evalFSLinks(fsys, name); err == nil {
+			t.Errorf("expected an error resolving %q", name)
+		}
+	}
+}
+
+func TestListFilesMapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":             {Data: []byte("package main\n")},
+		"docs/notes.md":       {Data: []byte("notes\n")},
+		"internal/util/x.go":  {Data: []byte("package util\n")},
+		"node_modules/dep.js": {Data: []byte("dependency\n")},
+		".git/config":         {Data: []byte("hidden\n")},
+	}
+
+	files, stats := ListFiles(context.Background(), Options{FS: fsys})
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{"docs/notes.md", "internal/util/x.go", "main.go"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, paths)
+	}
+	if stats.FileCount() != len(want) {
+		t.Errorf("expected %d files counted, got %d", len(want), stats.FileCount())
+	}
+	if files[0].Info == nil || files[0].Info.Size() != int64(len("notes\n")) {
+		t.Errorf("expected the size of docs/notes.md, got %v", files[0].Info)
+	}
+
+	settings := NewSettings()
+	settings.FilenameRegex = mustGetMatchRegex(t, false, false, `\.go$`)
+	list := io.NopCloser(strings.NewReader("main.go\ndocs/notes.md\ninternal/util/x.go\n"))
+	files, _ = ListFiles(context.Background(), Options{FS: fsys, FileList: list, Settings: settings})
+	if len(files) != 2 || files[0].Path != "main.go" || files[1].Path != "internal/util/x.go" || files[1].Info == nil {
+		t.Errorf("expected the listed .go files in order, got %v", files)
+	}
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// globEntry is a single --glob or --iglob pattern, split into path segments
+type globEntry struct {
+	raw        string
+	segments   []string
+	negated    bool
+	ignoreCase bool
+	anchored   bool
+}
+
+// parseGlob compiles a glob.  A leading '!' negates it, and '**' matches any
+// number of directories.  A glob containing a '/' is anchored at the search
+// root, while one without matches the base name of a path at any depth.
+func parseGlob(value string, ignoreCase bool) (globEntry, error) {
+	entry := globEntry{raw: value, ignoreCase: ignoreCase}
+	pattern := strings.TrimSpace(value)
+	if strings.HasPrefix(pattern, "!") {
+		entry.negated = true
+		pattern = pattern[1:]
+	}
+	pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+	entry.anchored = strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return entry, fmt.Errorf("invalid glob %q: empty pattern", value)
+	}
+	if ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	entry.segments = strings.Split(pattern, "/")
+	for _, segment := range entry.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return entry, fmt.Errorf("invalid glob %q: %w", value, err)
+		}
+	}
+	return entry, nil
+}
+
+// matches reports whether the glob matches the path segments, which are
+// relative to the search root.  "*.go" matches a file in any directory, but
+// "cmd/*/main.go" only matches below the cmd directory at the root.
+func (g *globEntry) matches(segments []string) bool {
+	if !g.anchored {
+		return len(segments) > 0 && matchSegments(g.segments, segments[len(segments)-1:])
+	}
+	return matchSegments(g.segments, segments)
+}
+
+// matchSegments matches glob segments against path segments, with "**"
+// standing for zero or more whole segments
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(pattern[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// splitGlobPath splits a file path into the segments globs are matched against
+func splitGlobPath(p string) []string {
+	return strings.Split(filepath.ToSlash(filepath.Clean(p)), "/")
+}
+
+// globPath returns path relative to root, the search root it was found
+// under, which is the path globs are matched against.  Paths that aren't
+// under a root, like those of --files-from, are matched as given, and a root
+// that is the file itself is matched by its name.
+func globPath(root string, path string) string {
+	if root == "" {
+		return path
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	if rel == "." {
+		return filepath.Base(path)
+	}
+	return rel
+}
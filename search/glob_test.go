@@ -0,0 +1,117 @@
+package search
+
+import "testing"
+
+// ---------------------------------------------------------------------------
+// Glob matching
+// ---------------------------------------------------------------------------
+
+func TestGlobMatches(t *testing.T) {
+	cases := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/tool/main.go", true},
+		{"*.go", "main.go.orig", false},
+		{"**/*_mock.go", "store_mock.go", true},
+		{"**/*_mock.go", "internal/store/store_mock.go", true},
+		{"**/*_mock.go", "internal/store/store.go", false},
+		{"cmd/*/main.go", "cmd/tool/main.go", true},
+		{"cmd/*/main.go", "src/cmd/tool/main.go", false},
+		{"cmd/*/main.go", "cmd/a/b/main.go", false},
+		{"cmd/**/main.go", "cmd/a/b/main.go", true},
+		{"vendor/**", "vendor", true},
+		{"vendor/**", "a/vendor/lib/x.go", false},
+		{"**/vendor/**", "a/vendor/lib/x.go", true},
+		{"src/*.go", "src/b.go", true},
+		{"src/*.go", "a/src/b.go", false},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"/*.go", "main.go", true},
+		{"/*.go", "cmd/main.go", false},
+		{"testdata/", "pkg/testdata", true},
+		{"[ab]*.txt", "dir/beta.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"*.go", "/tmp/abs/main.go", true},
+	}
+	for _, tc := range cases {
+		entry, err := parseGlob(tc.glob, false)
+		if err != nil {
+			t.Fatalf("parseGlob(%q): %v", tc.glob, err)
+		}
+		if got := entry.matches(splitGlobPath(tc.path)); got != tc.want {
+			t.Errorf("glob %q against %q = %v, want %v", tc.glob, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestParseGlobErrors(t *testing.T) {
+	for _, glob := range []string{"", "!", "[a-"} {
+		if _, err := parseGlob(glob, false); err == nil {
+			t.Errorf("expected an error for glob %q", glob)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Settings glob filter
+// ---------------------------------------------------------------------------
+
+func TestPassesGlobFilterOrdered(t *testing.T) {
+	resetTestState(t)
+	if err := searcher.settings.AddGlobs(false, "*.go", "!**/*_mock.go", "special_mock.go"); err != nil {
+		t.Fatalf("AddGlobs: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"main.go":                true,
+		"store/store_mock.go":    false,
+		"store/special_mock.go":  true, // the last matching glob wins
+		"README.md":              false,
+		"scripts/build.sh":       false,
+		"store/store_mock.go.gz": false,
+	} {
+		if got := searcher.settings.PassesGlobFilter(path); got != want {
+			t.Errorf("PassesGlobFilter(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestPassesGlobFilterOnlyNegations(t *testing.T) {
+	resetTestState(t)
+	if err := searcher.settings.AddGlobs(false, "!*.min.js", "!testdata"); err != nil {
+		t.Fatalf("AddGlobs: %v", err)
+	}
+	if !searcher.settings.PassesGlobFilter("src/app.js") {
+		t.Error("expected unmatched files to pass when every glob is negated")
+	}
+	if searcher.settings.PassesGlobFilter("dist/app.min.js") {
+		t.Error("expected app.min.js to be excluded")
+	}
+	if !searcher.settings.ExcludedByGlob("pkg/testdata") {
+		t.Error("expected the testdata directory to be pruned")
+	}
+	if searcher.settings.ExcludedByGlob("pkg/src") {
+		t.Error("did not expect pkg/src to be pruned")
+	}
+}
+
+func TestIglob(t *testing.T) {
+	resetTestState(t)
+	if err := searcher.settings.AddGlobs(true, "*.MD"); err != nil {
+		t.Fatalf("AddGlobs: %v", err)
+	}
+	if err := searcher.settings.AddGlobs(false, "!CHANGELOG.md"); err != nil {
+		t.Fatalf("AddGlobs: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"README.md":    true,
+		"docs/x.Md":    true,
+		"CHANGELOG.md": false,
+		"changelog.md": true, // the negation is case-sensitive
+	} {
+		if got := searcher.settings.PassesGlobFilter(path); got != want {
+			t.Errorf("PassesGlobFilter(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Interruption records why a search was stopped before it finished
+type Interruption int32
+
+const (
+	NotInterrupted Interruption = iota
+	InterruptedByTimeout
+	InterruptedByCancel
+)
+
+func (i Interruption) String() string {
+	switch i {
+	case InterruptedByTimeout:
+		return "timed out"
+	case InterruptedByCancel:
+		return "was interrupted"
+	default:
+		return "not interrupted"
+	}
+}
+
+// interruptWhenDone stops the search once ctx is done.  Calling the returned
+// function stops watching ctx.
+func (s *Searcher) interruptWhenDone(ctx context.Context) func() bool {
+	return context.AfterFunc(ctx, func() { s.interruptIfDone(ctx) })
+}
+
+// interruptIfDone records the interruption of a search whose ctx is done, as
+// a timeout if its deadline passed and as a cancellation otherwise
+func (s *Searcher) interruptIfDone(ctx context.Context) {
+	if ctx.Err() == nil {
+		return
+	}
+	reason := InterruptedByCancel
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = InterruptedByTimeout
+	}
+	s.debug(s.colors.Blue, "The search", reason, s.colors.Restore)
+	s.stats.Interrupt(reason)
+}
+
+// errInterrupted ends the reads of a search that has been interrupted
+var errInterrupted = errors.New("search interrupted")
+
+// interruptibleReader fails its reads once the search is interrupted, so that
+// a file with very long lines or a large compressed stream is abandoned
+// promptly rather than only at its next line
+type interruptibleReader struct {
+	r     io.Reader
+	stats *Statistics
+}
+
+func (ir interruptibleReader) Read(p []byte) (int, error) {
+	if ir.stats.Interrupted() {
+		return 0, errInterrupted
+	}
+	return ir.r.Read(p)
+}
+
+// abandonableReader reads from a stream that may block indefinitely, like a
+// pipe that nothing is writing to, on a goroutine of its own so that a
+// blocked read is given up as soon as ctx is done.  The abandoned read
+// finishes in the background and its data is dropped.
+type abandonableReader struct {
+	ctx context.Context
+	r   io.Reader
+	buf []byte
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+func (ar *abandonableReader) Read(p []byte) (int, error) {
+	if ar.ctx.Err() != nil {
+		return 0, errInterrupted
+	}
+	// The read goes through buf since p may be reused once it is abandoned
+	if len(ar.buf) < len(p) {
+		ar.buf = make([]byte, len(p))
+	}
+	buf := ar.buf[:len(p)]
+	done := make(chan readResult, 1)
+	go func() {
+		n, err := ar.r.Read(buf)
+		done <- readResult{n, err}
+	}()
+	select {
+	case result := <-done:
+		return copy(p, buf[:result.n]), result.err
+	case <-ar.ctx.Done():
+		return 0, errInterrupted
+	}
+}
+
+// abandonableReadCloser is an abandonableReader that closes the stream it
+// reads from
+type abandonableReadCloser struct {
+	*abandonableReader
+	io.Closer
+}
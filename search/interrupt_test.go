@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Statistics.Interrupt
+// ---------------------------------------------------------------------------
+
+func TestInterruptKeepsFirstReason(t *testing.T) {
+	s := NewStatistics()
+	if s.Interrupted() {
+		t.Fatal("did not expect a new search to be interrupted")
+	}
+	s.Interrupt(InterruptedByTimeout)
+	s.Interrupt(InterruptedByCancel)
+	if s.Interruption() != InterruptedByTimeout {
+		t.Errorf("expected the timeout to be kept, got %v", s.Interruption())
+	}
+}
+
+func TestProcessFileStopsWalkWhenInterrupted(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+	searcher.stats.Interrupt(InterruptedByCancel)
+
+	searcher.walkRoots([]string{tmpDir})
+	if len(searcher.filesToScan) != 0 {
+		t.Errorf("did not expect files to be queued after an interrupt, got %v", searcher.filesToScan)
+	}
+}
+
+// endlessLine is a reader of a single line that never ends.  It interrupts
+// stats once it has been read from a few times.
+type endlessLine struct {
+	stats *Statistics
+	reads int
+}
+
+func (e *endlessLine) Read(p []byte) (int, error) {
+	e.reads++
+	if e.reads == 3 {
+		e.stats.Interrupt(InterruptedByCancel)
+	}
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func TestScanStopsWithinLongLineWhenInterrupted(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.MatchRegex = mustGetMatchRegex(t, false, false, "TODO")
+	searcher.settings.NoMaxLineLength = true
+	reader := &endlessLine{stats: searcher.stats}
+
+	done := make(chan []Match)
+	go func() { done <- searcher.scanForMatches("endless", reader, nil) }()
+	select {
+	case matches := <-done:
+		if len(matches) != 0 || searcher.stats.ErroredFilesCount() != 0 {
+			t.Errorf("expected an interrupted scan without matches or errors, got %v and %d errors", matches, searcher.stats.ErroredFilesCount())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scan of the line did not stop after the interrupt")
+	}
+}
+
+func TestSearchFinishesWhenAbandoned(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 0; i < 500; i++ {
+		fsys[fmt.Sprintf("f%03d.txt", i)] = &fstest.MapFile{Data: []byte("TODO\nTODO\n")}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := Search(ctx, Options{Pattern: "TODO", FS: fsys})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+
+	// Give up without receiving any of the matches
+	cancel()
+	select {
+	case <-results.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the search is still blocked after its caller gave up")
+	}
+	if stats := results.Wait(); stats.Interruption() != InterruptedByCancel {
+		t.Errorf("expected the search to be cancelled, got %v", stats.Interruption())
+	}
+}
+
+// blockedPipe returns head, then blocks like a pipe that nothing writes to
+// until unblock is closed
+type blockedPipe struct {
+	head    io.Reader
+	unblock chan struct{}
+}
+
+func (b *blockedPipe) Read(p []byte) (int, error) {
+	if n, err := b.head.Read(p); err != io.EOF {
+		return n, err
+	}
+	<-b.unblock
+	return 0, io.EOF
+}
+
+func TestSearchTimeoutAbandonsBlockedInput(t *testing.T) {
+	pipe := &blockedPipe{head: strings.NewReader("TODO first\n"), unblock: make(chan struct{})}
+	defer close(pipe.unblock)
+	settings := NewSettings()
+	settings.Timeout = 50 * time.Millisecond
+
+	results, err := Search(context.Background(), Options{Pattern: "TODO", Input: pipe, Settings: settings})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	var matches []Match
+	done := make(chan *Statistics)
+	go func() {
+		for m := range results.Matches() {
+			matches = append(matches, m)
+		}
+		done <- results.Wait()
+	}()
+	select {
+	case stats := <-done:
+		if stats.Interruption() != InterruptedByTimeout {
+			t.Errorf("expected the search to time out, got %v", stats.Interruption())
+		}
+		if len(matches) != 1 {
+			t.Errorf("expected the match read before the timeout, got %v", matches)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the search is still blocked reading its input after the timeout")
+	}
+}
+
+func TestStopAbandonsBlockedFileList(t *testing.T) {
+	pipe := &blockedPipe{head: strings.NewReader(""), unblock: make(chan struct{})}
+	defer close(pipe.unblock)
+
+	results, err := Search(context.Background(), Options{Pattern: "TODO", FileList: io.NopCloser(pipe), FS: fstest.MapFS{}})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	results.Stop()
+	done := make(chan *Statistics)
+	go func() { done <- results.Wait() }()
+	select {
+	case stats := <-done:
+		if stats.Interruption() != InterruptedByCancel {
+			t.Errorf("expected the search to be stopped, got %v", stats.Interruption())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the search is still blocked reading its file list after Stop")
+	}
+}
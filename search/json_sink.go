@@ -0,0 +1,101 @@
+package search
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONSink writes the events of a search as JSON Lines, one object per
+// event, with a "type" of begin, match, context, end or summary, or file for
+// a listing from ListFiles
+type JSONSink struct {
+	enc *json.Encoder
+}
+
+func NewJSONSink(out io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(out)}
+}
+
+// jsonEvent is a single line of JSONSink output.  Only the fields that
+// belong to the event's type are set.
+type jsonEvent struct {
+	Type       string  `json:"type"`
+	Path       string  `json:"path,omitempty"`
+	LineNumber int     `json:"line_number,omitempty"`
+	Text       *string `json:"text,omitempty"`
+	MatchStart *int    `json:"match_start,omitempty"`
+	MatchEnd   *int    `json:"match_end,omitempty"`
+	Binary     bool    `json:"binary,omitempty"`
+	Matches    *int    `json:"matches,omitempty"`
+	Size       *int64  `json:"size,omitempty"`
+	Modified   string  `json:"modified,omitempty"`
+
+	*jsonSummary
+}
+
+type jsonSummary struct {
+	ElapsedMs    int64 `json:"elapsed_ms"`
+	FilesScanned int   `json:"files_scanned"`
+	FilesMatched int   `json:"files_matched"`
+	LinesScanned int   `json:"lines_scanned"`
+	TotalMatches int   `json:"total_matches"`
+	Truncated    bool  `json:"truncated"`
+	TimedOut     bool  `json:"timed_out"`
+	Interrupted  bool  `json:"interrupted"`
+}
+
+func (j *JSONSink) BeginFile(path string) {
+	j.enc.Encode(jsonEvent{Type: "begin", Path: path})
+}
+
+func (j *JSONSink) Match(m Match) {
+	event := jsonEvent{Type: "match", Path: m.Path, Binary: m.Binary}
+	if !m.Binary {
+		event.LineNumber = m.LineNumber
+		event.Text = jsonText(m.Line)
+		event.MatchStart, event.MatchEnd = &m.Match[0], &m.Match[1]
+	}
+	j.enc.Encode(event)
+}
+
+func (j *JSONSink) Context(m Match) {
+	j.enc.Encode(jsonEvent{Type: "context", Path: m.Path, LineNumber: m.LineNumber, Text: jsonText(m.Line)})
+}
+
+// jsonText returns line as a string for an event.  encoding/json replaces
+// any invalid UTF-8 in it.
+func jsonText(line []byte) *string {
+	text := string(line)
+	return &text
+}
+
+func (j *JSONSink) EndFile(path string, matches int) {
+	j.enc.Encode(jsonEvent{Type: "end", Path: path, Matches: &matches})
+}
+
+// File writes one of the files of a listing from ListFiles, with its size
+// and modification time
+func (j *JSONSink) File(f FileToScan) {
+	event := jsonEvent{Type: "file", Path: f.Path}
+	if f.Info != nil {
+		size := f.Info.Size()
+		event.Size = &size
+		event.Modified = f.Info.ModTime().UTC().Format(time.RFC3339)
+	}
+	j.enc.Encode(event)
+}
+
+func (j *JSONSink) Summary(stats *Statistics) {
+	summary := &jsonSummary{
+		ElapsedMs:    stats.ElapsedTime().Milliseconds(),
+		FilesScanned: stats.FileCount(),
+		FilesMatched: stats.FilesMatchedCount(),
+		LinesScanned: stats.LineCount(),
+		TotalMatches: stats.MatchCount(),
+		Truncated:    stats.Truncated() || stats.Interrupted(),
+		TimedOut:     stats.Interruption() == InterruptedByTimeout,
+		Interrupted:  stats.Interrupted(),
+	}
+	j.enc.Encode(jsonEvent{Type: "summary", jsonSummary: summary})
+}
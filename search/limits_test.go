@@ -0,0 +1,30 @@
+package search
+
+import "testing"
+
+// ---------------------------------------------------------------------------
+// Statistics.reserveResult
+// ---------------------------------------------------------------------------
+
+func TestReserveResult(t *testing.T) {
+	s := NewStatistics()
+	for i := 0; i < 2; i++ {
+		if !s.reserveResult(2) {
+			t.Fatalf("expected result %d to be reserved", i+1)
+		}
+	}
+	if s.Truncated() || !s.ResultLimitReached(2) {
+		t.Error("expected the limit to be reached without truncating")
+	}
+	if s.reserveResult(2) || !s.Truncated() {
+		t.Error("expected a result past the limit to be refused and truncate the results")
+	}
+
+	unlimited := NewStatistics()
+	for i := 0; i < 100; i++ {
+		unlimited.reserveResult(0)
+	}
+	if unlimited.Truncated() || unlimited.ResultLimitReached(0) {
+		t.Error("expected a limit of 0 to mean no limit")
+	}
+}
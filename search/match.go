@@ -0,0 +1,17 @@
+package search
+
+// Match is a line that matched.  Match holds the start and end of the first
+// match within Line.  In a binary file that isn't searched as text only the
+// Path is set, with Binary, since the line isn't worth showing.
+type Match struct {
+	Path       string
+	LineNumber int
+	Line       []byte
+	Match      []int
+	MaxLength  int
+	Binary     bool
+}
+
+func (m *Match) hasMatch() bool {
+	return m.LineNumber != 0 && len(m.Line) > 0 && len(m.Match) > 0
+}
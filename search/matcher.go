@@ -0,0 +1,310 @@
+package search
+
+import (
+	"bytes"
+	"regexp"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxMatcherLiterals is the most alternatives a pattern may expand to and
+// still be searched for as a set of literals rather than with the regex
+const maxMatcherLiterals = 16
+
+// Matcher finds what a search is looking for within a line.  NewMatcher picks
+// the fastest Matcher that finds the same matches as a regex.  Other matching
+// strategies can be plugged in through Settings.Matcher, which takes the place
+// of Settings.MatchRegex when set.
+type Matcher interface {
+	// Find returns the start and end of the first match in line, or nil if
+	// there is none
+	Find(line []byte) []int
+	// FindAll returns the start and end of every match in line, in order and
+	// without overlaps, or nil if there are none
+	FindAll(line []byte) [][]int
+}
+
+// NewMatcher returns a Matcher that finds the same matches as re.  Patterns
+// that are a plain string, a case-insensitive ASCII string, or a handful of
+// such strings separated by | are searched for without the regex engine.
+func NewMatcher(re *regexp.Regexp) Matcher {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return regexMatcher{re}
+	}
+	literals, fold, ok := expandLiterals(parsed)
+	if !ok {
+		return regexMatcher{re}
+	}
+	switch {
+	case len(literals) > 1:
+		return &multiLiteralMatcher{literals: literals, fold: fold}
+	case fold:
+		return foldedLiteralMatcher{literals[0]}
+	default:
+		return literalMatcher{literals[0]}
+	}
+}
+
+// regexMatcher matches an RE2 regex, and is used for any pattern the other
+// matchers can't handle
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) Find(line []byte) []int {
+	return m.re.FindIndex(line)
+}
+
+func (m regexMatcher) FindAll(line []byte) [][]int {
+	return m.re.FindAllIndex(line, -1)
+}
+
+// literalMatcher matches a string exactly
+type literalMatcher struct {
+	literal []byte
+}
+
+func (m literalMatcher) Find(line []byte) []int {
+	if i := bytes.Index(line, m.literal); i >= 0 {
+		return []int{i, i + len(m.literal)}
+	}
+	return nil
+}
+
+func (m literalMatcher) FindAll(line []byte) [][]int {
+	return findAll(line, m.Find)
+}
+
+// foldedLiteralMatcher matches an ASCII string regardless of case.  The
+// literal is held in lower case.
+type foldedLiteralMatcher struct {
+	literal []byte
+}
+
+func (m foldedLiteralMatcher) Find(line []byte) []int {
+	if i := indexFold(line, m.literal); i >= 0 {
+		return []int{i, i + len(m.literal)}
+	}
+	return nil
+}
+
+func (m foldedLiteralMatcher) FindAll(line []byte) [][]int {
+	return findAll(line, m.Find)
+}
+
+// multiLiteralMatcher matches any of a set of strings.  Like the regex
+// alternation it stands in for, the leftmost match wins, and of the matches
+// starting there the earliest alternative.
+type multiLiteralMatcher struct {
+	literals [][]byte
+	fold     bool
+}
+
+func (m *multiLiteralMatcher) Find(line []byte) []int {
+	var found []int
+	for _, literal := range m.literals {
+		// Only the part of the line before the best match so far can hold a
+		// better one
+		limit := len(line)
+		if found != nil {
+			limit = found[0] + len(literal) - 1
+			if limit > len(line) {
+				limit = len(line)
+			}
+		}
+		var i int
+		if m.fold {
+			i = indexFold(line[:limit], literal)
+		} else {
+			i = bytes.Index(line[:limit], literal)
+		}
+		if i >= 0 && (found == nil || i < found[0]) {
+			found = []int{i, i + len(literal)}
+		}
+	}
+	return found
+}
+
+func (m *multiLiteralMatcher) FindAll(line []byte) [][]int {
+	return findAll(line, m.Find)
+}
+
+// findAll collects the matches of find in line, resuming the search at the
+// end of each match.  The matches must not be empty.
+func findAll(line []byte, find func([]byte) []int) [][]int {
+	var all [][]int
+	for offset := 0; offset < len(line); {
+		loc := find(line[offset:])
+		if loc == nil {
+			break
+		}
+		all = append(all, []int{offset + loc[0], offset + loc[1]})
+		offset += loc[1]
+	}
+	return all
+}
+
+// indexFold is bytes.Index comparing ASCII letters regardless of case.
+// literal must be in lower case.
+func indexFold(s []byte, literal []byte) int {
+	if len(literal) == 0 {
+		return 0
+	}
+	first, firstUpper := literal[0], toUpperASCII(literal[0])
+	for i := 0; i+len(literal) <= len(s); i++ {
+		if c := s[i]; c != first && c != firstUpper {
+			continue
+		}
+		if equalFoldASCII(s[i:i+len(literal)], literal) {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalFoldASCII reports whether s equals the lower case literal, ignoring
+// the case of ASCII letters
+func equalFoldASCII(s []byte, literal []byte) bool {
+	for i, c := range s {
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != literal[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func toUpperASCII(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+	return c
+}
+
+// expandLiterals lists the strings a parsed regex matches, in the order the
+// regex prefers them, if it only matches a few non-empty strings.  fold is
+// true if they are to be compared regardless of case, which is only offered
+// for ASCII since other letters can fold to strings of a different length.
+func expandLiterals(re *syntax.Regexp) (literals [][]byte, fold bool, ok bool) {
+	// exact is set once part of the regex needs an exact comparison of
+	// letters, folded once part needs a case-insensitive one
+	var exact, folded bool
+	var expand func(re *syntax.Regexp) ([]string, bool)
+	expand = func(re *syntax.Regexp) ([]string, bool) {
+		switch re.Op {
+		case syntax.OpLiteral:
+			caseless := re.Flags&syntax.FoldCase != 0
+			for _, r := range re.Rune {
+				if r == utf8.RuneError || (caseless && !foldsWithinASCII(r)) {
+					return nil, false
+				}
+				if unicode.SimpleFold(r) != r {
+					exact = exact || !caseless
+					folded = folded || caseless
+				}
+			}
+			return []string{string(re.Rune)}, true
+		case syntax.OpCharClass:
+			return expandCharClass(re, &exact, &folded)
+		case syntax.OpConcat:
+			product := []string{""}
+			for _, sub := range re.Sub {
+				options, ok := expand(sub)
+				if !ok || len(product)*len(options) > maxMatcherLiterals {
+					return nil, false
+				}
+				next := make([]string, 0, len(product)*len(options))
+				for _, prefix := range product {
+					for _, option := range options {
+						next = append(next, prefix+option)
+					}
+				}
+				product = next
+			}
+			return product, true
+		case syntax.OpAlternate:
+			var all []string
+			for _, sub := range re.Sub {
+				options, ok := expand(sub)
+				if !ok || len(all)+len(options) > maxMatcherLiterals {
+					return nil, false
+				}
+				all = append(all, options...)
+			}
+			return all, true
+		case syntax.OpEmptyMatch:
+			return []string{""}, true
+		case syntax.OpCapture:
+			return expand(re.Sub[0])
+		default:
+			return nil, false
+		}
+	}
+
+	options, ok := expand(re)
+	if !ok || (exact && folded) {
+		return nil, false, false
+	}
+	literals = make([][]byte, 0, len(options))
+	for _, option := range options {
+		if option == "" {
+			// Empty matches are left to the regex engine
+			return nil, false, false
+		}
+		if folded {
+			option = string(bytes.ToLower([]byte(option)))
+		}
+		literals = append(literals, []byte(option))
+	}
+	return literals, folded, true
+}
+
+// expandCharClass lists the characters of a small character class.  Under
+// (?i) the class holds both cases of each letter, and only one is kept.
+func expandCharClass(re *syntax.Regexp, exact *bool, folded *bool) ([]string, bool) {
+	caseless := re.Flags&syntax.FoldCase != 0
+	var options []string
+	seen := map[rune]bool{}
+	for i := 0; i+1 < len(re.Rune); i += 2 {
+		lo, hi := re.Rune[i], re.Rune[i+1]
+		if int(hi-lo)+len(options) >= maxMatcherLiterals {
+			return nil, false
+		}
+		for r := lo; r <= hi; r++ {
+			if r == utf8.RuneError || (caseless && !foldsWithinASCII(r)) {
+				return nil, false
+			}
+			if unicode.SimpleFold(r) != r {
+				*exact = *exact || !caseless
+				*folded = *folded || caseless
+			}
+			if caseless {
+				r = unicode.ToLower(r)
+			}
+			if !seen[r] {
+				seen[r] = true
+				options = append(options, string(r))
+			}
+		}
+	}
+	return options, len(options) > 0
+}
+
+// foldsWithinASCII reports whether r and everything it is equal to regardless
+// of case are ASCII.  'k' and 's' aren't, as they also match the Kelvin sign
+// and the long s.
+func foldsWithinASCII(r rune) bool {
+	if r >= utf8.RuneSelf {
+		return false
+	}
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
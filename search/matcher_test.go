@@ -0,0 +1,134 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewMatcherChoosesEngine(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    string
+	}{
+		{"TODO", "search.literalMatcher"},
+		{`a\.b`, "search.literalMatcher"},
+		{"(?i)todo", "search.foldedLiteralMatcher"},
+		{"(?i)fixme 123", "search.foldedLiteralMatcher"},
+		{"(?i)123", "search.literalMatcher"},
+		{"TODO|FIXME|XXX", "*search.multiLiteralMatcher"},
+		{"(?i)todo|fixme", "*search.multiLiteralMatcher"},
+		{"foo|foobar", "*search.multiLiteralMatcher"},
+		{"ba[rz]", "*search.multiLiteralMatcher"},
+		{"(TODO)", "search.literalMatcher"},
+		{"café", "search.literalMatcher"},
+		{"(?i)café", "search.regexMatcher"},
+		{"(?i)task", "search.regexMatcher"},
+		{"(?i)kind", "search.regexMatcher"},
+		{"x(?i)y", "search.regexMatcher"},
+		{"TODO|", "search.regexMatcher"},
+		{"^TODO", "search.regexMatcher"},
+		{`TODO\b`, "search.regexMatcher"},
+		{"TO+DO", "search.regexMatcher"},
+		{"[a-z]+", "search.regexMatcher"},
+		{"[^a]", "search.regexMatcher"},
+		{"a|b|c|d|e|f|g|h|i|j|k|l|m|n|o|p|q", "search.regexMatcher"},
+	}
+	for _, tc := range cases {
+		got := fmt.Sprintf("%T", NewMatcher(regexp.MustCompile(tc.pattern)))
+		if got != tc.want {
+			t.Errorf("NewMatcher(%q) = %s, want %s", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+func TestMatchersAgreeWithRegex(t *testing.T) {
+	patterns := []string{
+		"TODO", "(?i)todo", "TODO|FIXME", "(?i)todo|fixme", "foo|foobar", "foobar|foo",
+		"(a|ab)(c|bcd)", "ba[rz]", "(?i)ba[rz]", "é", "ab|b", "aa",
+	}
+	lines := []string{
+		"", "TODO", "todo and ToDo and TODO", "// FIXME: TODO later", "no match here",
+		"foobar foo fo", "abcd abc ac", "bar baz BAR BaZ", "café é", "aaaaa", "abab",
+		"\xffTODO\xfe", "TOD", "xfixmex",
+	}
+	for _, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		matcher := NewMatcher(re)
+		for _, line := range lines {
+			if got, want := matcher.Find([]byte(line)), re.FindIndex([]byte(line)); !reflect.DeepEqual(got, want) {
+				t.Errorf("%T for %q: Find(%q) = %v, want %v", matcher, pattern, line, got, want)
+			}
+			if got, want := matcher.FindAll([]byte(line)), re.FindAllIndex([]byte(line), -1); !reflect.DeepEqual(got, want) {
+				t.Errorf("%T for %q: FindAll(%q) = %v, want %v", matcher, pattern, line, got, want)
+			}
+		}
+	}
+}
+
+// upperMatcher is a Matcher that finds runs of capital letters, standing in
+// for a strategy that isn't a regex
+type upperMatcher struct{}
+
+func (upperMatcher) Find(line []byte) []int {
+	for i, c := range line {
+		if 'A' <= c && c <= 'Z' {
+			end := i
+			for end < len(line) && 'A' <= line[end] && line[end] <= 'Z' {
+				end++
+			}
+			return []int{i, end}
+		}
+	}
+	return nil
+}
+
+func (m upperMatcher) FindAll(line []byte) [][]int {
+	return findAll(line, m.Find)
+}
+
+func TestSettingsMatcher(t *testing.T) {
+	resetTestState(t)
+	searcher.settings.Matcher = upperMatcher{}
+	searcher.settings.MatchRegex = regexp.MustCompile("ignored")
+	searcher.fsys = fstest.MapFS{"notes.txt": {Data: []byte("lower case\nsome CAPS here\n")}}
+
+	matches := searcher.checkForMatches("notes.txt")
+	if len(matches) != 1 || matches[0].LineNumber != 2 || !reflect.DeepEqual(matches[0].Match, []int{5, 9}) {
+		t.Errorf("expected the custom matcher to find CAPS on line 2, got %+v", matches)
+	}
+}
+
+func TestSearchPatternOverridesSettingsMatcher(t *testing.T) {
+	fsys := fstest.MapFS{"notes.txt": {Data: []byte("lower case\nsome CAPS here\n")}}
+	settings := NewSettings()
+	settings.Matcher = upperMatcher{}
+	matchedLines := func(opts Options) []int {
+		t.Helper()
+		results, err := Search(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("starting the search: %v", err)
+		}
+		var lines []int
+		for m := range results.Matches() {
+			lines = append(lines, m.LineNumber)
+		}
+		results.Wait()
+		return lines
+	}
+
+	if got := matchedLines(Options{Pattern: "lower", FS: fsys, Settings: settings}); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("expected Pattern to be searched for instead of the settings' Matcher, got lines %v", got)
+	}
+	if settings.Matcher == nil {
+		t.Error("expected the caller's settings to keep their Matcher")
+	}
+
+	// Without a Pattern the Matcher is used as is
+	if got := matchedLines(Options{FS: fsys, Settings: settings}); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("expected the Matcher to find CAPS on line 2, got lines %v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDepthUnlimited is the --max-depth value that disables the depth limit
+const MaxDepthUnlimited = -1
+
+var sizeSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// dateLayouts are the absolute times accepted by --newer-than and --older-than
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseSize converts sizes like "512", "10K", "1.5M" or "2GiB" to bytes.
+// Suffixes are powers of 1024 and case-insensitive.
+func ParseSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	if trimmed == "" {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	// "10KB", "10KiB" and "10K" are all the same
+	number := strings.TrimSuffix(trimmed, "IB")
+	if number == trimmed {
+		number = strings.TrimSuffix(trimmed, "B")
+	}
+
+	multiplier := int64(1)
+	for _, s := range sizeSuffixes {
+		if strings.HasSuffix(number, s.suffix) {
+			number = strings.TrimSuffix(number, s.suffix)
+			multiplier = s.multiplier
+			break
+		}
+	}
+
+	parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid size %q (use bytes or a K, M, G or T suffix)", value)
+	}
+	return int64(parsed * float64(multiplier)), nil
+}
+
+// ParseTimeBound converts a duration ago ("36h", "7d", "2w") or a date
+// ("2024-05-01", "2024-05-01 13:00", RFC 3339) into an absolute time.  Dates
+// without a time zone are in local time.
+func ParseTimeBound(value string, now time.Time) (time.Time, error) {
+	trimmed := strings.TrimSpace(value)
+	if d, ok := parseLongDuration(trimmed); ok {
+		return now.Add(-d), nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, trimmed, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a duration like 36h, 7d or 2w, or a date like 2024-05-01)", value)
+}
+
+// parseLongDuration extends time.ParseDuration with d (days) and w (weeks)
+func parseLongDuration(value string) (time.Duration, bool) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, found := strings.CutSuffix(value, suffix); found {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			return time.Duration(n * float64(unit)), true
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// passesMetadata applies the size and modification time filters to a file
+// about to be queued.  Archives are let through like they are for the include
+// filters, since the limits apply to their members.
+func (s *Searcher) passesMetadata(path string, info os.FileInfo) bool {
+	if s.settings.SearchArchives && hasArchiveExtension(path) {
+		return true
+	}
+	if !s.settings.PassesMetadataFilter(info) {
+		s.debug(s.colors.Blue, "File", path, "is outside the size or modification time limits and will be skipped", s.colors.Restore)
+		return false
+	}
+	return true
+}
+
+// walkDepth returns how many levels below root path is, where the files
+// directly inside root are at depth 1
+func walkDepth(root string, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// depthLimitedWalkFunc wraps processFile for a walk of root, pruning
+// directories whose contents would be deeper than --max-depth
+func (s *Searcher) depthLimitedWalkFunc(root string) filepath.WalkFunc {
+	return func(path string, info os.FileInfo, err error) error {
+		if err == nil && s.settings.MaxDepth != MaxDepthUnlimited {
+			depth := walkDepth(root, path)
+			if depth > s.settings.MaxDepth {
+				s.debug(s.colors.Blue, "Path", path, "is deeper than --max-depth and will be skipped", s.colors.Restore)
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return fileProcessingComplete
+			}
+			if info.IsDir() && depth == s.settings.MaxDepth && depth > 0 {
+				s.debug(s.colors.Blue, "Directory", path, "is at --max-depth and will be pruned", s.colors.Restore)
+				return filepath.SkipDir
+			}
+		}
+		return s.processFile(root, path, info, err)
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// ParseSize / ParseTimeBound
+// ---------------------------------------------------------------------------
+
+func TestParseSize(t *testing.T) {
+	for value, want := range map[string]int64{
+		"0":      0,
+		"512":    512,
+		"100B":   100,
+		"10k":    10 * 1024,
+		"10KB":   10 * 1024,
+		"10KiB":  10 * 1024,
+		"1.5M":   3 * 512 * 1024,
+		"2G":     2 << 30,
+		" 1t ":   1 << 40,
+		"0.5mib": 512 * 1024,
+	} {
+		got, err := ParseSize(value)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "K", "ten", "-5", "10X", "10 MB extra"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Errorf("expected an error for size %q", value)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	cases := map[string]time.Time{
+		"36h":                  now.Add(-36 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+		"7d":                   now.AddDate(0, 0, -7),
+		"2w":                   now.AddDate(0, 0, -14),
+		"1.5d":                 now.Add(-36 * time.Hour),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+		"2024-05-01 13:30":     time.Date(2024, 5, 1, 13, 30, 0, 0, time.Local),
+		"2024-05-01T13:30:15Z": time.Date(2024, 5, 1, 13, 30, 15, 0, time.UTC),
+	}
+	for value, want := range cases {
+		got, err := ParseTimeBound(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "yesterday", "-3d", "2024-13-01", "7 days"} {
+		if _, err := ParseTimeBound(value, now); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Settings.PassesMetadataFilter / walkDepth
+// ---------------------------------------------------------------------------
+
+func TestPassesMetadataFilter(t *testing.T) {
+	resetTestState(t)
+	now := time.Now()
+	small := stubFileInfo{size: 100, modTime: now.Add(-48 * time.Hour)}
+	large := stubFileInfo{size: 10 << 20, modTime: now.Add(-time.Hour)}
+
+	if !searcher.settings.PassesMetadataFilter(small) || !searcher.settings.PassesMetadataFilter(large) {
+		t.Fatal("expected every file to pass without limits")
+	}
+
+	searcher.settings.MaxFilesize = 1 << 20
+	if !searcher.settings.PassesMetadataFilter(small) || searcher.settings.PassesMetadataFilter(large) {
+		t.Error("expected only the small file to pass --max-filesize")
+	}
+	searcher.settings.MaxFilesize = 0
+
+	searcher.settings.MinFilesize = 1024
+	if searcher.settings.PassesMetadataFilter(small) || !searcher.settings.PassesMetadataFilter(large) {
+		t.Error("expected only the large file to pass --min-filesize")
+	}
+	searcher.settings.MinFilesize = 0
+
+	searcher.settings.NewerThan = now.Add(-24 * time.Hour)
+	if searcher.settings.PassesMetadataFilter(small) || !searcher.settings.PassesMetadataFilter(large) {
+		t.Error("expected only the recent file to pass --newer-than")
+	}
+	searcher.settings.NewerThan = time.Time{}
+
+	searcher.settings.OlderThan = now.Add(-24 * time.Hour)
+	if !searcher.settings.PassesMetadataFilter(small) || searcher.settings.PassesMetadataFilter(large) {
+		t.Error("expected only the old file to pass --older-than")
+	}
+
+	if !searcher.settings.PassesMetadataFilter(nil) {
+		t.Error("expected files without metadata to pass")
+	}
+}
+
+func TestWalkDepth(t *testing.T) {
+	sep := string(filepath.Separator)
+	cases := []struct {
+		root string
+		path string
+		want int
+	}{
+		{".", ".", 0},
+		{".", "a.go", 1},
+		{".", "src" + sep + "a.go", 2},
+		{"src", "src" + sep + "pkg" + sep + "a.go", 2},
+		{"file.go", "file.go", 0},
+	}
+	for _, tc := range cases {
+		if got := walkDepth(tc.root, tc.path); got != tc.want {
+			t.Errorf("walkDepth(%q, %q) = %d, want %d", tc.root, tc.path, got, tc.want)
+		}
+	}
+}
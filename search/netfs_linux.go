@@ -0,0 +1,129 @@
+//go:build linux
+
+package search
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// networkFilesystems are the statfs(2) magic numbers of filesystems whose
+// reads go over the network
+var networkFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x00c36400: "ceph",
+	0x5346414f: "afs",
+	0x01021997: "9p",
+}
+
+// fuseMagic is the statfs(2) magic number of every FUSE filesystem, most of
+// which are local, so the subtype in the mount table decides
+const fuseMagic = 0x65735546
+
+// networkFuseSubtypes are the FUSE filesystems whose reads go over the network
+var networkFuseSubtypes = map[string]bool{
+	"sshfs":     true,
+	"rclone":    true,
+	"s3fs":      true,
+	"gcsfuse":   true,
+	"goofys":    true,
+	"curlftpfs": true,
+	"glusterfs": true,
+	"ceph-fuse": true,
+	"blobfuse2": true,
+	"juicefs":   true,
+}
+
+// isNetworkFilesystem reports whether path is on NFS, SMB, a network FUSE
+// filesystem like sshfs or another filesystem that is likely to be remote
+func isNetworkFilesystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+	if uint32(stat.Type) == fuseMagic {
+		return isNetworkFuse(path)
+	}
+	_, remote := networkFilesystems[uint32(stat.Type)]
+	return remote
+}
+
+// isNetworkFuse reports whether the FUSE filesystem path is on is a network
+// one, going by the type the mount table gives it, e.g. "fuse.sshfs"
+func isNetworkFuse(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	mountinfo, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return false
+	}
+	defer mountinfo.Close()
+	return networkFuseSubtypes[fuseSubtype(mountinfo, abs)]
+}
+
+// fuseSubtype returns the subtype of the FUSE mount that the absolute path abs
+// is on, going by mountinfo in the format of /proc/self/mountinfo.  A mount
+// whose type has no "fuse." prefix gives its type as is.
+func fuseSubtype(mountinfo io.Reader, abs string) string {
+	var mountPoint, fsType string
+	scanner := bufio.NewScanner(mountinfo)
+	for scanner.Scan() {
+		// The optional fields end at a "-", which is followed by the type
+		fields := strings.Fields(scanner.Text())
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if len(fields) < 5 || separator < 0 || separator+1 >= len(fields) {
+			continue
+		}
+		point := unescapeMountPoint(fields[4])
+		if (abs != point && !isWithin(abs, point)) || len(point) < len(mountPoint) {
+			continue
+		}
+		// Later entries mounted over the same point hide earlier ones
+		mountPoint, fsType = point, fields[separator+1]
+	}
+
+	if subtype, ok := strings.CutPrefix(fsType, "fuse."); ok {
+		return subtype
+	}
+	if fsType == "fuseblk" || fsType == "fuse" {
+		return ""
+	}
+	// Some FUSE filesystems give their name as the type, e.g. "sshfs"
+	return fsType
+}
+
+// unescapeMountPoint undoes the octal escapes, like \040 for a space, that
+// the mount table uses for whitespace and backslashes in paths
+func unescapeMountPoint(point string) string {
+	var b strings.Builder
+	for i := 0; i < len(point); i++ {
+		if point[i] == '\\' && i+4 <= len(point) {
+			if value, err := strconv.ParseUint(point[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(point[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuseSubtype(t *testing.T) {
+	mountinfo := strings.Join([]string{
+		`22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw`,
+		`40 22 0:35 / /home/me/remote rw,nosuid shared:20 - fuse.sshfs me@host:/srv rw,user_id=1000`,
+		`41 22 0:36 / /home/me/my\040vault rw,nosuid - fuse.gocryptfs /home/me/.vault rw`,
+		`42 22 0:37 / /mnt/usb rw,relatime - fuseblk /dev/sdb1 rw`,
+		`43 40 0:38 / /home/me/remote/local rw shared:21 master:3 - fuse.bindfs /srv rw`,
+	}, "\n")
+	for path, want := range map[string]string{
+		"/home/me/remote":            "sshfs",
+		"/home/me/remote/src/a.go":   "sshfs",
+		"/home/me/remote/local/x":    "bindfs",
+		"/home/me/my vault/notes.md": "gocryptfs",
+		"/mnt/usb/photo.jpg":         "",
+		"/home/me/remotes":           "ext4",
+	} {
+		if got := fuseSubtype(strings.NewReader(mountinfo), path); got != want {
+			t.Errorf("fuseSubtype(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if networkFuseSubtypes["gocryptfs"] || !networkFuseSubtypes["sshfs"] {
+		t.Error("expected only network FUSE filesystems to count as remote")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package search
+
+// isNetworkFilesystem always reports false, since the filesystem type isn't
+// checked on this platform
+func isNetworkFilesystem(path string) bool {
+	return false
+}
@@ -0,0 +1,418 @@
+package search
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+const (
+	scannerDefaultInitialCap = 64 * 1024
+	scannerMinimumInitialCap = 4 * 1024
+	scannerMaxTokenCap       = 256 * 1024 * 1024
+	scannerMaxLineSlack      = 1024
+)
+
+var fileProcessingComplete error = nil
+
+func (s *Searcher) scannerBufferLimits(info os.FileInfo) (int, int) {
+	initialCap := scannerDefaultInitialCap
+	maxToken := scannerDefaultInitialCap
+
+	if info != nil {
+		if size := info.Size(); size > 0 {
+			if size > int64(scannerMaxTokenCap) {
+				maxToken = scannerMaxTokenCap
+			} else {
+				maxToken = int(size)
+			}
+			if int64(initialCap) > size {
+				initialCap = int(size)
+			}
+		}
+	}
+
+	if !s.settings.NoMaxLineLength {
+		desired := s.settings.MaxLineLength + scannerMaxLineSlack
+		if desired < scannerMinimumInitialCap {
+			desired = scannerMinimumInitialCap
+		}
+		if desired > scannerMaxTokenCap {
+			desired = scannerMaxTokenCap
+		}
+		if desired > maxToken {
+			maxToken = desired
+		}
+	} else if maxToken == scannerDefaultInitialCap {
+		maxToken = scannerMaxTokenCap
+	}
+
+	if maxToken < scannerMinimumInitialCap {
+		maxToken = scannerMinimumInitialCap
+	}
+	if maxToken > scannerMaxTokenCap {
+		maxToken = scannerMaxTokenCap
+	}
+
+	if initialCap < scannerMinimumInitialCap {
+		initialCap = scannerMinimumInitialCap
+	}
+	if initialCap > maxToken {
+		initialCap = maxToken
+	}
+
+	return initialCap, maxToken
+}
+
+func containsNullByte(line []byte) bool {
+	for _, el := range line {
+		if el == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Searcher) checkForMatches(path string) []Match {
+	s.debug(s.colors.Blue+"Checking file for matches:"+s.colors.Restore, path)
+	file, err := s.fsys.Open(path)
+	if err != nil {
+		s.debug(s.colors.Red+"Error opening file at '"+path+"'.  It might be a bad symlink.  Err: "+s.colors.Restore, err)
+		s.reportError(err)
+		return []Match{}
+	}
+	defer func() {
+		// if path == "src/main/java/com/canopy/service/EFileService.java" {
+		// 	fmt.Println("Closing the file: " + path)
+		// }
+		file.Close()
+	}()
+
+	fileInfo, statErr := file.Stat()
+	if statErr != nil {
+		s.debug(s.colors.Red+"Unable to stat file '"+path+"' while sizing scanner buffer. Falling back to defaults. Err: "+s.colors.Restore, statErr)
+	}
+
+	var reader io.Reader = file
+	if s.settings.SearchArchives {
+		br := bufio.NewReader(file)
+		if kind := sniffArchive(br); kind != archiveNone {
+			s.stats.incrArchiveCount()
+			if readerAt, ok := file.(io.ReaderAt); ok && kind == archiveZip && fileInfo != nil {
+				return s.scanArchive(path, kind, br, readerAt, fileInfo.Size(), 1)
+			}
+			return s.scanArchive(path, kind, br, nil, 0, 1)
+		}
+		reader = br
+	}
+	if s.settings.SearchZip {
+		decompressed, format, err := decompressingReader(reader)
+		if err != nil {
+			s.debug(s.colors.Red+"Unable to decompress file '"+path+"'. File will be skipped.  Err: "+s.colors.Restore, err)
+			s.stats.incrErroredFilesCount()
+			return []Match{}
+		}
+		if format != compressionNone {
+			s.debug(s.colors.Blue+"Decompressing "+format.String()+" file:"+s.colors.Restore, path)
+			s.stats.incrDecompressedCount()
+			// The on-disk size says nothing about the decompressed line lengths
+			fileInfo = nil
+		}
+		reader = decompressed
+	}
+
+	return s.scanForMatches(path, reader, fileInfo)
+}
+
+// scanForMatches checks each line read from reader against the match regex.
+// path is only used for reporting, and info (which may be nil) is used to
+// size the scanner buffer.
+func (s *Searcher) scanForMatches(path string, reader io.Reader, fileInfo os.FileInfo) []Match {
+	retval := make([]Match, 0, 50)
+
+	buffered := bufio.NewReaderSize(interruptibleReader{reader, s.stats}, encodingSniffSize)
+	head, _ := buffered.Peek(encodingSniffSize)
+	reader, enc := decodingReader(buffered, s.settings.Encoding)
+	binary := s.settings.Binary != BinaryText && looksBinary(head, enc)
+	if binary && s.settings.Binary == BinarySkip {
+		s.debug(s.colors.Blue+"Not processing binary file:"+s.colors.Restore, path)
+		s.stats.incrSkippedNullCount()
+		return retval
+	}
+	if enc != EncodingUTF8 {
+		s.debug(s.colors.Blue+"Transcoding "+enc.String()+" file:"+s.colors.Restore, path)
+		s.stats.incrTranscodedCount()
+		// The on-disk size says nothing about the transcoded line lengths
+		fileInfo = nil
+	}
+
+	// Split function defaults to ScanLines
+	scanner := bufio.NewScanner(reader)
+
+	initialCap, maxToken := s.scannerBufferLimits(fileInfo)
+
+	// Fix for max token size:  https://stackoverflow.com/a/37455465/2062384
+	buf := make([]byte, 0, initialCap)
+	scanner.Buffer(buf, maxToken)
+
+	matcher := s.lineMatcher()
+	var lineNumber int = 0
+	fileMatched := false
+	fileMatchCount := 0
+	fileResults := 0
+	// An interruption fails the next read, so the lines already read are
+	// still matched, like those of a pipe read before a timeout
+	for scanner.Scan() {
+		lineNumber += 1
+		line := scanner.Bytes()
+		s.stats.incrLineCount()
+		matchedBefore := false
+		if !binary && s.settings.Binary != BinaryText && containsNullByte(line) {
+			// The sniffed block looked like text but this is a binary file.
+			// Its earlier lines are binary content too, so their matches are
+			// dropped: a binary file is skipped or reported as a whole.
+			s.debug(s.colors.Blue+"Binary content found part way through file:"+s.colors.Restore, path)
+			s.stats.dropMatches(fileMatchCount, fileResults, fileMatched)
+			retval = retval[:0]
+			if s.settings.Binary == BinarySkip {
+				s.stats.incrSkippedNullCount()
+				return retval
+			}
+			binary = true
+			matchedBefore = fileMatched
+			fileMatched, fileMatchCount, fileResults = false, 0, 0
+		}
+		if matchIndex := matcher.Find(line); matchIndex != nil || matchedBefore {
+			// we have a match! loc == nil means no match so just ignore that case
+			// With --filename-only only the first match of a file is a result
+			if !s.settings.FilenameOnly || !fileMatched {
+				if !s.stats.reserveResult(s.settings.MaxResults) {
+					s.debug(s.colors.Blue+"Reached --max-results, stopping the scan of file:"+s.colors.Restore, path)
+					return retval
+				}
+				fileResults++
+			}
+			s.stats.incrMatchCount()
+			fileMatchCount++
+			if !fileMatched {
+				fileMatched = true
+				s.stats.incrFilesMatchedCount()
+			}
+			if s.settings.Quiet {
+				// The exit status is all that's wanted, so one match is enough
+				return retval
+			}
+			if binary {
+				// Only report that the file matches, like grep does
+				retval = append(retval, Match{Path: path, Binary: true})
+				return retval
+			}
+			if !s.settings.FilenameOnly || fileMatchCount == 1 {
+				// The scanner reuses its buffer, so keep a copy for later consumers
+				line = append([]byte(nil), line...)
+				if !s.settings.FilenameOnly && !s.settings.NoMaxLineLength && (len(line) > s.settings.MaxLineLength) {
+					s.stats.incrSkippedLongCount()
+				}
+				retval = append(retval, Match{Path: path, LineNumber: lineNumber, Line: line, Match: matchIndex, MaxLength: s.settings.MaxLineLength})
+			}
+			if s.settings.MaxCount > 0 && fileMatchCount >= s.settings.MaxCount {
+				s.debug(s.colors.Blue+"Reached --max-count, stopping the scan of file:"+s.colors.Restore, path)
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); errors.Is(err, errInterrupted) {
+		s.debug(s.colors.Blue+"Search interrupted, stopping the scan of file:"+s.colors.Restore, path)
+	} else if err != nil {
+		s.debug(s.colors.Red+"Error scanning line from file '"+path+"'. File will be skipped.  Err: "+s.colors.Restore, err)
+		s.stats.incrErroredFilesCount()
+	}
+	return retval
+}
+
+// processFile handles a path found by the walk of root
+func (s *Searcher) processFile(root string, path string, info os.FileInfo, err error) error {
+	if s.stats.Interrupted() {
+		return filepath.SkipAll
+	}
+	if err != nil {
+		s.debug("filepath.Walk encountered error with path '"+path+"'", err)
+		return fileProcessingComplete
+	}
+
+	if info.IsDir() {
+		// The root itself is named by the caller, so only its contents are
+		// matched against the globs
+		if s.settings.ShouldExcludeDir(path) || (path != root && s.settings.ExcludedByGlob(globPath(root, path))) {
+			s.debug(s.colors.Blue, "Directory", path, "is excluded and will be pruned", s.colors.Restore)
+			return filepath.SkipDir
+		}
+		if s.settings.IsHidden(path) {
+			s.debug(s.colors.Blue, "Directory", path, "is hidden and will be pruned", s.colors.Restore)
+			return filepath.SkipDir // skip the whole sub-contents of this hidden directory
+		} else {
+			return fileProcessingComplete
+		}
+	}
+
+	info, ok := s.resolveSymlink(path, info)
+	if !ok {
+		return fileProcessingComplete
+	}
+
+	if s.passesMetadata(path, info) && s.shouldScanFile(path, globPath(root, path), path == root, nil) {
+		s.stats.incrFilesToScan()
+		defer s.stats.incrFileCount()
+
+		s.filesToScan = append(s.filesToScan, FileToScan{Path: path, Info: info, Err: err})
+	}
+	return fileProcessingComplete
+}
+
+// shouldScanFile applies the exclude, include, filename and hidden filters
+// to a single (non-directory) path.  Globs are matched against rel, the path
+// relative to the search root.  named is set for a file the caller gave as a
+// search root, which like grep is searched even if it's hidden or excluded by
+// default.  interpreter reads the file's #! line for the type filter; if it's
+// nil the line is read from the file at path.
+func (s *Searcher) shouldScanFile(path string, rel string, named bool, interpreter func() string) bool {
+	if s.settings.excludedBy(path, s.settings.UseDefaultExcludes && !named) {
+		s.debug(s.colors.Blue, "File", path, "is excluded and will be skipped", s.colors.Restore)
+		return false
+	}
+
+	if s.settings.ExcludedByGlob(rel) {
+		s.debug(s.colors.Blue, "File", path, "is excluded by a glob and will be skipped", s.colors.Restore)
+		return false
+	}
+
+	hidden := !named && s.settings.IsHidden(path)
+	if s.settings.SearchArchives && hasArchiveExtension(path) && !hidden {
+		// The include and filename filters are applied to the archive's members
+		s.debug(s.colors.Blue+"Queueing archive:", path)
+		return true
+	}
+
+	if !s.settings.ShouldIncludeFile(path) {
+		s.debug(s.colors.Blue, "File", path, "does not match include filter and will be skipped", s.colors.Restore)
+		return false
+	}
+
+	if !s.settings.PassesGlobFilter(rel) {
+		s.debug(s.colors.Blue, "File", path, "does not match any glob and will be skipped", s.colors.Restore)
+		return false
+	}
+
+	if !s.settings.PassesFileFilter(path) {
+		s.debug(s.colors.Blue + "Ignoring file cause it doesn't match filter: " + s.colors.Restore + path)
+		return false
+	}
+
+	s.debug(s.colors.Blue+"Passes file filter:", path)
+	if hidden {
+		s.debug(s.colors.Blue + "Hidden file '" + s.colors.Restore + path + s.colors.Blue + "' not processed")
+		return false
+	}
+
+	// Checked last since it may need to read the file's #! line
+	if interpreter == nil {
+		interpreter = func() string { return shebangInterpreter(s.fsys, path) }
+	}
+	if !s.settings.passesTypeFilterWith(path, interpreter) {
+		s.debug(s.colors.Blue, "File", path, "does not match type filter and will be skipped", s.colors.Restore)
+		return false
+	}
+	return true
+}
+
+// CompileMatchRegex compiles the regex to search for.  Unless ignoreCase or
+// matchCase is set it uses smart case: a regex that is all lower case is
+// case-insensitive, but one with capitals is case-sensitive.
+func CompileMatchRegex(ignoreCase bool, matchCase bool, usersRegex string) (*regexp.Regexp, error) {
+	regexToCompile := usersRegex
+	if ignoreCase || (!matchCase && !regexp.MustCompile("[A-Z]").MatchString(usersRegex)) {
+		regexToCompile = "(?i)" + usersRegex
+	}
+
+	compiled, err := regexp.Compile(regexToCompile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match regex %q: %w", usersRegex, err)
+	}
+
+	return compiled, nil
+}
+
+func (s *Searcher) worker(id int, jobs <-chan string, results chan<- []Match) {
+	for file := range jobs {
+		if s.searchStopped() {
+			// Drain the queue, nothing more will be reported
+			continue
+		}
+		s.debug(s.colors.Blue, "Worker number", id, "started file", s.colors.Restore, file)
+		results <- s.checkForMatches(file)
+		s.debug(s.colors.Blue, "Worker number", id, "finished file", s.colors.Restore, file)
+	}
+}
+
+// searchStopped reports whether the remaining files can be skipped, because
+// the search was interrupted, --quiet has found a match or --max-results has
+// been reached.  In the last case the results are marked as truncated, since
+// a skipped file might have matched.
+func (s *Searcher) searchStopped() bool {
+	if s.stats.Interrupted() {
+		return true
+	}
+	if s.settings.Quiet && s.stats.MatchCount() > 0 {
+		return true
+	}
+	if s.stats.ResultLimitReached(s.settings.MaxResults) {
+		s.stats.markTruncated()
+		return true
+	}
+	return false
+}
+
+// scanFiles fans the paths received on jobs out to a pool of workers and
+// passes each file's results to handle as they arrive.  It returns once jobs
+// has been closed and every queued file has been scanned.
+func (s *Searcher) scanFiles(jobs <-chan string, handle func([]Match)) {
+	results := make(chan []Match, 100)
+
+	numWorkers := s.threads
+	if numWorkers < 1 {
+		numWorkers = runtime.NumCPU()
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			s.worker(id, jobs, results)
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		handle(result)
+	}
+}
+
+// queuedFiles returns a closed channel holding the paths collected by the walker
+func (s *Searcher) queuedFiles() <-chan string {
+	jobs := make(chan string, len(s.filesToScan))
+	for _, val := range s.filesToScan {
+		jobs <- val.Path
+	}
+	close(jobs)
+	return jobs
+}
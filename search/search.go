@@ -0,0 +1,259 @@
+// Package search is the engine behind findref.  It walks directories
+// applying findref's filters (the default excludes, hidden files, includes,
+// globs, file types, size and time limits), skips or transcodes binary and
+// non UTF-8 files, optionally looks inside compressed files and archives, and
+// reports every line that matches a regex.  It searches the operating
+// system's filesystem, or any fs.FS given in the Options.
+//
+// A search is configured with a Settings and started with Search:
+//
+//	results, err := search.Search(ctx, search.Options{Pattern: "TODO", Paths: []string{"."}})
+//	if err != nil {
+//		return err
+//	}
+//	for m := range results.Matches() {
+//		fmt.Printf("%s:%d:%s\n", m.Path, m.LineNumber, m.Line)
+//	}
+//	stats := results.Wait()
+package search
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// stdinPath is the name reported for matches in Options.Input by default
+const stdinPath = "(standard input)"
+
+// Options describes a search for Search
+type Options struct {
+	// Pattern is the RE2 regex to search for.  Like the findref command it
+	// uses smart case: it is case-insensitive unless it contains a capital
+	// letter, which IgnoreCase and MatchCase override.  It replaces both
+	// Settings.Matcher and Settings.MatchRegex; if Pattern is empty, they are
+	// used as is.
+	Pattern    string
+	IgnoreCase bool
+	MatchCase  bool
+
+	// Paths are the directories and files to search, "." if there are none
+	Paths []string
+
+	// FS, if set, is searched instead of the operating system's filesystem,
+	// e.g. an embed.FS, a zip.Reader or an fstest.MapFS.  Paths and the
+	// entries of FileList are then slash-separated paths within it, as
+	// fs.ValidPath describes, and matches are reported with those paths.
+	FS fs.FS
+
+	// FileList, if set, is searched instead of Paths.  It lists the files to
+	// search, separated by newlines or NUL bytes, and is closed once read.
+	FileList io.ReadCloser
+
+	// Input, if set, is searched instead of Paths as if it were a single
+	// file.  Its matches are reported as InputName, or "(standard input)".
+	Input     io.Reader
+	InputName string
+
+	// Settings holds the filters and limits of the search.  If nil the
+	// defaults from NewSettings are used.  The search works on a copy, so
+	// the Settings may be reused.
+	Settings *Settings
+
+	// DebugOutput is where the debug output of a search with Settings.Debug
+	// is written, standard error if it isn't set
+	DebugOutput io.Writer
+
+	// Errors, if set, is called with the problems that don't stop the
+	// search, like a start directory that can't be read.  It may be called
+	// from several goroutines at once.
+	Errors func(error)
+}
+
+// Results are the matches of a running search
+type Results struct {
+	matches   chan Match
+	done      chan struct{}
+	stats     *Statistics
+	stopReads context.CancelFunc
+}
+
+// Matches returns the matches as they are found.  The matches of a file
+// arrive together, but files are reported in the order they finish.  The
+// channel is closed when the search is done.
+func (r *Results) Matches() <-chan Match {
+	return r.matches
+}
+
+// Stats returns the statistics of the search so far
+func (r *Results) Stats() *Statistics {
+	return r.stats
+}
+
+// Stop ends the search early, as if it had been interrupted.  Unlike
+// cancelling the search's context, the matches found so far are still
+// delivered.
+func (r *Results) Stop() {
+	r.stats.Interrupt(InterruptedByCancel)
+	r.stopReads()
+}
+
+// Wait discards any matches that haven't been received, waits for the search
+// to finish and returns its final statistics
+func (r *Results) Wait() *Statistics {
+	for range r.matches {
+	}
+	<-r.done
+	return r.stats
+}
+
+// Search starts a search in the background and returns its results.  The
+// search stops early if ctx is done or Settings.Timeout passes, in which case
+// the statistics record the interruption.  The caller must receive every
+// match, or cancel ctx, for the search to finish.  Reads of Options.Input
+// and Options.FileList that block are given up when the search stops.
+func Search(ctx context.Context, opts Options) (*Results, error) {
+	s := searcherFor(opts)
+	settings := s.settings
+	if opts.Pattern != "" {
+		matchRegex, err := CompileMatchRegex(opts.IgnoreCase, opts.MatchCase, opts.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		settings.MatchRegex = matchRegex
+		// Pattern takes the place of a Matcher the settings may carry
+		settings.Matcher = nil
+	}
+	if settings.MatchRegex == nil && settings.Matcher == nil {
+		return nil, fmt.Errorf("%s", "a pattern to search for is required")
+	}
+
+	inputName := opts.InputName
+	if inputName == "" {
+		inputName = stdinPath
+	}
+
+	roots := NormalizeRoots(opts.Paths)
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	// A timeout stops the search, but the matches found before it are
+	// still delivered.  Only the caller giving up abandons them.
+	abandoned := ctx.Done()
+	cancel := func() {}
+	if settings.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+	}
+	stopWatching := s.interruptWhenDone(ctx)
+	readCtx, stopReads := context.WithCancel(ctx)
+
+	var input io.Reader
+	if opts.Input != nil {
+		input = &abandonableReader{ctx: readCtx, r: opts.Input}
+	}
+	fileList := opts.FileList
+	if fileList != nil {
+		fileList = abandonableReadCloser{&abandonableReader{ctx: readCtx, r: fileList}, fileList}
+	}
+
+	results := &Results{
+		matches:   make(chan Match, 100),
+		done:      make(chan struct{}),
+		stats:     s.stats,
+		stopReads: stopReads,
+	}
+
+	emit := func(batch []Match) {
+		for _, m := range batch {
+			select {
+			case results.matches <- m:
+			case <-abandoned:
+				// Nobody is listening any more, and the workers are stopping
+				return
+			}
+		}
+	}
+
+	go func() {
+		defer close(results.done)
+		defer close(results.matches)
+		defer cancel()
+		defer stopReads()
+		defer stopWatching()
+		// The reads given up when ctx is done may end the search before
+		// interruptWhenDone has recorded why
+		defer s.interruptIfDone(ctx)
+
+		switch {
+		case input != nil:
+			emit(s.scanInput(inputName, input))
+		case fileList != nil:
+			s.threads = s.workerCount(nil)
+			s.scanFiles(s.listedFiles(fileList), emit)
+		default:
+			s.debug(s.colors.Blue, "roots: ", s.colors.Restore, roots)
+			s.walkRoots(roots)
+			s.threads = s.workerCount(roots)
+			s.debug(s.colors.Blue, "threads: ", s.colors.Restore, s.threads)
+			s.scanFiles(s.queuedFiles(), emit)
+		}
+	}()
+
+	return results, nil
+}
+
+// ListFiles returns the files a search with opts would scan, in the order it
+// would queue them, without reading them.  Options.Pattern and Options.Input
+// are ignored.  Like a search, the listing stops early if ctx is done or
+// Settings.Timeout passes, in which case the statistics record the
+// interruption and the files found so far are returned.
+func ListFiles(ctx context.Context, opts Options) ([]FileToScan, *Statistics) {
+	s := searcherFor(opts)
+	settings := s.settings
+	if settings.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, settings.Timeout)
+		defer cancel()
+	}
+	defer s.interruptWhenDone(ctx)()
+	defer s.interruptIfDone(ctx)
+
+	if opts.FileList != nil {
+		files := []FileToScan{}
+		list := abandonableReadCloser{&abandonableReader{ctx: ctx, r: opts.FileList}, opts.FileList}
+		for path := range s.listedFiles(list) {
+			info, err := fs.Stat(s.fsys, path)
+			files = append(files, FileToScan{Path: path, Info: info, Err: err})
+		}
+		return files, s.stats
+	}
+
+	roots := NormalizeRoots(opts.Paths)
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	s.debug(s.colors.Blue, "roots: ", s.colors.Restore, roots)
+	s.walkRoots(roots)
+	return s.filesToScan, s.stats
+}
+
+// searcherFor creates the Searcher for a search with opts.  It works on a
+// copy of the settings, so the caller's are left as they were.
+func searcherFor(opts Options) *Searcher {
+	settings := NewSettings()
+	if opts.Settings != nil {
+		copied := *opts.Settings
+		settings = &copied
+	}
+	s := newSearcher(settings)
+	s.onError = opts.Errors
+	if opts.FS != nil {
+		s.fsys = opts.FS
+	}
+	if opts.DebugOutput != nil {
+		s.debugOut = opts.DebugOutput
+	}
+	return s
+}
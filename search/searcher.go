@@ -0,0 +1,71 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Searcher runs a single search.  It owns the settings and statistics of the
+// search and the files queued for it, so that several searches (e.g.
+// concurrent MCP calls) can run side by side.  Search and ListFiles create
+// and run one.
+type Searcher struct {
+	settings *Settings
+	stats    *Statistics
+	fsys     fs.FS
+	threads  int
+	onError  func(error)
+	colors   *Colors
+
+	debugOut io.Writer
+	debugMux sync.Mutex
+
+	matcher     Matcher
+	matcherOnce sync.Once
+
+	filesToScan []FileToScan
+}
+
+func newSearcher(settings *Settings) *Searcher {
+	return &Searcher{
+		settings:    settings,
+		stats:       NewStatistics(),
+		fsys:        osFS{},
+		colors:      settings.colors(),
+		debugOut:    os.Stderr,
+		filesToScan: make([]FileToScan, 0, 100),
+	}
+}
+
+// lineMatcher returns Settings.Matcher, or else the fastest Matcher for
+// Settings.MatchRegex, which is only worked out once
+func (s *Searcher) lineMatcher() Matcher {
+	s.matcherOnce.Do(func() {
+		s.matcher = s.settings.Matcher
+		if s.matcher == nil {
+			s.matcher = NewMatcher(s.settings.MatchRegex)
+			s.debug(s.colors.Blue, "matcher: ", s.colors.Restore, fmt.Sprintf("%T", s.matcher))
+		}
+	})
+	return s.matcher
+}
+
+func (s *Searcher) debug(a ...interface{}) {
+	if s.settings.Debug {
+		s.debugMux.Lock()
+		defer s.debugMux.Unlock()
+		fmt.Fprintln(s.debugOut, a...)
+	}
+}
+
+// reportError passes a problem that doesn't stop the search, like an
+// unreadable start directory, to the caller's error handler
+func (s *Searcher) reportError(err error) {
+	s.debug(s.colors.Red+"Error:"+s.colors.Restore, err)
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
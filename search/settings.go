@@ -0,0 +1,545 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var defaultExcludeDirs = []string{
+	".git",
+	".svn",
+	".hg",
+	".bzr",
+	"CVS",
+	"vendor",
+	"node_modules",
+	"build",
+	"dist",
+	"out",
+	"coverage",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"composer.lock",
+	"Gemfile.lock",
+	"mix.lock",
+	"Cargo.lock",
+	"Pipfile.lock",
+	"poetry.lock",
+	"Podfile.lock",
+	"go.sum",
+	"gradle.lockfile",
+}
+
+var defaultExcludeEntries = buildExcludeEntries(defaultExcludeDirs...)
+
+// DefaultExcludes returns the directories and files that are skipped unless
+// Settings.UseDefaultExcludes is turned off
+func DefaultExcludes() []string {
+	return append([]string(nil), defaultExcludeDirs...)
+}
+
+type excludeEntry struct {
+	value     string
+	matchBase bool
+}
+
+type Settings struct {
+	Debug              bool
+	FilenameOnly       bool
+	IncludeHidden      bool
+	MaxLineLength      int
+	NoMaxLineLength    bool
+	SearchZip          bool
+	SearchArchives     bool
+	ArchiveDepth       int
+	Encoding           Encoding
+	Binary             BinaryPolicy
+	MinFilesize        int64
+	MaxFilesize        int64
+	NewerThan          time.Time
+	OlderThan          time.Time
+	MaxDepth           int
+	FollowSymlinks     bool
+	Quiet              bool
+	MaxCount           int
+	MaxResults         int
+	Timeout            time.Duration
+	Threads            int
+	MatchRegex         *regexp.Regexp
+	Matcher            Matcher
+	FilenameRegex      *regexp.Regexp
+	HiddenFileRegex    *regexp.Regexp
+	Colors             *Colors
+	UseDefaultExcludes bool
+	excludes           []excludeEntry
+	excludePatterns    []*regexp.Regexp
+	includes           []excludeEntry
+	includePatterns    []*regexp.Regexp
+	globs              []globEntry
+	fileTypes          map[string]*FileType
+	selectedTypes      []*FileType
+	excludedTypes      []*FileType
+}
+
+func NewSettings() *Settings {
+	s := &Settings{
+		Debug:              false,
+		FilenameOnly:       false,
+		IncludeHidden:      false,
+		MaxLineLength:      2000,
+		NoMaxLineLength:    false,
+		SearchZip:          false,
+		SearchArchives:     false,
+		ArchiveDepth:       ArchiveDepthDefault,
+		Encoding:           EncodingAuto,
+		Binary:             BinarySkip,
+		MinFilesize:        0,
+		MaxFilesize:        0,
+		MaxDepth:           MaxDepthUnlimited,
+		MatchRegex:         nil,
+		FilenameRegex:      regexp.MustCompile(".*"),
+		HiddenFileRegex:    regexp.MustCompile(`(^|\/)\.`),
+		Colors:             NewColors(),
+		excludes:           []excludeEntry{},
+		excludePatterns:    []*regexp.Regexp{},
+		includes:           []excludeEntry{},
+		includePatterns:    []*regexp.Regexp{},
+		globs:              []globEntry{},
+		fileTypes:          newFileTypeRegistry(),
+		selectedTypes:      []*FileType{},
+		excludedTypes:      []*FileType{},
+		UseDefaultExcludes: true,
+	}
+	return s
+}
+
+func (s *Settings) PassesFileFilter(path string) bool {
+	return s.FilenameRegex.MatchString(path)
+}
+
+func (s *Settings) IsHidden(path string) bool {
+	// Ignore hidden files unless the IncludeHidden flag is set
+	return path != "." && !s.IncludeHidden && s.HiddenFileRegex.MatchString(path)
+}
+
+func (s *Settings) AddExcludeDirs(dirs ...string) {
+	s.AddExcludes(dirs...)
+}
+
+func (s *Settings) AddExcludes(paths ...string) {
+	s.excludes = append(s.excludes, buildExcludeEntries(paths...)...)
+}
+
+func (s *Settings) AddExcludePatterns(patterns ...string) error {
+	for _, p := range patterns {
+		trimmed := strings.TrimSpace(p)
+		if trimmed == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(trimmed)
+		if err != nil {
+			return fmt.Errorf("invalid exclude-pattern %q: %w", trimmed, err)
+		}
+		s.excludePatterns = append(s.excludePatterns, compiled)
+	}
+	return nil
+}
+
+func (s *Settings) ExcludePatterns() []string {
+	retval := make([]string, 0, len(s.excludePatterns))
+	for _, re := range s.excludePatterns {
+		retval = append(retval, re.String())
+	}
+	return retval
+}
+
+func (s *Settings) ExcludeDirs() []string {
+	return s.Excludes()
+}
+
+func (s *Settings) Excludes() []string {
+	total := len(s.excludes)
+	if s.UseDefaultExcludes {
+		total += len(defaultExcludeDirs)
+	}
+	retval := make([]string, 0, total)
+	for _, entry := range s.excludes {
+		retval = append(retval, entry.value)
+	}
+	if s.UseDefaultExcludes {
+		retval = append(retval, defaultExcludeDirs...)
+	}
+	return retval
+}
+
+func (s *Settings) AddIncludes(paths ...string) {
+	s.includes = append(s.includes, buildExcludeEntries(paths...)...)
+}
+
+func (s *Settings) AddIncludePatterns(patterns ...string) error {
+	for _, p := range patterns {
+		trimmed := strings.TrimSpace(p)
+		if trimmed == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(trimmed)
+		if err != nil {
+			return fmt.Errorf("invalid include-pattern %q: %w", trimmed, err)
+		}
+		s.includePatterns = append(s.includePatterns, compiled)
+	}
+	return nil
+}
+
+func (s *Settings) IncludePatterns() []string {
+	retval := make([]string, 0, len(s.includePatterns))
+	for _, re := range s.includePatterns {
+		retval = append(retval, re.String())
+	}
+	return retval
+}
+
+func (s *Settings) Includes() []string {
+	retval := make([]string, 0, len(s.includes))
+	for _, entry := range s.includes {
+		retval = append(retval, entry.value)
+	}
+	return retval
+}
+
+func (s *Settings) HasIncludes() bool {
+	return len(s.includes) > 0 || len(s.includePatterns) > 0
+}
+
+func (s *Settings) ShouldIncludeFile(path string) bool {
+	if !s.HasIncludes() {
+		return true
+	}
+	cleanedPath := filepath.Clean(path)
+	pathBase := filepath.Base(cleanedPath)
+	if s.matchesExclude(cleanedPath, pathBase, s.includes) {
+		return true
+	}
+	for _, re := range s.includePatterns {
+		if re.MatchString(cleanedPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// PassesMetadataFilter applies --min-filesize, --max-filesize, --newer-than
+// and --older-than.  A zero value disables each limit.
+func (s *Settings) PassesMetadataFilter(info os.FileInfo) bool {
+	if info == nil {
+		return true
+	}
+	if s.MinFilesize > 0 && info.Size() < s.MinFilesize {
+		return false
+	}
+	if s.MaxFilesize > 0 && info.Size() > s.MaxFilesize {
+		return false
+	}
+	if !s.NewerThan.IsZero() && !info.ModTime().After(s.NewerThan) {
+		return false
+	}
+	if !s.OlderThan.IsZero() && !info.ModTime().Before(s.OlderThan) {
+		return false
+	}
+	return true
+}
+
+// AddGlobs appends globs to the ordered glob filter.  A leading '!' turns a
+// glob into an exclusion.
+func (s *Settings) AddGlobs(ignoreCase bool, globs ...string) error {
+	for _, g := range globs {
+		if strings.TrimSpace(g) == "" {
+			continue
+		}
+		entry, err := parseGlob(g, ignoreCase)
+		if err != nil {
+			return err
+		}
+		s.globs = append(s.globs, entry)
+	}
+	return nil
+}
+
+func (s *Settings) Globs() []string {
+	retval := make([]string, 0, len(s.globs))
+	for _, entry := range s.globs {
+		retval = append(retval, entry.raw)
+	}
+	return retval
+}
+
+// globDecision evaluates the globs in order against path, which is relative
+// to the search root.  The last glob that matches decides whether the path is
+// included; matched is false if none did.
+func (s *Settings) globDecision(path string) (matched bool, included bool) {
+	if len(s.globs) == 0 || path == "." {
+		return false, false
+	}
+	segments := splitGlobPath(path)
+	var lowered []string
+	for i := range s.globs {
+		entry := &s.globs[i]
+		candidate := segments
+		if entry.ignoreCase {
+			if lowered == nil {
+				lowered = splitGlobPath(strings.ToLower(path))
+			}
+			candidate = lowered
+		}
+		if entry.matches(candidate) {
+			matched, included = true, !entry.negated
+		}
+	}
+	return matched, included
+}
+
+// PassesGlobFilter reports whether the globs allow the file at path, which is
+// relative to the search root.  If no glob matches, the file passes only when
+// every glob is a negation.
+func (s *Settings) PassesGlobFilter(path string) bool {
+	if matched, included := s.globDecision(path); matched {
+		return included
+	}
+	for _, entry := range s.globs {
+		if !entry.negated {
+			return false
+		}
+	}
+	return true
+}
+
+// ExcludedByGlob reports whether a negated glob is the last glob to match
+// path, which is relative to the search root.  Directories are only ever
+// pruned this way, since a positive glob like "*.go" says nothing about which
+// directories to enter.
+func (s *Settings) ExcludedByGlob(path string) bool {
+	matched, included := s.globDecision(path)
+	return matched && !included
+}
+
+// AddFileTypes registers user defined file types, replacing any built in
+// type of the same name
+func (s *Settings) AddFileTypes(types map[string]FileType) error {
+	for _, name := range sortedFileTypeNames(types) {
+		t := types[name]
+		t.Name = name
+		normalized, err := normalizeFileType(t)
+		if err != nil {
+			return err
+		}
+		s.fileTypes[normalized.Name] = &normalized
+	}
+	return nil
+}
+
+// FileTypes returns every known file type sorted by name
+func (s *Settings) FileTypes() []*FileType {
+	retval := make([]*FileType, 0, len(s.fileTypes))
+	for _, t := range s.fileTypes {
+		retval = append(retval, t)
+	}
+	sort.Slice(retval, func(i, j int) bool { return retval[i].Name < retval[j].Name })
+	return retval
+}
+
+// SelectTypes limits the search to files of the named types.  Names may be
+// repeated or comma separated.
+func (s *Settings) SelectTypes(names ...string) error {
+	types, err := s.lookupFileTypes(names)
+	s.selectedTypes = append(s.selectedTypes, types...)
+	return err
+}
+
+// ExcludeTypes skips files of the named types
+func (s *Settings) ExcludeTypes(names ...string) error {
+	types, err := s.lookupFileTypes(names)
+	s.excludedTypes = append(s.excludedTypes, types...)
+	return err
+}
+
+func (s *Settings) lookupFileTypes(names []string) ([]*FileType, error) {
+	retval := make([]*FileType, 0, len(names))
+	for _, name := range splitTypeNames(names) {
+		t, ok := s.fileTypes[name]
+		if !ok {
+			return retval, fmt.Errorf("unknown file type %q (see --type-list)", name)
+		}
+		retval = append(retval, t)
+	}
+	return retval, nil
+}
+
+func (s *Settings) HasTypeFilter() bool {
+	return len(s.selectedTypes) > 0 || len(s.excludedTypes) > 0
+}
+
+// PassesTypeFilter reports whether path belongs to one of the selected types
+// (if any were selected) and to none of the excluded types.  The file is only
+// opened to read its #! line if it has no extension and a type needs it.
+func (s *Settings) PassesTypeFilter(path string) bool {
+	return s.passesTypeFilterIn(osFS{}, path)
+}
+
+// passesTypeFilterIn is PassesTypeFilter for a file within fsys
+func (s *Settings) passesTypeFilterIn(fsys fs.FS, path string) bool {
+	return s.passesTypeFilterWith(path, func() string { return shebangInterpreter(fsys, path) })
+}
+
+// passesTypeFilterWith is PassesTypeFilter for a file whose #! interpreter,
+// if a type needs it, is read by interpreter
+func (s *Settings) passesTypeFilterWith(path string, interpreter func() string) bool {
+	if !s.HasTypeFilter() {
+		return true
+	}
+	base := filepath.Base(path)
+	shebang, shebangRead := "", false
+	matches := func(t *FileType) bool {
+		if t.matchesName(base) {
+			return true
+		}
+		if len(t.Shebangs) == 0 || hasExtension(base) {
+			return false
+		}
+		if !shebangRead {
+			shebang, shebangRead = interpreter(), true
+		}
+		return shebang != "" && t.matchesInterpreter(shebang)
+	}
+
+	if len(s.selectedTypes) > 0 {
+		selected := false
+		for _, t := range s.selectedTypes {
+			if matches(t) {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			return false
+		}
+	}
+	for _, t := range s.excludedTypes {
+		if matches(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// PathExcluded reports whether a search of root would pass over path, a
+// file within it, because the file or one of the directories leading to it
+// from root is excluded or hidden.  Paths outside root are excluded too.
+func (s *Settings) PathExcluded(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	if rel != "." {
+		dir := filepath.Clean(root)
+		if s.ShouldExcludeDir(dir) || s.IsHidden(dir) {
+			return true
+		}
+		for _, name := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+			if name == "." {
+				continue
+			}
+			dir = filepath.Join(dir, name)
+			if s.ShouldExcludeDir(dir) || s.ExcludedByGlob(globPath(root, dir)) || s.IsHidden(dir) {
+				return true
+			}
+		}
+	}
+	return s.ShouldExcludeFile(path) || s.ExcludedByGlob(globPath(root, path)) || s.IsHidden(path)
+}
+
+func (s *Settings) ShouldExcludeDir(path string) bool {
+	return s.shouldExclude(path)
+}
+
+func (s *Settings) ShouldExcludeFile(path string) bool {
+	return s.shouldExclude(path)
+}
+
+func (s *Settings) shouldExclude(path string) bool {
+	return s.excludedBy(path, s.UseDefaultExcludes)
+}
+
+// excludedBy reports whether path is excluded by the user's excludes and
+// exclude patterns, or by the default excludes too if defaults is set
+func (s *Settings) excludedBy(path string, defaults bool) bool {
+	cleanedPath := filepath.Clean(path)
+	pathBase := filepath.Base(cleanedPath)
+	if s.matchesExclude(cleanedPath, pathBase, s.excludes) {
+		return true
+	}
+	if defaults && s.matchesExclude(cleanedPath, pathBase, defaultExcludeEntries) {
+		return true
+	}
+	for _, re := range s.excludePatterns {
+		if re.MatchString(cleanedPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Settings) matchesExclude(cleanedPath string, pathBase string, entries []excludeEntry) bool {
+	for _, entry := range entries {
+		if cleanedPath == entry.value {
+			return true
+		}
+		if entry.matchBase && pathBase == entry.value {
+			return true
+		}
+		if !entry.matchBase && !filepath.IsAbs(entry.value) {
+			if strings.HasSuffix(cleanedPath, string(filepath.Separator)+entry.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func buildExcludeEntries(paths ...string) []excludeEntry {
+	entries := make([]excludeEntry, 0, len(paths))
+	for _, path := range paths {
+		trimmed := strings.TrimSpace(path)
+		if trimmed == "" {
+			continue
+		}
+		cleaned := filepath.Clean(trimmed)
+		if cleaned == "." {
+			continue
+		}
+		entry := excludeEntry{
+			value:     cleaned,
+			matchBase: filepath.Base(cleaned) == cleaned,
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// colors returns the Colors of the settings, or no colors at all for
+// settings that weren't made by NewSettings
+func (s *Settings) colors() *Colors {
+	if s.Colors == nil {
+		colors := NewColors()
+		colors.ZeroColors()
+		return colors
+	}
+	return s.Colors
+}
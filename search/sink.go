@@ -0,0 +1,109 @@
+package search
+
+import "sort"
+
+// Sink receives the results of a search as a stream of events.  Deliver
+// passes the matches one file at a time: BeginFile, then Match (and Context)
+// for each of the file's lines, then EndFile.  Summary comes last, once the
+// search is done.  The methods are called from a single goroutine.
+type Sink interface {
+	BeginFile(path string)
+	Match(m Match)
+	// Context receives a line shown around a match to give it context.  It
+	// has no span, so its Match is nil.
+	Context(m Match)
+	EndFile(path string, matches int)
+	Summary(stats *Statistics)
+}
+
+// Deliver passes the matches to sink as they arrive, and then the final
+// statistics.  It returns once the search is done.
+func (r *Results) Deliver(sink Sink) *Statistics {
+	files := fileEvents{sink: sink}
+	for m := range r.matches {
+		files.match(m)
+	}
+	files.end()
+
+	stats := r.Wait()
+	sink.Summary(stats)
+	return stats
+}
+
+// DeliverSorted is Deliver, but holds the matches back until the search is
+// done so that the files can be passed to sink in order of their paths
+func (r *Results) DeliverSorted(sink Sink) *Statistics {
+	var all []Match
+	for m := range r.matches {
+		all = append(all, m)
+	}
+	// The matches of a file arrive in order, so a stable sort keeps them so
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Path < all[j].Path
+	})
+
+	files := fileEvents{sink: sink}
+	for _, m := range all {
+		files.match(m)
+	}
+	files.end()
+
+	stats := r.Wait()
+	sink.Summary(stats)
+	return stats
+}
+
+// fileEvents turns a stream of matches, in which the matches of a file are
+// next to each other, into the file events of a Sink
+type fileEvents struct {
+	sink    Sink
+	path    string
+	matches int
+	open    bool
+}
+
+func (f *fileEvents) match(m Match) {
+	if f.open && m.Path != f.path {
+		f.end()
+	}
+	if !f.open {
+		f.sink.BeginFile(m.Path)
+		f.path, f.matches, f.open = m.Path, 0, true
+	}
+	f.sink.Match(m)
+	f.matches++
+}
+
+func (f *fileEvents) end() {
+	if f.open {
+		f.sink.EndFile(f.path, f.matches)
+		f.open = false
+	}
+}
+
+// Collector is a Sink that keeps the results of a search in memory
+type Collector struct {
+	// Files are the paths of the files with matches, in the order delivered
+	Files        []string
+	Matches      []Match
+	ContextLines []Match
+	Stats        *Statistics
+}
+
+func (c *Collector) BeginFile(path string) {
+	c.Files = append(c.Files, path)
+}
+
+func (c *Collector) Match(m Match) {
+	c.Matches = append(c.Matches, m)
+}
+
+func (c *Collector) Context(m Match) {
+	c.ContextLines = append(c.ContextLines, m)
+}
+
+func (c *Collector) EndFile(path string, matches int) {}
+
+func (c *Collector) Summary(stats *Statistics) {
+	c.Stats = stats
+}
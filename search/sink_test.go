@@ -0,0 +1,220 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// recordingSink is a Sink that notes each event it receives
+type recordingSink struct {
+	events []string
+}
+
+func (r *recordingSink) BeginFile(path string) {
+	r.events = append(r.events, "begin "+path)
+}
+
+func (r *recordingSink) Match(m Match) {
+	r.events = append(r.events, fmt.Sprintf("match %s:%d", m.Path, m.LineNumber))
+}
+
+func (r *recordingSink) Context(m Match) {
+	r.events = append(r.events, fmt.Sprintf("context %s:%d", m.Path, m.LineNumber))
+}
+
+func (r *recordingSink) EndFile(path string, matches int) {
+	r.events = append(r.events, fmt.Sprintf("end %s %d", path, matches))
+}
+
+func (r *recordingSink) Summary(stats *Statistics) {
+	r.events = append(r.events, fmt.Sprintf("summary %d", stats.MatchCount()))
+}
+
+var sinkTestFS = fstest.MapFS{
+	"b.txt":     {Data: []byte("TODO b1\nnothing\nTODO b3\n")},
+	"a.txt":     {Data: []byte("TODO a1\n")},
+	"c/d.txt":   {Data: []byte("no match\n")},
+	"c/e.txt":   {Data: []byte("x\nTODO e2\n")},
+	"empty.txt": {Data: []byte("")},
+}
+
+func TestDeliverGroupsMatchesByFile(t *testing.T) {
+	settings := NewSettings()
+	settings.Threads = 1
+	results, err := Search(context.Background(), Options{Pattern: "TODO", FS: sinkTestFS, Settings: settings})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	sink := &recordingSink{}
+	stats := results.Deliver(sink)
+
+	if stats.MatchCount() != 4 {
+		t.Errorf("expected 4 matches, got %d", stats.MatchCount())
+	}
+	// Files may arrive in any order, but each one's events must be together
+	open := ""
+	seen := map[string]bool{}
+	for _, event := range sink.events[:len(sink.events)-1] {
+		kind, rest, _ := strings.Cut(event, " ")
+		switch kind {
+		case "begin":
+			if open != "" || seen[rest] {
+				t.Fatalf("unexpected %q in %v", event, sink.events)
+			}
+			open, seen[rest] = rest, true
+		case "match":
+			if !strings.HasPrefix(rest, open+":") {
+				t.Fatalf("match outside its file in %v", sink.events)
+			}
+		case "end":
+			if !strings.HasPrefix(rest, open+" ") {
+				t.Fatalf("unexpected %q in %v", event, sink.events)
+			}
+			open = ""
+		}
+	}
+	if last := sink.events[len(sink.events)-1]; last != "summary 4" {
+		t.Errorf("expected the summary last, got %q", last)
+	}
+}
+
+func TestDeliverSorted(t *testing.T) {
+	results, err := Search(context.Background(), Options{Pattern: "TODO", FS: sinkTestFS})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	sink := &recordingSink{}
+	results.DeliverSorted(sink)
+
+	want := []string{
+		"begin a.txt", "match a.txt:1", "end a.txt 1",
+		"begin b.txt", "match b.txt:1", "match b.txt:3", "end b.txt 2",
+		"begin c/e.txt", "match c/e.txt:2", "end c/e.txt 1",
+		"summary 4",
+	}
+	if strings.Join(sink.events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(sink.events, "\n"))
+	}
+}
+
+func TestCollector(t *testing.T) {
+	results, err := Search(context.Background(), Options{Pattern: "TODO", FS: sinkTestFS})
+	if err != nil {
+		t.Fatalf("starting the search: %v", err)
+	}
+	collector := &Collector{}
+	stats := results.DeliverSorted(collector)
+
+	if strings.Join(collector.Files, ",") != "a.txt,b.txt,c/e.txt" {
+		t.Errorf("unexpected files %v", collector.Files)
+	}
+	if len(collector.Matches) != 4 || string(collector.Matches[2].Line) != "TODO b3" {
+		t.Errorf("unexpected matches %+v", collector.Matches)
+	}
+	if collector.Stats != stats {
+		t.Error("expected the collector to keep the final statistics")
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewJSONSink(&out)
+	sink.BeginFile("a.txt")
+	sink.Match(Match{Path: "a.txt", LineNumber: 3, Line: []byte("x TODO \xff"), Match: []int{2, 6}})
+	sink.Context(Match{Path: "a.txt", LineNumber: 4, Line: []byte("after")})
+	sink.EndFile("a.txt", 1)
+	sink.BeginFile("b.bin")
+	sink.Match(Match{Path: "b.bin", Binary: true})
+	sink.EndFile("b.bin", 1)
+	stats := NewStatistics()
+	stats.incrMatchCount()
+	stats.Interrupt(InterruptedByTimeout)
+	sink.Summary(stats)
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 8 {
+		t.Fatalf("expected 8 events, got %d: %v", len(events), events)
+	}
+
+	match := events[1]
+	if match["type"] != "match" || match["line_number"] != 3.0 || match["text"] != "x TODO �" ||
+		match["match_start"] != 2.0 || match["match_end"] != 6.0 {
+		t.Errorf("unexpected match event %v", match)
+	}
+	if context := events[2]; context["type"] != "context" || context["text"] != "after" || context["match_start"] != nil {
+		t.Errorf("unexpected context event %v", context)
+	}
+	if end := events[3]; end["type"] != "end" || end["matches"] != 1.0 {
+		t.Errorf("unexpected end event %v", end)
+	}
+	if binary := events[5]; binary["binary"] != true || binary["text"] != nil {
+		t.Errorf("unexpected binary match event %v", binary)
+	}
+	summary := events[7]
+	if summary["type"] != "summary" || summary["total_matches"] != 1.0 || summary["timed_out"] != true ||
+		summary["interrupted"] != true || summary["truncated"] != true {
+		t.Errorf("unexpected summary event %v", summary)
+	}
+}
+
+func TestTextSink(t *testing.T) {
+	var out bytes.Buffer
+	settings := NewSettings()
+	settings.Colors.ZeroColors()
+	settings.MaxLineLength = 20
+	sink := NewTextSink(&out, settings)
+	sink.Match(Match{Path: "a.txt", LineNumber: 1, Line: []byte("x TODO"), Match: []int{2, 6}})
+	sink.Context(Match{Path: "a.txt", LineNumber: 2, Line: []byte("after")})
+	long := strings.Repeat("a", 50) + "TODO" + strings.Repeat("b", 50)
+	sink.Match(Match{Path: "a.txt", LineNumber: 3, Line: []byte(long), Match: []int{50, 54}})
+	sink.Match(Match{Path: "b.bin", Binary: true})
+	sink.Summary(NewStatistics())
+
+	want := "a.txt:1:x TODO\n" +
+		"a.txt-2-after\n" +
+		"a.txt:3:..." + strings.Repeat("a", 40) + "TODO" + strings.Repeat("b", 40) + "...\n" +
+		"Binary file b.bin matches\n"
+	if out.String() != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, out.String())
+	}
+
+	out.Reset()
+	sink.ShowStats = true
+	sink.Summary(NewStatistics())
+	if !strings.Contains(out.String(), "Matches found: 0") {
+		t.Errorf("expected statistics, got %q", out.String())
+	}
+}
+
+func TestTextSinkColorsArePerSettings(t *testing.T) {
+	plain := NewSettings()
+	plain.Colors.ZeroColors()
+	colored := NewSettings()
+
+	var plainOut, coloredOut bytes.Buffer
+	NewTextSink(&plainOut, plain).Match(Match{Path: "a.txt", LineNumber: 1, Line: []byte("TODO"), Match: []int{0, 4}})
+	NewTextSink(&coloredOut, colored).Match(Match{Path: "a.txt", LineNumber: 1, Line: []byte("TODO"), Match: []int{0, 4}})
+	if plainOut.String() != "a.txt:1:TODO\n" {
+		t.Errorf("expected no colors, got %q", plainOut.String())
+	}
+	if !strings.Contains(coloredOut.String(), colored.Colors.LightRed+"TODO"+colored.Colors.Restore) {
+		t.Errorf("expected turning off the colors of other settings to leave these alone, got %q", coloredOut.String())
+	}
+	if NewTextSink(&plainOut, &Settings{}).colors.Red != "" {
+		t.Error("expected settings without colors to print none")
+	}
+}
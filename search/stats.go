@@ -0,0 +1,258 @@
+package search
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Statistics struct {
+	filesToScan  int
+	filesScanned int
+	linesScanned int
+	matchesFound int
+	filesMatched int
+	skippedLong  int
+	skippedNull  int
+	erroredFiles int
+	decompressed int
+	archives     int
+	transcoded   int
+	brokenLinks  int
+	rootErrors   int
+	results      int
+	truncated    bool
+	interruption atomic.Int32 // checked for every line, so it doesn't take the lock
+	startTime    time.Time
+	mux          sync.Mutex
+}
+
+func NewStatistics() *Statistics {
+	return &Statistics{
+		filesScanned: 0,
+		linesScanned: 0,
+		matchesFound: 0,
+		skippedLong:  0,
+		skippedNull:  0,
+		erroredFiles: 0,
+		startTime:    time.Now(),
+	}
+}
+
+func (s *Statistics) incrFilesToScan() {
+	s.mux.Lock()
+	s.filesToScan++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrLineCount() {
+	s.mux.Lock()
+	s.linesScanned++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrFileCount() {
+	s.mux.Lock()
+	s.filesScanned++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrMatchCount() {
+	s.mux.Lock()
+	s.matchesFound++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrFilesMatchedCount() {
+	s.mux.Lock()
+	s.filesMatched++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrSkippedLongCount() {
+	s.mux.Lock()
+	s.skippedLong++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrSkippedNullCount() {
+	s.mux.Lock()
+	s.skippedNull++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrErroredFilesCount() {
+	s.mux.Lock()
+	s.erroredFiles++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrDecompressedCount() {
+	s.mux.Lock()
+	s.decompressed++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrArchiveCount() {
+	s.mux.Lock()
+	s.archives++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrTranscodedCount() {
+	s.mux.Lock()
+	s.transcoded++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrBrokenLinkCount() {
+	s.mux.Lock()
+	s.brokenLinks++
+	s.mux.Unlock()
+}
+
+func (s *Statistics) incrUnreadableRootCount() {
+	s.mux.Lock()
+	s.rootErrors++
+	s.mux.Unlock()
+}
+
+// reserveResult claims one of the limit results a search may report, or
+// marks the results as truncated if they have all been claimed.  A limit of 0
+// means there is no limit.
+func (s *Statistics) reserveResult(limit int) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if limit > 0 && s.results >= limit {
+		s.truncated = true
+		return false
+	}
+	s.results++
+	return true
+}
+
+// dropMatches takes back the matches of a file, and the results they
+// reserved, once they turn out not to be reportable after all
+func (s *Statistics) dropMatches(matches int, results int, fileMatched bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.matchesFound -= matches
+	s.results -= results
+	if fileMatched {
+		s.filesMatched--
+	}
+}
+
+func (s *Statistics) markTruncated() {
+	s.mux.Lock()
+	s.truncated = true
+	s.mux.Unlock()
+}
+
+// Interrupt stops the search, keeping the first reason if it was already
+// stopped
+func (s *Statistics) Interrupt(reason Interruption) {
+	s.interruption.CompareAndSwap(int32(NotInterrupted), int32(reason))
+}
+
+func (s *Statistics) LineCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.linesScanned
+}
+
+func (s *Statistics) FilesToScanCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.filesToScan
+}
+
+func (s *Statistics) FileCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.filesScanned
+}
+
+func (s *Statistics) MatchCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.matchesFound
+}
+
+func (s *Statistics) FilesMatchedCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.filesMatched
+}
+
+func (s *Statistics) SkippedNullCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.skippedNull
+}
+
+func (s *Statistics) ErroredFilesCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.erroredFiles
+}
+
+func (s *Statistics) DecompressedCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.decompressed
+}
+
+func (s *Statistics) ArchiveCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.archives
+}
+
+func (s *Statistics) TranscodedCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.transcoded
+}
+
+func (s *Statistics) BrokenLinkCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.brokenLinks
+}
+
+func (s *Statistics) UnreadableRootCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rootErrors
+}
+
+func (s *Statistics) ResultLimitReached(limit int) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return limit > 0 && s.results >= limit
+}
+
+func (s *Statistics) Truncated() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.truncated
+}
+
+func (s *Statistics) Interruption() Interruption {
+	return Interruption(s.interruption.Load())
+}
+
+func (s *Statistics) Interrupted() bool {
+	return s.Interruption() != NotInterrupted
+}
+
+func (s *Statistics) SkippedLongCount() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.skippedLong
+}
+
+func (s *Statistics) ElapsedTime() time.Duration {
+	return time.Now().Sub(s.startTime)
+}
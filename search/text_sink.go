@@ -0,0 +1,173 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// TextSink prints the results of a search the way the findref command does:
+// grep style lines with the match highlighted, only the paths of the files
+// with FilenameOnly, and the statistics at the end if ShowStats is set
+type TextSink struct {
+	out       io.Writer
+	settings  *Settings
+	colors    *Colors
+	ShowStats bool
+}
+
+// NewTextSink returns a TextSink printing to out.  The line length limit,
+// FilenameOnly and the colors are taken from settings.
+func NewTextSink(out io.Writer, settings *Settings) *TextSink {
+	return &TextSink{out: out, settings: settings, colors: settings.colors()}
+}
+
+func (t *TextSink) BeginFile(path string) {}
+
+func (t *TextSink) Match(m Match) {
+	switch {
+	case t.settings.FilenameOnly:
+		fmt.Fprintf(t.out, "%s%s%s\n", t.colors.Purple, m.Path, t.colors.Restore)
+	case m.Binary:
+		printBinaryMatch(t.out, t.colors, m.Path)
+	case !t.settings.NoMaxLineLength && (len(m.Line) > t.settings.MaxLineLength):
+		printMatchClip(t.out, t.colors, &m)
+	default:
+		printMatch(t.out, t.colors, &m)
+	}
+}
+
+// Context prints a context line like grep does, with - rather than : after
+// the path and line number
+func (t *TextSink) Context(m Match) {
+	if t.settings.FilenameOnly {
+		return
+	}
+	fmt.Fprintf(t.out, "%s%s%s-%s%s%s-%s\n",
+		t.colors.Purple,
+		m.Path,
+		t.colors.Restore,
+		t.colors.Green,
+		strconv.Itoa(m.LineNumber),
+		t.colors.Restore,
+		string(m.Line),
+	)
+}
+
+func (t *TextSink) EndFile(path string, matches int) {}
+
+// File prints one of the files of a listing from ListFiles, like the paths
+// printed with FilenameOnly
+func (t *TextSink) File(f FileToScan) {
+	fmt.Fprintf(t.out, "%s%s%s\n", t.colors.Purple, f.Path, t.colors.Restore)
+}
+
+func (t *TextSink) Summary(stats *Statistics) {
+	if !t.ShowStats {
+		return
+	}
+	fmt.Fprintf(t.out, "%sElapsed time:%s  %s\n", t.colors.Cyan, t.colors.Restore, stats.ElapsedTime().String())
+	fmt.Fprintf(t.out, "%sLines scanned:%s %d\n", t.colors.Cyan, t.colors.Restore, stats.LineCount())
+	fmt.Fprintf(t.out, "%sFiles scanned:%s %d\n", t.colors.Cyan, t.colors.Restore, stats.FileCount())
+	fmt.Fprintf(t.out, "%sMatches found:%s %d\n", t.colors.Cyan, t.colors.Restore, stats.MatchCount())
+	fmt.Fprintf(t.out, "%sSkipped Long: %s %d\n", t.colors.Cyan, t.colors.Restore, stats.SkippedLongCount())
+	fmt.Fprintf(t.out, "%sSkipped Null: %s %d\n", t.colors.Cyan, t.colors.Restore, stats.SkippedNullCount())
+	fmt.Fprintf(t.out, "%sErrored Files:%s %d\n", t.colors.Cyan, t.colors.Restore, stats.ErroredFilesCount())
+	fmt.Fprintf(t.out, "%sTranscoded:   %s %d\n", t.colors.Cyan, t.colors.Restore, stats.TranscodedCount())
+	fmt.Fprintf(t.out, "%sBroken Links: %s %d\n", t.colors.Cyan, t.colors.Restore, stats.BrokenLinkCount())
+	if stats.Interrupted() {
+		fmt.Fprintf(t.out, "%sPartial:      %s yes, the search %s\n", t.colors.Cyan, t.colors.Restore, stats.Interruption())
+	}
+	if stats.Truncated() {
+		fmt.Fprintf(t.out, "%sTruncated:    %s yes, --max-results was reached\n", t.colors.Cyan, t.colors.Restore)
+	}
+	if t.settings.SearchZip {
+		fmt.Fprintf(t.out, "%sDecompressed: %s %d\n", t.colors.Cyan, t.colors.Restore, stats.DecompressedCount())
+	}
+	if t.settings.SearchArchives {
+		fmt.Fprintf(t.out, "%sArchives:     %s %d\n", t.colors.Cyan, t.colors.Restore, stats.ArchiveCount())
+	}
+}
+
+const sideBuffer = 40
+
+// Prints the filename and line number, plus text with match in red
+// Emulates exactly the behavior of grep
+func printMatch(w io.Writer, colors *Colors, m *Match) {
+	fmt.Fprintf(w, "%s%s%s%s:%s:%s%s%s%s%s%s\n",
+		colors.Purple,
+		m.Path,
+		colors.Restore,
+		colors.Green,
+		strconv.Itoa(m.LineNumber),
+		colors.Restore,
+		string(m.Line[:m.Match[0]]),
+		colors.LightRed,
+		string(m.Line[m.Match[0]:m.Match[1]]),
+		colors.Restore,
+		string(m.Line[m.Match[1]:]),
+	)
+}
+
+// Prints the filename and line number, but if the text on the left or right
+// exceeds the size of sideBuffer then replace that part with a yellow ...
+func printMatchClip(w io.Writer, colors *Colors, m *Match) {
+	startStr := "..."
+	endStr := "..."
+	start := m.Match[0] - sideBuffer
+	end := m.Match[1] + sideBuffer
+
+	if start < 0 {
+		start = 0
+		startStr = ""
+	}
+	if end > len(m.Line)-1 {
+		end = len(m.Line) - 1
+		endStr = ""
+	}
+
+	fmt.Fprintf(w, "%s%s%s%s:%s:%s%s%s%s%s%s%s%s%s%s%s%s\n",
+		colors.Purple,
+		m.Path,
+		colors.Restore,
+		colors.Green,
+		strconv.Itoa(m.LineNumber),
+		colors.Restore,
+		colors.Yellow,
+		startStr,
+		colors.Restore,
+		string(m.Line[start:m.Match[0]]),
+		colors.LightRed,
+		string(m.Line[m.Match[0]:m.Match[1]]),
+		colors.Restore,
+		string(m.Line[m.Match[1]:end]),
+		colors.Yellow,
+		endStr,
+		colors.Restore,
+	)
+}
+
+// Prints the filename and line number, but replaces text with:
+// "<match exceeded maximum length of 2000>"
+func printMatchTooLong(w io.Writer, colors *Colors, m *Match) {
+	fmt.Fprintf(w, "%s%s%s%s:%s:%s%s%s%s%s%s%s%s\n",
+		colors.Purple,
+		m.Path,
+		colors.Restore,
+		colors.Green,
+		strconv.Itoa(m.LineNumber),
+		colors.Restore,
+		colors.Yellow,
+		"<match exceeded maximum length of ",
+		colors.Restore,
+		strconv.Itoa(m.MaxLength),
+		colors.Yellow,
+		">",
+		colors.Restore,
+	)
+}
+
+// printBinaryMatch reports a match in a binary file the way grep does
+func printBinaryMatch(w io.Writer, colors *Colors, path string) {
+	fmt.Fprintf(w, "Binary file %s%s%s matches\n", colors.Purple, path, colors.Restore)
+}
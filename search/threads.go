@@ -0,0 +1,26 @@
+package search
+
+import "runtime"
+
+// networkOversubscription is how many workers run per CPU when searching a
+// network filesystem, where they spend most of their time waiting on I/O
+const networkOversubscription = 4
+
+// workerCount returns the number of files to scan at once.  --threads wins;
+// otherwise there is one worker per CPU, or several when one of the roots is
+// on a network filesystem.
+func (s *Searcher) workerCount(roots []string) int {
+	if s.settings.Threads > 0 {
+		return s.settings.Threads
+	}
+	if !s.onOSFilesystem() {
+		return runtime.NumCPU()
+	}
+	for _, root := range roots {
+		if isNetworkFilesystem(root) {
+			s.debug(s.colors.Blue, "Root", root, "is on a network filesystem, oversubscribing workers", s.colors.Restore)
+			return runtime.NumCPU() * networkOversubscription
+		}
+	}
+	return runtime.NumCPU()
+}
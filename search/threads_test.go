@@ -0,0 +1,21 @@
+package search
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestWorkerCount(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	if !isNetworkFilesystem(tmpDir) {
+		if got := searcher.workerCount([]string{tmpDir}); got != runtime.NumCPU() {
+			t.Errorf("expected one worker per CPU, got %d", got)
+		}
+	}
+
+	searcher.settings.Threads = 3
+	if got := searcher.workerCount([]string{tmpDir}); got != 3 {
+		t.Errorf("expected --threads to win, got %d", got)
+	}
+}
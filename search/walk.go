@@ -0,0 +1,129 @@
+package search
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// fileID identifies a directory so that symlink cycles can be detected.  On
+// unix it is the device and inode pair; elsewhere the resolved path is used.
+type fileID struct {
+	dev  uint64
+	ino  uint64
+	path string
+}
+
+// walkTree walks root like filepath.Walk, additionally descending into
+// symlinked directories when --follow is set.  visited is shared between the
+// roots of a search so a directory reached through several links is only
+// searched once.
+func (s *Searcher) walkTree(root string, fn filepath.WalkFunc, visited map[fileID]bool) error {
+	if !s.settings.FollowSymlinks {
+		if s.onOSFilesystem() {
+			return filepath.Walk(root, fn)
+		}
+		return s.walkFS(root, fn)
+	}
+
+	info, err := fs.Lstat(s.fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = s.walkFollowing(root, info, fn, visited)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (s *Searcher) walkFollowing(path string, info fs.FileInfo, fn filepath.WalkFunc, visited map[fileID]bool) error {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := fs.Stat(s.fsys, path)
+		if err != nil || !target.IsDir() {
+			// Broken links and links to files are handled by processFile
+			return fn(path, info, nil)
+		}
+		s.debug(s.colors.Blue, "Following symlinked directory", path, s.colors.Restore)
+		info = target
+	}
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	if id, ok := s.dirIdentity(path, info); ok {
+		if visited[id] {
+			s.debug(s.colors.Blue, "Directory", path, "was already searched (symlink loop?) and will be skipped", s.colors.Restore)
+			return nil
+		}
+		visited[id] = true
+	}
+
+	if err := fn(path, info, nil); err != nil {
+		return err
+	}
+
+	names, err := readDirNames(s.fsys, path)
+	if err != nil {
+		// Give the walk function a chance to report the error, like filepath.Walk
+		return fn(path, info, err)
+	}
+
+	for _, name := range names {
+		child := s.join(path, name)
+		childInfo, err := fs.Lstat(s.fsys, child)
+		if err != nil {
+			if err := fn(child, childInfo, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		err = s.walkFollowing(child, childInfo, fn, visited)
+		if err == filepath.SkipDir {
+			if !childInfo.IsDir() && childInfo.Mode()&fs.ModeSymlink == 0 {
+				// SkipDir from a file skips the rest of its directory
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readDirNames returns the sorted names of the entries in dir
+func readDirNames(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resolveSymlink replaces the info of a symlink with that of its target.  ok
+// is false for broken links and for links to directories that aren't being
+// followed, which are counted and skipped.
+func (s *Searcher) resolveSymlink(path string, info fs.FileInfo) (fs.FileInfo, bool) {
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return info, true
+	}
+	target, err := fs.Stat(s.fsys, path)
+	if err != nil {
+		s.debug(s.colors.Blue, "Broken symlink", path, "will be skipped. Err:", err, s.colors.Restore)
+		s.stats.incrBrokenLinkCount()
+		return info, false
+	}
+	if target.IsDir() {
+		s.debug(s.colors.Blue, "Symlinked directory", path, "will be skipped (use --follow to search it)", s.colors.Restore)
+		return info, false
+	}
+	return target, true
+}
@@ -0,0 +1,74 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// walkTree
+// ---------------------------------------------------------------------------
+
+func TestWalkTreeFollowsLinksAndStopsAtLoops(t *testing.T) {
+	resetTestState(t)
+	root, _ := fixture.Monorepo(t)
+	searcher.settings.FollowSymlinks = true
+
+	var seen []string
+	err := searcher.walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			seen = append(seen, rel)
+		}
+		return nil
+	}, map[fileID]bool{})
+	if err != nil {
+		t.Fatalf("walkTree: %v", err)
+	}
+
+	want := []string{
+		filepath.Join("packages", "app", "main.go"),
+		filepath.Join("packages", "app", "shared", "lib.go"),
+	}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, seen)
+	}
+}
+
+func TestWalkTreeSkipDir(t *testing.T) {
+	resetTestState(t)
+	root, _ := fixture.Monorepo(t)
+	searcher.settings.FollowSymlinks = true
+
+	var seen []string
+	searcher.walkTree(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() && filepath.Base(path) == "shared" {
+			return filepath.SkipDir
+		}
+		if err == nil && !info.IsDir() {
+			seen = append(seen, filepath.Base(path))
+		}
+		return nil
+	}, map[fileID]bool{})
+	if len(seen) != 1 || seen[0] != "main.go" {
+		t.Errorf("expected only main.go, got %v", seen)
+	}
+}
+
+func TestListedFilesBrokenLink(t *testing.T) {
+	resetTestState(t)
+	tmpDir := t.TempDir()
+	link := filepath.Join(tmpDir, "bad_link.txt")
+	fixture.Symlink(t, filepath.Join(tmpDir, "missing.txt"), link)
+
+	if listed := collectListed(link + "\n"); len(listed) != 0 {
+		t.Errorf("did not expect the broken link to be queued, got %v", listed)
+	}
+	if searcher.stats.BrokenLinkCount() != 1 || searcher.stats.ErroredFilesCount() != 0 {
+		t.Errorf("expected 1 broken link and no errors, got %d and %d", searcher.stats.BrokenLinkCount(), searcher.stats.ErroredFilesCount())
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/freedomben/findref/search"
+)
+
+// TemplateMatch is the data passed to the --template for each matching line.
+type TemplateMatch struct {
+	Path       string
+	LineNumber int
+	Line       string
+	Column     int
+	Match      string
+	Spans      [][]int
+	Captures   []string
+	Named      map[string]string
+}
+
+// TemplateFile is the data passed to the --file-template once per file with
+// at least one match, before that file's matches are rendered.
+type TemplateFile struct {
+	Path       string
+	MatchCount int
+}
+
+// TemplateSummary is the data passed to the --summary-template after the
+// search has completed.
+type TemplateSummary struct {
+	Elapsed      time.Duration
+	FilesScanned int
+	FilesMatched int
+	LinesScanned int
+	Matches      int
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"relpath": func(path string) string {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return path
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return path
+			}
+			rel, err := filepath.Rel(cwd, abs)
+			if err != nil {
+				return path
+			}
+			return rel
+		},
+		"abspath": func(path string) string {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return path
+			}
+			return abs
+		},
+		"base": filepath.Base,
+		"dir":  filepath.Dir,
+		"color": func(name string, text interface{}) (string, error) {
+			code, ok := colorByName(name)
+			if !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			return code + fmt.Sprint(text) + colors.Restore, nil
+		},
+		"highlight": func(m TemplateMatch) string {
+			var b strings.Builder
+			last := 0
+			for _, span := range m.Spans {
+				b.WriteString(m.Line[last:span[0]])
+				b.WriteString(colors.LightRed)
+				b.WriteString(m.Line[span[0]:span[1]])
+				b.WriteString(colors.Restore)
+				last = span[1]
+			}
+			b.WriteString(m.Line[last:])
+			return b.String()
+		},
+		"trim":  strings.TrimSpace,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
+
+func colorByName(name string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "red":
+		return colors.Red, true
+	case "blue":
+		return colors.Blue, true
+	case "cyan":
+		return colors.Cyan, true
+	case "green":
+		return colors.Green, true
+	case "black":
+		return colors.Black, true
+	case "brown":
+		return colors.Brown, true
+	case "white":
+		return colors.White, true
+	case "yellow":
+		return colors.Yellow, true
+	case "purple":
+		return colors.Purple, true
+	case "lightred":
+		return colors.LightRed, true
+	case "darkgray":
+		return colors.DarkGray, true
+	case "lightgray":
+		return colors.LightGray, true
+	case "lightblue":
+		return colors.LightBlue, true
+	case "lightcyan":
+		return colors.LightCyan, true
+	case "lightgreen":
+		return colors.LightGreen, true
+	case "lightpurple":
+		return colors.LightPurple, true
+	}
+	return "", false
+}
+
+// parseOutputTemplate compiles a user supplied template.  Escape sequences
+// like \n and \t are expanded so they can be passed easily on the command line.
+func parseOutputTemplate(name string, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs()).Parse(expandEscapes(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, text, err)
+	}
+	return tmpl, nil
+}
+
+// expandEscapes expands \n and \t in the literal text of a template.  The
+// {{ }} actions are left alone, so the escapes in their string constants keep
+// the meaning Go gives them.
+func expandEscapes(text string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\t`, "\t")
+	var b strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			b.WriteString(replacer.Replace(text))
+			return b.String()
+		}
+		b.WriteString(replacer.Replace(text[:start]))
+		end := actionEnd(text, start+2)
+		b.WriteString(text[start:end])
+		text = text[end:]
+	}
+}
+
+// actionEnd returns the index just past the }} that closes the action whose
+// body starts at i, skipping over quoted strings and comments, or len(text)
+// if the action is never closed
+func actionEnd(text string, i int) int {
+	for i < len(text) {
+		switch text[i] {
+		case '/':
+			if !strings.HasPrefix(text[i:], "/*") {
+				i++
+				continue
+			}
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return len(text)
+			}
+			i += end + 4
+		case '"', '\'', '`':
+			quote := text[i]
+			for i++; i < len(text) && text[i] != quote; i++ {
+				if text[i] == '\\' && quote != '`' {
+					i++
+				}
+			}
+			i++
+		case '}':
+			if strings.HasPrefix(text[i:], "}}") {
+				return i + 2
+			}
+			i++
+		default:
+			i++
+		}
+	}
+	return len(text)
+}
+
+// lineMatcher returns the Matcher the search uses, worked out once, or nil if
+// there is neither a Matcher nor a regex in the settings
+func (c *command) lineMatcher() search.Matcher {
+	if c.matcher == nil {
+		switch {
+		case c.settings.Matcher != nil:
+			c.matcher = c.settings.Matcher
+		case c.settings.MatchRegex != nil:
+			c.matcher = search.NewMatcher(c.settings.MatchRegex)
+		}
+	}
+	return c.matcher
+}
+
+func (c *command) newTemplateMatch(m search.Match) TemplateMatch {
+	tm := TemplateMatch{
+		Path:       m.Path,
+		LineNumber: m.LineNumber,
+		Line:       string(m.Line),
+		Column:     m.Match[0] + 1,
+		Match:      string(m.Line[m.Match[0]:m.Match[1]]),
+		Spans:      [][]int{},
+		Captures:   []string{},
+		Named:      map[string]string{},
+	}
+
+	if matcher := c.lineMatcher(); matcher != nil {
+		for _, loc := range matcher.FindAll(m.Line) {
+			tm.Spans = append(tm.Spans, []int{loc[0], loc[1]})
+		}
+	}
+	if len(tm.Spans) == 0 {
+		tm.Spans = append(tm.Spans, []int{m.Match[0], m.Match[1]})
+	}
+
+	// Only a regex has capture groups
+	if c.settings.Matcher != nil || c.settings.MatchRegex == nil {
+		return tm
+	}
+	if submatches := c.settings.MatchRegex.FindSubmatch(m.Line); submatches != nil {
+		names := c.settings.MatchRegex.SubexpNames()
+		for i := 1; i < len(submatches); i++ {
+			tm.Captures = append(tm.Captures, string(submatches[i]))
+			if names[i] != "" {
+				tm.Named[names[i]] = string(submatches[i])
+			}
+		}
+	}
+	return tm
+}
+
+func (c *command) executeTemplate(tmpl *template.Template, data interface{}) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		fmt.Fprintln(os.Stderr, colors.Red+"[error]: rendering "+tmpl.Name()+": "+err.Error()+colors.Restore)
+		return
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	c.out.Write(buf.Bytes())
+}
+
+// templateSink renders the results of the search through the --template,
+// --file-template and --summary-template options.  Anything they leave out is
+// printed by the embedded TextSink.
+type templateSink struct {
+	*search.TextSink
+	c *command
+
+	// fileMatches holds the matches of the file being rendered, since
+	// --file-template needs to know how many there are
+	fileMatches []search.Match
+}
+
+func (t *templateSink) Match(m search.Match) {
+	if m.Binary || !t.c.usesTemplates() {
+		t.TextSink.Match(m)
+		return
+	}
+	t.fileMatches = append(t.fileMatches, m)
+}
+
+// EndFile renders the matches of a single file (or of a member of an
+// archive) through the configured templates
+func (t *templateSink) EndFile(path string, matches int) {
+	if len(t.fileMatches) == 0 {
+		return
+	}
+	if t.c.fileTemplate != nil {
+		t.c.executeTemplate(t.c.fileTemplate, TemplateFile{Path: path, MatchCount: len(t.fileMatches)})
+	}
+	for _, m := range t.fileMatches {
+		if t.c.matchTemplate != nil {
+			t.c.executeTemplate(t.c.matchTemplate, t.c.newTemplateMatch(m))
+		} else {
+			t.TextSink.Match(m)
+		}
+	}
+	t.fileMatches = t.fileMatches[:0]
+}
+
+func (t *templateSink) Summary(stats *search.Statistics) {
+	if t.c.summaryTemplate != nil {
+		t.c.executeTemplate(t.c.summaryTemplate, TemplateSummary{
+			Elapsed:      stats.ElapsedTime(),
+			FilesScanned: stats.FileCount(),
+			FilesMatched: stats.FilesMatchedCount(),
+			LinesScanned: stats.LineCount(),
+			Matches:      stats.MatchCount(),
+		})
+	}
+	t.TextSink.Summary(stats)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/search"
+)
+
+// ---------------------------------------------------------------------------
+// parseOutputTemplate / newTemplateMatch
+// ---------------------------------------------------------------------------
+
+func TestParseOutputTemplateEmpty(t *testing.T) {
+	tmpl, err := parseOutputTemplate("template", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tmpl != nil {
+		t.Fatal("expected nil template for empty input")
+	}
+}
+
+func TestParseOutputTemplateInvalid(t *testing.T) {
+	_, err := parseOutputTemplate("template", "{{.Path")
+	if err == nil {
+		t.Fatal("expected error for invalid template")
+	}
+	if !strings.Contains(err.Error(), "invalid template") {
+		t.Errorf("expected error to mention invalid template, got %q", err.Error())
+	}
+}
+
+func TestNewTemplateMatchSpansAndCaptures(t *testing.T) {
+	resetTestState(t)
+	cmd.settings.MatchRegex = regexp.MustCompile(`(?P<key>\w+)=(\d+)`)
+	line := []byte("a=1 b=2")
+	m := search.Match{Path: "conf.txt", LineNumber: 3, Line: line, Match: cmd.settings.MatchRegex.FindIndex(line), MaxLength: 2000}
+
+	tm := cmd.newTemplateMatch(m)
+	if tm.Column != 1 {
+		t.Errorf("expected column 1, got %d", tm.Column)
+	}
+	if tm.Match != "a=1" {
+		t.Errorf("expected match %q, got %q", "a=1", tm.Match)
+	}
+	if len(tm.Spans) != 2 || tm.Spans[1][0] != 4 || tm.Spans[1][1] != 7 {
+		t.Errorf("unexpected spans: %v", tm.Spans)
+	}
+	if len(tm.Captures) != 2 || tm.Captures[0] != "a" || tm.Captures[1] != "1" {
+		t.Errorf("unexpected captures: %v", tm.Captures)
+	}
+	if tm.Named["key"] != "a" {
+		t.Errorf("expected named capture key=a, got %v", tm.Named)
+	}
+}
+
+func TestExpandEscapes(t *testing.T) {
+	cases := map[string]string{
+		`{{.Path}}\t{{.Line}}\n`:    "{{.Path}}\t{{.Line}}\n",
+		`{{printf "%s\n" .Path}}\n`: `{{printf "%s\n" .Path}}` + "\n",
+		`{{printf "}}\t" .Path}}\t`: `{{printf "}}\t" .Path}}` + "\t",
+		"{{printf `a\\n` .Path}}":   "{{printf `a\\n` .Path}}",
+		`a\n{{/* \n */}}b\n{{.Path`: "a\n{{/* \\n */}}b\n{{.Path",
+		`{{/* don't */}}\n`:         "{{/* don't */}}\n",
+	}
+	for in, want := range cases {
+		if got := expandEscapes(in); got != want {
+			t.Errorf("expandEscapes(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	tmpl, err := parseOutputTemplate("template", `{{printf "%s\n" .Path}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var b strings.Builder
+	tmpl.Execute(&b, TemplateMatch{Path: "a.go"})
+	if b.String() != "a.go\n" {
+		t.Errorf("expected the escape in the string constant to be kept, got %q", b.String())
+	}
+}
+
+func TestNewTemplateMatchUsesMatcher(t *testing.T) {
+	resetTestState(t)
+	cmd.settings.Matcher = search.NewMatcher(regexp.MustCompile(`(?i)todo`))
+	line := []byte("TODO and todo")
+	m := search.Match{Path: "a.txt", LineNumber: 1, Line: line, Match: []int{0, 4}, MaxLength: 2000}
+
+	tm := cmd.newTemplateMatch(m)
+	if len(tm.Spans) != 2 || tm.Spans[1][0] != 9 || tm.Spans[1][1] != 13 {
+		t.Errorf("expected the spans of the Matcher, got %v", tm.Spans)
+	}
+	if len(tm.Captures) != 0 {
+		t.Errorf("expected no captures without a regex, got %v", tm.Captures)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Integration: --template, --file-template, --summary-template
+// ---------------------------------------------------------------------------
+
+func TestIntegrationTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "main.go")
+	mustWriteFile(t, f, "package main\n\tx := TODO()\n")
+
+	stdout, stderr := runFindrefMain(t, []string{"--no-color", "--template", "{{base .Path}}|{{.LineNumber}}|{{.Column}}|{{.Match}}", "TODO", tmpDir})
+	if stderr != "" {
+		t.Fatalf("unexpected stderr: %q", stderr)
+	}
+	lines := splitLines(stdout)
+	expectContains(t, lines, "main.go|2|7|TODO")
+	if len(lines) != 1 {
+		t.Errorf("expected exactly 1 output line, got %v", lines)
+	}
+}
+
+func TestIntegrationFileAndSummaryTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO one\nTODO two\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "nothing\n")
+
+	stdout, _ := runFindrefMain(t, []string{
+		"--no-color",
+		"--file-template", "== {{base .Path}} {{.MatchCount}}",
+		"--template", "{{.LineNumber}}",
+		"--summary-template", "total {{.Matches}} in {{.FilesMatched}}",
+		"TODO", tmpDir,
+	})
+	lines := splitLines(stdout)
+	want := []string{"== a.txt 2", "1", "2", "total 2 in 1"}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %v, got %v", want, lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d: expected %q, got %q", i, want[i], lines[i])
+		}
+	}
+}
+
+func TestIntegrationFileTemplateDefaultMatchFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	f := filepath.Join(tmpDir, "a.txt")
+	mustWriteFile(t, f, "TODO one\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--file-template", "# {{base .Path}}", "TODO", tmpDir})
+	lines := splitLines(stdout)
+	expectContains(t, lines, "# a.txt")
+	expectContains(t, lines, f+":1:TODO one")
+}
+
+func TestIntegrationTemplateColorHelper(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO one\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--template", `{{color "green" .LineNumber}}`, "TODO", tmpDir})
+	expectContains(t, splitLines(stdout), colors.Green+"1"+colors.Restore)
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "--template", `{{color "green" .LineNumber}}`, "TODO", tmpDir})
+	expectContains(t, splitLines(stdout), "1")
+}
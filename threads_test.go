@@ -0,0 +1,20 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIntegrationThreads(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "a.txt"), "TODO a\n")
+	mustWriteFile(t, filepath.Join(tmpDir, "b.txt"), "TODO b\n")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "-j", "1", "TODO", tmpDir})
+	if lines := splitLines(stdout); len(lines) != 2 {
+		t.Errorf("expected 2 matches with a single worker, got %v", lines)
+	}
+	if cmd.settings.Threads != 1 {
+		t.Errorf("expected 1 thread, got %d", cmd.settings.Threads)
+	}
+}
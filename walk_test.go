@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/freedomben/findref/internal/fixture"
+)
+
+// ---------------------------------------------------------------------------
+// Integration: --follow and broken links
+// ---------------------------------------------------------------------------
+
+func TestIntegrationFollow(t *testing.T) {
+	root, _ := fixture.Monorepo(t)
+	linked := filepath.Join(root, "packages", "app", "shared", "lib.go")
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "TODO", root})
+	lines := splitLines(stdout)
+	expectNotContains(t, lines, linked+":1:// TODO shared")
+	if len(lines) != 1 {
+		t.Errorf("expected only main.go without --follow, got %v", lines)
+	}
+
+	stdout, _ = runFindrefMain(t, []string{"--no-color", "-L", "TODO", root})
+	lines = splitLines(stdout)
+	expectContains(t, lines, linked+":1:// TODO shared")
+	if len(lines) != 2 {
+		t.Errorf("expected each file once with --follow, got %v", lines)
+	}
+}
+
+func TestIntegrationBrokenLinkCounted(t *testing.T) {
+	tmpDir := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmpDir, "good.txt"), "TODO good\n")
+	fixture.Symlink(t, filepath.Join(tmpDir, "missing.txt"), filepath.Join(tmpDir, "bad_link.txt"))
+
+	stdout, _ := runFindrefMain(t, []string{"--no-color", "--stats", "--follow", "TODO", tmpDir})
+	if strings.Contains(stdout, "Error opening file") {
+		t.Errorf("expected broken links not to be reported as errors, got %q", stdout)
+	}
+	expectContains(t, splitLines(stdout), "Broken Links:  1")
+	if cmd.stats.ErroredFilesCount() != 0 {
+		t.Errorf("expected no errored files, got %d", cmd.stats.ErroredFilesCount())
+	}
+}
+
+func TestMCPSearchFollow(t *testing.T) {
+	resetTestState(t)
+	root, _ := fixture.Monorepo(t)
+
+	args, _ := json.Marshal(searchArgs{Pattern: "TODO", Directory: root, Follow: true, FilenameOnly: true})
+	result, _ := handleSearch(context.Background(), args)
+	var filenames []string
+	json.Unmarshal([]byte(result.Content[0].Text), &filenames)
+	if len(filenames) != 2 {
+		t.Errorf("expected main.go and the linked lib.go, got %v", filenames)
+	}
+}